@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime/pprof"
@@ -32,7 +33,7 @@ func main() {
 	defer pprof.StopCPUProfile()
 
 	fmt.Println("Starting profiled ecommerce 10GB CSV generation...")
-	if err := core.GenerateModelData("ecommerce", counts, "csv", "10"); err != nil {
+	if err := core.GenerateModelData(context.Background(), "ecommerce", counts, "csv", "10", 1, 10, "", core.DefaultOutputOptions); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -5,12 +5,6 @@ import (
 	"math/rand"
 )
 
-// DataGenerator defines the interface for generating synthetic data
-type DataGenerator interface {
-	GenerateDimensions(ctx context.Context, config GenerationConfig) ([]DimensionData, error)
-	GenerateFacts(ctx context.Context, config GenerationConfig, dimensions []DimensionData) ([]FactData, error)
-}
-
 // DataWriter defines the interface for writing data to different formats
 type DataWriter interface {
 	Write(ctx context.Context, data interface{}, format string, target string) error
@@ -46,31 +40,6 @@ type Future interface {
 	Done() <-chan struct{}
 }
 
-// GenerationConfig holds configuration for data generation
-type GenerationConfig struct {
-	TargetSizeGB float64
-	Format       string
-	OutputDir    string
-	NumWorkers   int
-	BatchSize    int
-	Seed         int64
-}
-
-// DimensionData represents generated dimension table data
-type DimensionData struct {
-	Type  string
-	Data  interface{}
-	Count int
-}
-
-// FactData represents generated fact table data
-type FactData struct {
-	Type   string
-	Data   interface{}
-	Count  int
-	Chunks [][]interface{} // For parallel processing
-}
-
 // future implements the Future interface
 type future struct {
 	task   Task
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	randv2 "math/rand/v2"
+	"time"
+
+	gf "github.com/brianvoe/gofakeit/v6"
+)
+
+// RandomSeed returns a fresh run seed for runs started without --seed.
+func RandomSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// DeriveSeed mixes the run seed with a table name and a shard index into the seed
+// of an independent random stream. Streams only depend on their own table and
+// shard, so output does not change with the number of CPUs or goroutine timing.
+func DeriveSeed(seed int64, table string, shard int) int64 {
+	var buf [8]byte
+	h := fnv.New64a()
+	binary.LittleEndian.PutUint64(buf[:], uint64(seed))
+	h.Write(buf[:])
+	h.Write([]byte(table))
+	binary.LittleEndian.PutUint64(buf[:], uint64(shard))
+	h.Write(buf[:])
+
+	// splitmix64 finaliser: spreads nearby inputs over the whole seed space.
+	z := h.Sum64() + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	if z == 0 {
+		// gofakeit treats a zero seed as "seed from crypto/rand".
+		z = 1
+	}
+	return int64(z)
+}
+
+// NewRand returns the math/rand stream of one table shard.
+func NewRand(seed int64, table string, shard int) *rand.Rand {
+	return rand.New(rand.NewSource(DeriveSeed(seed, table, shard)))
+}
+
+// NewRandV2 returns the math/rand/v2 stream of one table shard.
+func NewRandV2(seed int64, table string, shard int) *randv2.Rand {
+	return randv2.New(NewPCG(seed, table, shard))
+}
+
+// NewPCG returns the math/rand/v2 source of one table shard, for libraries such
+// as gonum that take a source rather than a generator.
+func NewPCG(seed int64, table string, shard int) *randv2.PCG {
+	s := uint64(DeriveSeed(seed, table, shard))
+	return randv2.NewPCG(s, s^0x9e3779b97f4a7c15)
+}
+
+// NewFaker returns a gofakeit instance of one table shard. The faker is not safe
+// for concurrent use; give every goroutine its own.
+func NewFaker(seed int64, table string, shard int) *gf.Faker {
+	return gf.NewUnlocked(DeriveSeed(seed, table, shard))
+}
+
+// NewByteSource returns the stream of random bytes of one table shard, for
+// drawing values such as UUIDs that only take a reader.
+func NewByteSource(seed int64, table string, shard int) io.Reader {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], uint64(DeriveSeed(seed, table, shard)))
+	return randv2.NewChaCha8(key)
+}
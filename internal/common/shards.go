@@ -0,0 +1,28 @@
+package common
+
+import "runtime"
+
+// Fact table sharding parameters
+const (
+	minRowsPerShard = 100_000
+	maxShards       = 256
+)
+
+// ShardCount returns how many files a fact table of the given size is split into.
+// It only depends on the row count, so the shard layout, and with it every shard's
+// key range and seed, is identical on machines with different CPU counts.
+func ShardCount(rows int) int {
+	shards := (rows + minRowsPerShard - 1) / minRowsPerShard
+	if shards < 1 {
+		return 1
+	}
+	if shards > maxShards {
+		return maxShards
+	}
+	return shards
+}
+
+// MaxParallelShards bounds how many shards are generated at the same time.
+func MaxParallelShards() int {
+	return runtime.NumCPU()
+}
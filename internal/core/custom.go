@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+
+	"github.com/peekknuf/Gengo/internal/formats"
+	custommodels "github.com/peekknuf/Gengo/internal/models/custom"
+	"github.com/peekknuf/Gengo/internal/schema"
+	customsimulation "github.com/peekknuf/Gengo/internal/simulation/custom"
+	"golang.org/x/sync/errgroup"
+)
+
+// LoadModelFile reads the custom model declared in the YAML model file at path
+// and registers it under its name like a built-in model. It returns the name of
+// the model.
+func LoadModelFile(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving model file %s: %w", path, err)
+	}
+	model, err := custommodels.Load(abs)
+	if err != nil {
+		return "", err
+	}
+	if other, err := lookupModel(model.Name); err == nil && other.file == "" {
+		return "", fmt.Errorf("model file %s: model name %s is taken by a built-in model", path, model.Name)
+	}
+
+	tables := make([]schema.Table, len(model.Tables))
+	for i, t := range model.Tables {
+		table := schema.Table{
+			Name:     t.Name,
+			Row:      reflect.New(t.RowType()).Elem().Interface(),
+			RowCount: t.Name,
+		}
+		for _, c := range t.Columns {
+			if c.PrimaryKey {
+				table.PrimaryKey = []string{c.Name}
+			}
+			if c.References != "" {
+				table.ForeignKeys = append(table.ForeignKeys, schema.ForeignKey{Columns: []string{c.Name}, References: c.References})
+			}
+		}
+		tables[i] = table
+	}
+
+	err = RegisterModel(Model[map[string]int]{
+		Name:   model.Name,
+		File:   model.Path,
+		Tables: tables,
+		RowCounts: func(targetGB float64, format string) (map[string]int, error) {
+			return CalculateCustomRowCounts(model, targetGB, format)
+		},
+		Generate: func(ctx context.Context, tables *tableTracker, counts map[string]int, opts formats.Options, outputDir string, seed int64) error {
+			return generateCustomDataConcurrently(ctx, tables, model, counts, opts, outputDir, seed)
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("model file %s: %w", path, err)
+	}
+	return model.Name, nil
+}
+
+// Estimated CSV widths of custom column values, separator included.
+const (
+	customIntBytes       = 5
+	customBigIntBytes    = 8
+	customFloatBytes     = 8
+	customBoolBytes      = 6
+	customTimestampBytes = 21
+)
+
+// CalculateCustomRowCounts determines the number of rows of each table of a
+// custom model, keyed by table name. Tables with a fixed row count keep it; the
+// rest of the target size is shared by the tables with a ratio so that their
+// row counts keep the ratios between them. Row sizes are estimated from the
+// column types.
+func CalculateCustomRowCounts(model *custommodels.Model, targetGB float64, format string) (map[string]int, error) {
+	if targetGB <= 0 {
+		return nil, fmt.Errorf("target size must be positive")
+	}
+
+	targetBytes := targetGB * 1024 * 1024 * 1024
+	// Parquet compresses to roughly two thirds of CSV, as for the TPC-DS model.
+	if format == "parquet" {
+		targetBytes *= 1.5
+	}
+
+	counts := make(map[string]int, len(model.Tables))
+	var ratioBytes float64
+	for _, t := range model.Tables {
+		rowBytes := estimateCustomRowBytes(t, format)
+		if t.Rows > 0 {
+			counts[t.Name] = t.Rows
+			targetBytes -= float64(t.Rows) * rowBytes
+		} else {
+			ratioBytes += t.Ratio * rowBytes
+		}
+	}
+	unit := math.Max(0, targetBytes) / ratioBytes
+	for _, t := range model.Tables {
+		if t.Ratio > 0 {
+			counts[t.Name] = int(math.Max(1.0, math.Round(t.Ratio*unit)))
+		}
+	}
+	return counts, nil
+}
+
+// estimateCustomRowBytes estimates the average size of a row of t as written
+// in format.
+func estimateCustomRowBytes(t *custommodels.Table, format string) float64 {
+	var size float64
+	for _, c := range t.Columns {
+		switch c.Type {
+		case "int32":
+			size += customIntBytes
+		case "int64":
+			size += customBigIntBytes
+		case "float64":
+			size += customFloatBytes
+		case "bool":
+			size += customBoolBytes
+		case "timestamp":
+			size += customTimestampBytes
+		default:
+			if len(c.Enum) > 0 {
+				var total int
+				for _, v := range c.Enum {
+					total += len(v)
+				}
+				size += float64(total)/float64(len(c.Enum)) + 1
+			} else {
+				size += AvgBytesStringShort + 1
+			}
+		}
+		if format == "json" {
+			size += float64(len(c.Name)) + 3 // "name":
+		}
+	}
+	return size
+}
+
+func generateCustomDataConcurrently(ctx context.Context, tables *tableTracker, model *custommodels.Model, counts map[string]int, opts formats.Options, outputDir string, seed int64) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, t := range model.Tables {
+		tables.Go(g, func() error {
+			return customsimulation.GenerateTable(ctx, t, counts, opts, outputDir, seed)
+		}, t.Name)
+	}
+	return g.Wait()
+}
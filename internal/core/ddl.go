@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peekknuf/Gengo/internal/formats"
+	"github.com/peekknuf/Gengo/internal/schema"
+)
+
+// SchemaFile is the name of the DDL file written to the output directory.
+const SchemaFile = "schema.sql"
+
+// SchemaDDL returns the CREATE TABLE statements of modelType (or one of its
+// aliases) in dialect, typed as the tables are written in format. CSV tables
+// are read in the default CSV dialect.
+func SchemaDDL(modelType, format, dialect string) (string, error) {
+	model, err := lookupModel(strings.TrimSpace(modelType))
+	if err != nil {
+		return "", err
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if err := model.checkFormat(format); err != nil {
+		return "", err
+	}
+	return schema.DDL(model.name, format, dialect, formats.DefaultCSVDialect)
+}
+
+// WriteSchemaFile writes ddl to schema.sql in dir, replacing it atomically.
+func WriteSchemaFile(dir, ddl string) error {
+	path := filepath.Join(dir, SchemaFile)
+	if err := os.WriteFile(formats.TempName(path), []byte(ddl), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", SchemaFile, err)
+	}
+	if err := os.Rename(formats.TempName(path), path); err != nil {
+		return fmt.Errorf("error writing %s: %w", SchemaFile, err)
+	}
+	return nil
+}
@@ -10,25 +10,34 @@ import (
 	"github.com/peekknuf/Gengo/internal/utils"
 )
 
-func GetUserInput(modelTypeFlag string, targetGBFlag float64, formatFlag, outputDirFlag string) (modelType string, counts interface{}, format string, outputDir string, err error) {
+// GetUserInput completes the configuration given by flags from interactive
+// prompts and calculates the row counts of the model, for the target size or,
+// if scaleFactor is set, for that benchmark scale factor. The row counts of the
+// tables in rows, keyed by table name, replace the calculated ones.
+func GetUserInput(modelTypeFlag string, targetGBFlag float64, scaleFactor int, formatFlag, outputDirFlag string, rows map[string]int) (modelType string, targetGB float64, counts interface{}, format string, outputDir string, err error) {
 	// --- Model Type ---
 	modelType = modelTypeFlag
 	if modelType == "" {
-		fmt.Print("Enter the data model to generate (ecommerce/ecommerce-ds/financial/medical): ")
+		fmt.Printf("Enter the data model to generate (%s): ", strings.Join(ModelNames(), "/"))
 		if _, scanErr := fmt.Scanln(&modelType); scanErr != nil {
 			err = fmt.Errorf("error reading model type: %w", scanErr)
 			return
 		}
 	}
 	modelType = strings.ToLower(strings.TrimSpace(modelType))
-	modelType, err = matchModelType(modelType)
+	model, err := lookupModel(modelType)
 	if err != nil {
 		return
 	}
+	modelType = model.name
 
 	// --- Target Size ---
-	targetGB := targetGBFlag
-	if targetGB == 0 {
+	if scaleFactor > 0 && model.scaleFactorRowCounts == nil {
+		err = fmt.Errorf("model %s has no scale factors; use a target size", modelType)
+		return
+	}
+	targetGB = targetGBFlag
+	if targetGB == 0 && scaleFactor == 0 {
 		var targetGBStr string
 		fmt.Print("Enter the approximate target size in GB (e.g., 0.5, 10): ")
 		if _, scanErr := fmt.Scanln(&targetGBStr); scanErr != nil {
@@ -41,7 +50,7 @@ func GetUserInput(modelTypeFlag string, targetGBFlag float64, formatFlag, output
 			return
 		}
 	}
-	if targetGB <= 0 {
+	if targetGB <= 0 && scaleFactor == 0 {
 		err = fmt.Errorf("target size must be positive")
 		return
 	}
@@ -49,118 +58,35 @@ func GetUserInput(modelTypeFlag string, targetGBFlag float64, formatFlag, output
 	// --- Output Format (determined before row counts for format-aware sizing) ---
 	format = formatFlag
 	if format == "" {
-		fmt.Print("Enter the desired output format (csv/json/parquet): ")
+		fmt.Printf("Enter the desired output format (%s): ", strings.Join(model.formats, "/"))
 		if _, scanErr := fmt.Scanln(&format); scanErr != nil {
 			err = fmt.Errorf("error reading output format: %w", scanErr)
 			return
 		}
 	}
 	format = strings.ToLower(strings.TrimSpace(format))
-	if format != "csv" && format != "json" && format != "parquet" {
-		err = fmt.Errorf("unsupported output format: %s", format)
+	if err = model.checkFormat(format); err != nil {
 		return
 	}
 
 	// --- Calculate Row Counts ---
-	switch modelType {
-	case "ecommerce":
-		var ecommerceCounts ECommerceRowCounts
-		ecommerceCounts, err = CalculateECommerceRowCounts(targetGB)
-		if err != nil {
-			err = fmt.Errorf("error calculating e-commerce row counts: %w", err)
-			return
-		}
-		counts = ecommerceCounts
-		fmt.Println("\n--- Estimated E-commerce Row Counts ---")
-		fmt.Printf("Customers:         %s\n", utils.AddUnderscores(ecommerceCounts.Customers))
-		fmt.Printf("Customer Addresses: %s\n", utils.AddUnderscores(ecommerceCounts.CustomerAddresses))
-		fmt.Printf("Suppliers:         %s\n", utils.AddUnderscores(ecommerceCounts.Suppliers))
-		fmt.Printf("Products:          %s\n", utils.AddUnderscores(ecommerceCounts.Products))
-		fmt.Printf("Product Categories: %s\n", utils.AddUnderscores(ecommerceCounts.ProductCategories))
-		fmt.Printf("Order Headers:     %s\n", utils.AddUnderscores(ecommerceCounts.OrderHeaders))
-		fmt.Printf("Order Items:       %s\n", utils.AddUnderscores(ecommerceCounts.OrderItems))
-	case "ecommerce-ds":
-		var ecommerceDSCounts ECommerceDSRowCounts
-		ecommerceDSCounts, err = CalculateECommerceDSRowCounts(targetGB, format)
-		if err != nil {
-			err = fmt.Errorf("error calculating e-commerce-ds row counts: %w", err)
-			return
-		}
-		counts = ecommerceDSCounts
-		fmt.Println("\n--- Estimated E-commerce DS (TPC-DS) Row Counts ---")
-		fmt.Println("\n📊 FACT TABLES:")
-		fmt.Printf("Store Sales:       %s\n", utils.AddUnderscores(ecommerceDSCounts.StoreSales))
-		fmt.Printf("Web Sales:         %s\n", utils.AddUnderscores(ecommerceDSCounts.WebSales))
-		fmt.Printf("Catalog Sales:     %s\n", utils.AddUnderscores(ecommerceDSCounts.CatalogSales))
-		fmt.Printf("Store Returns:     %s\n", utils.AddUnderscores(ecommerceDSCounts.StoreReturns))
-		fmt.Printf("Web Returns:       %s\n", utils.AddUnderscores(ecommerceDSCounts.WebReturns))
-		fmt.Printf("Catalog Returns:   %s\n", utils.AddUnderscores(ecommerceDSCounts.CatalogReturns))
-		fmt.Printf("Inventory:         %s\n", utils.AddUnderscores(ecommerceDSCounts.Inventory))
-
-		fmt.Println("\n🏢 CORE DIMENSIONS:")
-		fmt.Printf("Customers:         %s\n", utils.AddUnderscores(ecommerceDSCounts.Customers))
-		fmt.Printf("Items:             %s\n", utils.AddUnderscores(ecommerceDSCounts.Items))
-		fmt.Printf("Customer Addresses: %s\n", utils.AddUnderscores(ecommerceDSCounts.CustomerAddresses))
-		fmt.Printf("Promotions:        %s\n", utils.AddUnderscores(ecommerceDSCounts.Promotions))
-		fmt.Printf("Web Pages:         %s\n", utils.AddUnderscores(ecommerceDSCounts.WebPages))
-
-		fmt.Println("\n🏪 BUSINESS DIMENSIONS:")
-		fmt.Printf("Stores:            %s\n", utils.AddUnderscores(ecommerceDSCounts.Stores))
-		fmt.Printf("Warehouses:        %s\n", utils.AddUnderscores(ecommerceDSCounts.Warehouses))
-		fmt.Printf("Call Centers:      %s\n", utils.AddUnderscores(ecommerceDSCounts.CallCenters))
-		fmt.Printf("Web Sites:         %s\n", utils.AddUnderscores(ecommerceDSCounts.WebSites))
-		fmt.Printf("Catalog Pages:     %s\n", utils.AddUnderscores(ecommerceDSCounts.CatalogPages))
-
-		fmt.Println("\n👥 DEMOGRAPHIC DIMENSIONS:")
-		fmt.Printf("Customer Demographics: %s\n", utils.AddUnderscores(ecommerceDSCounts.CustomerDemographics))
-		fmt.Printf("Household Demographics: %s\n", utils.AddUnderscores(ecommerceDSCounts.HouseholdDemographics))
-		fmt.Printf("Income Bands:      %s\n", utils.AddUnderscores(ecommerceDSCounts.IncomeBands))
-
-		fmt.Println("\n🚚 OPERATIONAL DIMENSIONS:")
-		fmt.Printf("Reasons:           %s\n", utils.AddUnderscores(ecommerceDSCounts.Reasons))
-		fmt.Printf("Ship Modes:        %s\n", utils.AddUnderscores(ecommerceDSCounts.ShipModes))
-
-		// Calculate business metrics
-		totalSales := ecommerceDSCounts.StoreSales + ecommerceDSCounts.WebSales + ecommerceDSCounts.CatalogSales
-		totalReturns := ecommerceDSCounts.StoreReturns + ecommerceDSCounts.WebReturns + ecommerceDSCounts.CatalogReturns
-		avgOrdersPerCustomer := float64(totalSales) / float64(ecommerceDSCounts.Customers)
-		overallReturnRate := float64(totalReturns) / float64(totalSales) * 100
-
-		fmt.Println("\n📈 BUSINESS METRICS:")
-		fmt.Printf("Total Sales:       %s\n", utils.AddUnderscores(totalSales))
-		fmt.Printf("Total Returns:      %s\n", utils.AddUnderscores(totalReturns))
-		fmt.Printf("Orders/Customer:   %.1f\n", avgOrdersPerCustomer)
-		fmt.Printf("Return Rate:       %.1f%%\n", overallReturnRate)
-		fmt.Printf("Sales Distribution: Store %.0f%%, Web %.0f%%, Catalog %.0f%%\n",
-			float64(ecommerceDSCounts.StoreSales)/float64(totalSales)*100,
-			float64(ecommerceDSCounts.WebSales)/float64(totalSales)*100,
-			float64(ecommerceDSCounts.CatalogSales)/float64(totalSales)*100)
-	case "financial":
-		var financialCounts financialsimulation.FinancialRowCounts
-		financialCounts, err = CalculateFinancialRowCounts(targetGB)
-		if err != nil {
-			err = fmt.Errorf("error calculating financial row counts: %w", err)
-			return
-		}
-		counts = financialCounts
-		fmt.Println("\n--- Estimated Financial Row Counts ---")
-		fmt.Printf("Companies:             %s\n", utils.AddUnderscores(financialCounts.Companies))
-		fmt.Printf("Exchanges:             %s\n", utils.AddUnderscores(financialCounts.Exchanges))
-		fmt.Printf("Daily Stock Prices:    %s\n", utils.AddUnderscores(financialCounts.DailyStockPrices))
-	case "medical":
-		var medicalCounts medicalsimulation.MedicalRowCounts
-		medicalCounts, err = CalculateMedicalRowCounts(targetGB)
+	if scaleFactor > 0 {
+		counts, err = model.scaleFactorRowCounts(scaleFactor)
+	} else {
+		counts, err = model.rowCounts(targetGB, format)
+	}
+	if err != nil {
+		err = fmt.Errorf("error calculating %s row counts: %w", modelType, err)
+		return
+	}
+	if len(rows) > 0 {
+		counts, err = model.setRowCounts(counts, rows)
 		if err != nil {
-			err = fmt.Errorf("error calculating medical row counts: %w", err)
+			err = fmt.Errorf("error setting row counts: %w", err)
 			return
 		}
-		counts = medicalCounts
-		fmt.Println("\n--- Estimated Medical Row Counts ---")
-		fmt.Printf("Patients:      %s\n", utils.AddUnderscores(medicalCounts.Patients))
-		fmt.Printf("Doctors:       %s\n", utils.AddUnderscores(medicalCounts.Doctors))
-		fmt.Printf("Clinics:       %s\n", utils.AddUnderscores(medicalCounts.Clinics))
-		fmt.Printf("Appointments:  %s\n", utils.AddUnderscores(medicalCounts.Appointments))
 	}
+	model.printRowCounts(counts)
 
 	fmt.Println("----------------------------------------")
 	fmt.Printf("Note: Final output size may vary due to data generation specifics and compression.\n\n")
@@ -180,20 +106,96 @@ func GetUserInput(modelTypeFlag string, targetGBFlag float64, formatFlag, output
 		return
 	}
 
-	return modelType, counts, format, outputDir, nil
+	return modelType, targetGB, counts, format, outputDir, nil
 }
 
+// matchModelType resolves the name or alias of a registered model, including
+// loaded custom models, to the model name.
 func matchModelType(input string) (string, error) {
-	switch strings.ToLower(input) {
-	case "ecommerce", "ecom", "e-commerce", "e":
-		return "ecommerce", nil
-	case "ecommerce-ds", "ecom-ds", "e-commerce-ds", "eds":
-		return "ecommerce-ds", nil
-	case "financial", "fin", "f":
-		return "financial", nil
-	case "medical", "med", "m":
-		return "medical", nil
-	default:
-		return "", fmt.Errorf("unsupported model type: %s. Please choose ecommerce, financial, or medical", input)
+	model, err := lookupModel(input)
+	if err != nil {
+		return "", err
 	}
+	return model.name, nil
+}
+
+func printECommerceRowCounts(counts ECommerceRowCounts) {
+	fmt.Println("\n--- Estimated E-commerce Row Counts ---")
+	fmt.Printf("Customers:         %s\n", utils.AddUnderscores(counts.Customers))
+	fmt.Printf("Customer Addresses: %s\n", utils.AddUnderscores(counts.CustomerAddresses))
+	fmt.Printf("Suppliers:         %s\n", utils.AddUnderscores(counts.Suppliers))
+	fmt.Printf("Products:          %s\n", utils.AddUnderscores(counts.Products))
+	fmt.Printf("Product Categories: %s\n", utils.AddUnderscores(counts.ProductCategories))
+	fmt.Printf("Order Headers:     %s\n", utils.AddUnderscores(counts.OrderHeaders))
+	fmt.Printf("Order Items:       %s\n", utils.AddUnderscores(counts.OrderItems))
+}
+
+func printECommerceDSRowCounts(counts ECommerceDSRowCounts) {
+	if counts.ScaleFactor > 0 {
+		fmt.Printf("\n--- E-commerce DS (TPC-DS) Row Counts at Scale Factor %d ---\n", counts.ScaleFactor)
+	} else {
+		fmt.Println("\n--- Estimated E-commerce DS (TPC-DS) Row Counts ---")
+	}
+	fmt.Println("\n📊 FACT TABLES:")
+	fmt.Printf("Store Sales:       %s\n", utils.AddUnderscores(counts.StoreSales))
+	fmt.Printf("Web Sales:         %s\n", utils.AddUnderscores(counts.WebSales))
+	fmt.Printf("Catalog Sales:     %s\n", utils.AddUnderscores(counts.CatalogSales))
+	fmt.Printf("Store Returns:     %s\n", utils.AddUnderscores(counts.StoreReturns))
+	fmt.Printf("Web Returns:       %s\n", utils.AddUnderscores(counts.WebReturns))
+	fmt.Printf("Catalog Returns:   %s\n", utils.AddUnderscores(counts.CatalogReturns))
+	fmt.Printf("Inventory:         %s\n", utils.AddUnderscores(counts.Inventory))
+
+	fmt.Println("\n🏢 CORE DIMENSIONS:")
+	fmt.Printf("Customers:         %s\n", utils.AddUnderscores(counts.Customers))
+	fmt.Printf("Items:             %s\n", utils.AddUnderscores(counts.Items))
+	fmt.Printf("Customer Addresses: %s\n", utils.AddUnderscores(counts.CustomerAddresses))
+	fmt.Printf("Promotions:        %s\n", utils.AddUnderscores(counts.Promotions))
+	fmt.Printf("Web Pages:         %s\n", utils.AddUnderscores(counts.WebPages))
+
+	fmt.Println("\n🏪 BUSINESS DIMENSIONS:")
+	fmt.Printf("Stores:            %s\n", utils.AddUnderscores(counts.Stores))
+	fmt.Printf("Warehouses:        %s\n", utils.AddUnderscores(counts.Warehouses))
+	fmt.Printf("Call Centers:      %s\n", utils.AddUnderscores(counts.CallCenters))
+	fmt.Printf("Web Sites:         %s\n", utils.AddUnderscores(counts.WebSites))
+	fmt.Printf("Catalog Pages:     %s\n", utils.AddUnderscores(counts.CatalogPages))
+
+	fmt.Println("\n👥 DEMOGRAPHIC DIMENSIONS:")
+	fmt.Printf("Customer Demographics: %s\n", utils.AddUnderscores(counts.CustomerDemographics))
+	fmt.Printf("Household Demographics: %s\n", utils.AddUnderscores(counts.HouseholdDemographics))
+	fmt.Printf("Income Bands:      %s\n", utils.AddUnderscores(counts.IncomeBands))
+
+	fmt.Println("\n🚚 OPERATIONAL DIMENSIONS:")
+	fmt.Printf("Reasons:           %s\n", utils.AddUnderscores(counts.Reasons))
+	fmt.Printf("Ship Modes:        %s\n", utils.AddUnderscores(counts.ShipModes))
+
+	// Calculate business metrics
+	totalSales := counts.StoreSales + counts.WebSales + counts.CatalogSales
+	totalReturns := counts.StoreReturns + counts.WebReturns + counts.CatalogReturns
+	avgOrdersPerCustomer := float64(totalSales) / float64(counts.Customers)
+	overallReturnRate := float64(totalReturns) / float64(totalSales) * 100
+
+	fmt.Println("\n📈 BUSINESS METRICS:")
+	fmt.Printf("Total Sales:       %s\n", utils.AddUnderscores(totalSales))
+	fmt.Printf("Total Returns:      %s\n", utils.AddUnderscores(totalReturns))
+	fmt.Printf("Orders/Customer:   %.1f\n", avgOrdersPerCustomer)
+	fmt.Printf("Return Rate:       %.1f%%\n", overallReturnRate)
+	fmt.Printf("Sales Distribution: Store %.0f%%, Web %.0f%%, Catalog %.0f%%\n",
+		float64(counts.StoreSales)/float64(totalSales)*100,
+		float64(counts.WebSales)/float64(totalSales)*100,
+		float64(counts.CatalogSales)/float64(totalSales)*100)
+}
+
+func printFinancialRowCounts(counts financialsimulation.FinancialRowCounts) {
+	fmt.Println("\n--- Estimated Financial Row Counts ---")
+	fmt.Printf("Companies:             %s\n", utils.AddUnderscores(counts.Companies))
+	fmt.Printf("Exchanges:             %s\n", utils.AddUnderscores(counts.Exchanges))
+	fmt.Printf("Daily Stock Prices:    %s\n", utils.AddUnderscores(counts.DailyStockPrices))
+}
+
+func printMedicalRowCounts(counts medicalsimulation.MedicalRowCounts) {
+	fmt.Println("\n--- Estimated Medical Row Counts ---")
+	fmt.Printf("Patients:      %s\n", utils.AddUnderscores(counts.Patients))
+	fmt.Printf("Doctors:       %s\n", utils.AddUnderscores(counts.Doctors))
+	fmt.Printf("Clinics:       %s\n", utils.AddUnderscores(counts.Clinics))
+	fmt.Printf("Appointments:  %s\n", utils.AddUnderscores(counts.Appointments))
 }
@@ -0,0 +1,421 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/peekknuf/Gengo/internal/formats"
+	"github.com/peekknuf/Gengo/internal/schema"
+)
+
+// ManifestFile is the name of the run manifest in the output directory.
+const ManifestFile = "manifest.json"
+
+// manifestSaveInterval is how often the manifest is saved at most as files are
+// published. Saving it after every file would rewrite the whole list of files
+// for each of them.
+const manifestSaveInterval = time.Second
+
+// runManifest describes a run in its output directory: its configuration, every
+// published file with its row count, size and checksum, and every complete table
+// with its schema and timings. Pipelines use it to verify and load a dataset.
+//
+// It is saved whenever a table completes, when the run stops and, as files are
+// published, at most every manifestSaveInterval, so an interrupted run can be
+// resumed from it: the published files of the earlier attempt are kept and only
+// the missing ones are generated again, from the same seed and row counts.
+type runManifest struct {
+	Model          string                  `json:"model"`
+	ModelFile      string                  `json:"model_file,omitempty"`
+	GengoVersion   string                  `json:"gengo_version"`
+	Seed           int64                   `json:"seed"`
+	TargetGB       float64                 `json:"target_gb"`
+	Format         string                  `json:"format"`
+	CSV            *formats.CSVDialect     `json:"csv,omitempty"`
+	Compression    string                  `json:"compression,omitempty"`
+	ArrowIPC       string                  `json:"arrow_ipc,omitempty"`
+	AvroCodec      string                  `json:"avro_codec,omitempty"`
+	Parquet        *formats.ParquetOptions `json:"parquet,omitempty"`
+	PartitionBy    *formats.Partitioning   `json:"partition_by,omitempty"`
+	DDL            string                  `json:"ddl,omitempty"`
+	RowCounts      json.RawMessage         `json:"row_counts"`
+	Complete       bool                    `json:"complete"`
+	StartedAt      time.Time               `json:"started_at"`
+	FinishedAt     *time.Time              `json:"finished_at,omitempty"`
+	ElapsedSeconds float64                 `json:"elapsed_seconds,omitempty"`
+	Tables         []manifestTable         `json:"tables"`
+	Files          []manifestFile          `json:"files"`
+
+	mu            sync.Mutex
+	dir           string
+	saved         time.Time // when the manifest was last saved
+	tables        map[string]manifestTable
+	files         map[string]manifestFile
+	resumedTables map[string]bool // tables completed by the attempt being resumed
+	resumedFiles  map[string]bool // files published by the attempt being resumed
+}
+
+// manifestTable is a complete table. Rows and Files add up its published files;
+// the timings are wall-clock times of the attempt that generated it.
+type manifestTable struct {
+	Name       string           `json:"name"`
+	Rows       int64            `json:"rows"`
+	Files      int              `json:"files"`
+	Columns    []formats.Column `json:"columns"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Seconds    float64          `json:"seconds"`
+}
+
+// manifestFile is a published file, named relative to the output directory.
+type manifestFile struct {
+	Name   string `json:"name"`
+	Table  string `json:"table"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+func newRunManifest(dir, modelType, format, ddl string, seed int64, targetGB float64, counts interface{}, output OutputOptions) (*runManifest, error) {
+	rowCounts, err := json.Marshal(counts)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding row counts: %w", err)
+	}
+	m := &runManifest{
+		Model:        modelType,
+		GengoVersion: GengoVersion(),
+		Seed:         seed,
+		TargetGB:     targetGB,
+		Format:       format,
+		Compression:  output.Compression,
+		DDL:          ddl,
+		RowCounts:    rowCounts,
+		StartedAt:    time.Now().UTC(),
+		dir:          dir,
+	}
+	if model, err := lookupModel(modelType); err == nil {
+		m.ModelFile = model.file
+	}
+	if format == "csv" {
+		m.CSV = &output.CSV
+	}
+	if formats.DataFormat(format) == "parquet" {
+		m.Parquet = &output.Parquet
+	}
+	if format == "arrow" {
+		m.ArrowIPC = output.ArrowIPC
+	}
+	if format == "avro" {
+		m.AvroCodec = output.AvroCodec
+	}
+	if len(output.Partitioning.Keys) > 0 {
+		m.PartitionBy = &output.Partitioning
+	}
+	m.init()
+	return m, nil
+}
+
+// csvDialect returns the dialect the run writes CSV in. Runs recorded before
+// the dialect could be chosen used the default one.
+func (m *runManifest) csvDialect() formats.CSVDialect {
+	if m.CSV == nil {
+		return formats.DefaultCSVDialect
+	}
+	return *m.CSV
+}
+
+// parquetOptions returns the writer properties of the run's Parquet files.
+// Runs recorded before they could be chosen used the default ones.
+func (m *runManifest) parquetOptions() formats.ParquetOptions {
+	if m.Parquet == nil {
+		return formats.DefaultParquetOptions
+	}
+	return *m.Parquet
+}
+
+// partitioning returns the partitioning of the run's fact tables, which has
+// no keys if they are not partitioned.
+func (m *runManifest) partitioning() formats.Partitioning {
+	if m.PartitionBy == nil {
+		return formats.Partitioning{}
+	}
+	return *m.PartitionBy
+}
+
+// options returns the options the run's files are written with. The manifest
+// is their checkpoint.
+func (m *runManifest) options() formats.Options {
+	o := formats.DefaultOptions(m.Format)
+	o.CSV = m.csvDialect()
+	o.Compression = m.Compression
+	if m.ArrowIPC != "" {
+		o.ArrowIPC = m.ArrowIPC
+	}
+	if m.AvroCodec != "" {
+		o.AvroCodec = m.AvroCodec
+	}
+	o.Parquet = m.parquetOptions()
+	o.Partitioning = m.partitioning()
+	o.Checkpoint = m
+	return o
+}
+
+// loadRunManifest reads the manifest of an earlier run from dir. The model file
+// of a custom model is loaded again.
+func loadRunManifest(dir string) (*runManifest, error) {
+	path := filepath.Join(dir, ManifestFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading run manifest: %w", err)
+	}
+	m := &runManifest{dir: dir}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("error parsing run manifest %s: %w", path, err)
+	}
+	if m.ModelFile != "" {
+		name, err := LoadModelFile(m.ModelFile)
+		if err != nil {
+			return nil, err
+		}
+		if name != m.Model {
+			return nil, fmt.Errorf("model file %s now declares model %s, not %s", m.ModelFile, name, m.Model)
+		}
+	}
+	m.init()
+	for _, table := range m.Tables {
+		m.tables[table.Name] = table
+		m.resumedTables[table.Name] = true
+	}
+	for _, file := range m.Files {
+		m.files[file.Name] = file
+		m.resumedFiles[file.Name] = true
+	}
+	return m, nil
+}
+
+func (m *runManifest) init() {
+	m.tables = make(map[string]manifestTable)
+	m.files = make(map[string]manifestFile)
+	m.resumedTables = make(map[string]bool)
+	m.resumedFiles = make(map[string]bool)
+}
+
+// rowCounts decodes the row counts recorded for the manifest's model.
+func (m *runManifest) rowCounts() (interface{}, error) {
+	model, err := lookupModel(m.Model)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported model type in run manifest: %s", m.Model)
+	}
+	counts, err := model.decodeRowCounts(m.RowCounts)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing row counts in run manifest: %w", err)
+	}
+	return counts, nil
+}
+
+// Completed reports whether the attempt being resumed published filename and
+// the file is still there.
+func (m *runManifest) Completed(filename string) bool {
+	m.mu.Lock()
+	done := m.resumedFiles[m.fileName(filename)]
+	m.mu.Unlock()
+	if !done {
+		return false
+	}
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// CompletedMatching reports whether the attempt being resumed published files
+// whose names match pattern and they are all still there.
+func (m *runManifest) CompletedMatching(pattern string) bool {
+	m.mu.Lock()
+	var names []string
+	for name := range m.resumedFiles {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	m.mu.Unlock()
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(m.dir, filepath.FromSlash(name))); err != nil {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+// Published records newly published files. They are saved with the manifest
+// once manifestSaveInterval has passed since it was last saved.
+func (m *runManifest) Published(files map[string]formats.FileInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for filename, info := range files {
+		name := m.fileName(filename)
+		m.files[name] = manifestFile{
+			Name:   name,
+			Table:  formats.TableOfFile(name),
+			Rows:   info.Rows,
+			Bytes:  info.Bytes,
+			SHA256: info.SHA256,
+		}
+	}
+	if time.Since(m.saved) < manifestSaveInterval {
+		return nil
+	}
+	return m.save()
+}
+
+// fileName returns the name of an output file in the manifest: its path
+// relative to the output directory, with forward slashes.
+func (m *runManifest) fileName(filename string) string {
+	name, err := filepath.Rel(m.dir, filename)
+	if err != nil {
+		return filepath.Base(filename)
+	}
+	return filepath.ToSlash(name)
+}
+
+// tableFiles returns the published files of table, sorted.
+func (m *runManifest) tableFiles(table string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var files []string
+	for _, file := range m.files {
+		if file.Table == table {
+			files = append(files, filepath.Join(m.dir, filepath.FromSlash(file.Name)))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// tableResumed reports whether the attempt being resumed completed table.
+func (m *runManifest) tableResumed(table string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resumedTables[table]
+}
+
+// tableComplete records that every file of the given tables, generated since
+// started, has been published. The schema of each table is read from its first
+// file.
+func (m *runManifest) tableComplete(started time.Time, tables ...string) error {
+	finished := time.Now().UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range tables {
+		table := manifestTable{
+			Name:       name,
+			StartedAt:  started.UTC(),
+			FinishedAt: finished,
+			Seconds:    finished.Sub(started).Seconds(),
+		}
+		var first string
+		for _, file := range m.files {
+			if file.Table != name {
+				continue
+			}
+			table.Rows += file.Rows
+			table.Files++
+			if first == "" || file.Name < first {
+				first = file.Name
+			}
+		}
+		if first != "" {
+			columns, err := formats.FileColumns(filepath.Join(m.dir, first), m.csvDialect())
+			if err != nil {
+				return err
+			}
+			if !formats.NamesColumns(first, m.csvDialect()) {
+				if columns, err = m.schemaColumns(name); err != nil {
+					return err
+				}
+			}
+			table.Columns = columns
+		}
+		m.tables[name] = table
+	}
+	return m.save()
+}
+
+// schemaColumns returns the columns of table as the model declares them, for
+// files that do not name their columns.
+func (m *runManifest) schemaColumns(table string) ([]formats.Column, error) {
+	tables, err := schema.Tables(m.Model)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range tables {
+		if t.Name == table {
+			if names, err = columnNames(t, m.Format); err != nil {
+				return nil, err
+			}
+		}
+	}
+	columns := make([]formats.Column, len(names))
+	for i, name := range names {
+		columns[i] = formats.Column{Name: name}
+	}
+	return columns, nil
+}
+
+// write saves the manifest as it is.
+func (m *runManifest) write() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save()
+}
+
+// finish marks the run complete. The elapsed time spans every attempt of the run.
+func (m *runManifest) finish() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	finished := time.Now().UTC()
+	m.Complete = true
+	m.FinishedAt = &finished
+	m.ElapsedSeconds = finished.Sub(m.StartedAt).Seconds()
+	return m.save()
+}
+
+// save writes the manifest to a temporary file and renames it into place, so a
+// crash never leaves a truncated manifest. The caller holds m.mu.
+func (m *runManifest) save() error {
+	m.Tables = make([]manifestTable, 0, len(m.tables))
+	for _, name := range sortedKeys(m.tables) {
+		m.Tables = append(m.Tables, m.tables[name])
+	}
+	m.Files = make([]manifestFile, 0, len(m.files))
+	for _, name := range sortedKeys(m.files) {
+		m.Files = append(m.Files, m.files[name])
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding run manifest: %w", err)
+	}
+	path := filepath.Join(m.dir, ManifestFile)
+	if err := os.WriteFile(formats.TempName(path), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing run manifest: %w", err)
+	}
+	if err := os.Rename(formats.TempName(path), path); err != nil {
+		return fmt.Errorf("error writing run manifest: %w", err)
+	}
+	m.saved = time.Now()
+	return nil
+}
+
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,344 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/peekknuf/Gengo/internal/formats"
+	"github.com/peekknuf/Gengo/internal/schema"
+	financialsimulation "github.com/peekknuf/Gengo/internal/simulation/financial"
+	medicalsimulation "github.com/peekknuf/Gengo/internal/simulation/medical"
+	"github.com/peekknuf/Gengo/internal/utils"
+)
+
+// Model plugs a data model into gengo. C is the type of its row counts, which
+// are recorded in the run manifest as JSON.
+type Model[C any] struct {
+	// Name is the canonical name of the model; Aliases are accepted as well.
+	Name    string
+	Aliases []string
+	// File is the model file a custom model was read from.
+	File string
+	// Formats are the output formats the model can be written in besides
+	// csv, json, parquet, arrow, avro and the table formats.
+	Formats []string
+	// PartitionedFacts is set if the fact tables can be written partitioned
+	// with --partition-by.
+	PartitionedFacts bool
+	// Tables are the tables of the model in dependency order. A table's
+	// RowCount names the field of C (or the map key) that sizes it.
+	Tables []schema.Table
+	// RowCounts sizes the tables of the model for a target size in format.
+	RowCounts func(targetGB float64, format string) (C, error)
+	// ScaleFactorRowCounts, if set, sizes the tables for a benchmark scale
+	// factor instead of a target size.
+	ScaleFactorRowCounts func(scaleFactor int) (C, error)
+	// CheckRowCounts, if set, rejects row counts the model cannot generate,
+	// such as ones set with --rows.
+	CheckRowCounts func(counts C) error
+	// PrintRowCounts prints the planned row counts. If it is nil, the row
+	// count of every table is listed.
+	PrintRowCounts func(counts C)
+	// Generate generates every table and writes it to outputDir, running each
+	// table through tables.Go so its completion is recorded.
+	Generate func(ctx context.Context, tables *tableTracker, counts C, opts formats.Options, outputDir string, seed int64) error
+}
+
+// registeredModel is a Model with its row counts type erased.
+type registeredModel struct {
+	name    string
+	file    string
+	formats []string
+	tables  []schema.Table
+
+	partitionedFacts bool
+
+	rowCounts            func(targetGB float64, format string) (interface{}, error)
+	scaleFactorRowCounts func(scaleFactor int) (interface{}, error)
+	decodeRowCounts      func(data []byte) (interface{}, error)
+	checkRowCounts       func(counts interface{}) error
+	printRowCounts       func(counts interface{})
+	generate             func(ctx context.Context, tables *tableTracker, counts interface{}, opts formats.Options, outputDir string, seed int64) error
+}
+
+var (
+	modelsMu sync.Mutex
+	models   = make(map[string]*registeredModel) // by name and alias
+)
+
+// RegisterModel makes m available to generation, sizing, the DDL, validation
+// and resumed runs. A model registered again replaces the earlier one.
+func RegisterModel[C any](m Model[C]) error {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	for _, name := range append([]string{m.Name}, m.Aliases...) {
+		if other, ok := models[name]; ok && other.name != m.Name {
+			return fmt.Errorf("model name %s is taken by the %s model", name, other.name)
+		}
+	}
+
+	r := &registeredModel{
+		name:    m.Name,
+		file:    m.File,
+		formats: append(append([]string{"csv", "json", "parquet", "arrow", "avro"}, formats.TableFormats...), m.Formats...),
+		tables:  m.Tables,
+
+		partitionedFacts: m.PartitionedFacts,
+		rowCounts: func(targetGB float64, format string) (interface{}, error) {
+			return m.RowCounts(targetGB, formats.DataFormat(format))
+		},
+		decodeRowCounts: func(data []byte) (interface{}, error) {
+			var counts C
+			err := json.Unmarshal(data, &counts)
+			return counts, err
+		},
+		checkRowCounts: func(counts interface{}) error {
+			if m.CheckRowCounts == nil {
+				return nil
+			}
+			return m.CheckRowCounts(counts.(C))
+		},
+		generate: func(ctx context.Context, tables *tableTracker, counts interface{}, opts formats.Options, outputDir string, seed int64) error {
+			return m.Generate(ctx, tables, counts.(C), opts, outputDir, seed)
+		},
+	}
+	if m.ScaleFactorRowCounts != nil {
+		r.scaleFactorRowCounts = func(scaleFactor int) (interface{}, error) {
+			return m.ScaleFactorRowCounts(scaleFactor)
+		}
+	}
+	if m.PrintRowCounts != nil {
+		r.printRowCounts = func(counts interface{}) { m.PrintRowCounts(counts.(C)) }
+	} else {
+		r.printRowCounts = r.printTableRowCounts
+	}
+
+	for name, other := range models {
+		if other.name == m.Name {
+			delete(models, name)
+		}
+	}
+	for _, name := range append([]string{m.Name}, m.Aliases...) {
+		models[name] = r
+	}
+	schema.Register(m.Name, m.Tables)
+	return nil
+}
+
+// lookupModel returns the model called name or one of its aliases.
+func lookupModel(name string) (*registeredModel, error) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	if m, ok := models[strings.ToLower(name)]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported model type: %s. Please choose %s", name, strings.Join(modelNamesLocked(), ", "))
+}
+
+// ModelNames returns the names of the registered models, sorted.
+func ModelNames() []string {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	return modelNamesLocked()
+}
+
+func modelNamesLocked() []string {
+	var names []string
+	for name, m := range models {
+		if name == m.name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkFormat returns an error unless m can be written in format.
+func (m *registeredModel) checkFormat(format string) error {
+	for _, f := range m.formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format: %s. Please choose %s", format, strings.Join(m.formats, ", "))
+}
+
+// CheckPartitioning returns an error unless the fact tables of modelType can
+// be partitioned by p: the model must support it and at least one of its fact
+// tables must have the partition keys.
+func CheckPartitioning(modelType string, p formats.Partitioning) error {
+	if len(p.Keys) == 0 {
+		return nil
+	}
+	if err := p.Check(); err != nil {
+		return err
+	}
+	model, err := lookupModel(modelType)
+	if err != nil {
+		return err
+	}
+	if !model.partitionedFacts {
+		return fmt.Errorf("the %s model cannot write partitioned fact tables", model.name)
+	}
+	for _, t := range model.tables {
+		if !strings.HasPrefix(t.Name, "fact_") {
+			continue
+		}
+		columns, err := columnNames(t, "parquet")
+		if err != nil {
+			return err
+		}
+		if _, ok := p.Columns(columns); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("no fact table of the %s model has the partition keys %s", model.name, strings.Join(p.Keys, ", "))
+}
+
+// setRowCounts returns counts with the row counts of the tables in rows, keyed
+// by table name, replaced. A table that scales with a table in rows keeps its
+// ratio to it unless it is in rows too; the other tables keep their row counts.
+func (m *registeredModel) setRowCounts(counts interface{}, rows map[string]int) (interface{}, error) {
+	byField, err := fieldRowCounts(counts)
+	if err != nil {
+		return nil, err
+	}
+	planned := make(map[string]int, len(byField))
+	for field, n := range byField {
+		planned[field] = n
+	}
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t, ok := m.table(name)
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("model %s has no table %s", m.name, name)
+		case t.RowCount == "":
+			return nil, fmt.Errorf("the row count of %s is fixed and cannot be set", name)
+		case t.Derived:
+			return nil, fmt.Errorf("the row count of %s follows from other tables and cannot be set", name)
+		case rows[name] <= 0:
+			return nil, fmt.Errorf("row count of %s must be positive", name)
+		}
+		byField[t.RowCount] = rows[name]
+	}
+	for _, t := range m.tables {
+		base, ok := m.table(t.ScalesWith)
+		if _, set := rows[t.Name]; set || !ok || planned[base.RowCount] == 0 {
+			continue
+		}
+		if _, set := rows[base.Name]; set {
+			ratio := float64(planned[t.RowCount]) / float64(planned[base.RowCount])
+			byField[t.RowCount] = int(math.Round(float64(rows[base.Name]) * ratio))
+		}
+	}
+
+	data, err := json.Marshal(byField)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding row counts: %w", err)
+	}
+	if counts, err = m.decodeRowCounts(data); err != nil {
+		return nil, fmt.Errorf("error decoding row counts: %w", err)
+	}
+	if err := m.checkRowCounts(counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (m *registeredModel) table(name string) (schema.Table, bool) {
+	for _, t := range m.tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return schema.Table{}, false
+}
+
+// fieldRowCounts returns counts as a map from field name, or table name for a
+// custom model, to row count.
+func fieldRowCounts(counts interface{}) (map[string]int, error) {
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding row counts: %w", err)
+	}
+	var byField map[string]int
+	if err := json.Unmarshal(data, &byField); err != nil {
+		return nil, fmt.Errorf("error decoding row counts: %w", err)
+	}
+	return byField, nil
+}
+
+// printTableRowCounts lists the planned row count of every table of m.
+func (m *registeredModel) printTableRowCounts(counts interface{}) {
+	byField, err := fieldRowCounts(counts)
+	if err != nil {
+		return
+	}
+	fmt.Printf("\n--- Estimated %s Row Counts ---\n", m.name)
+	for _, t := range m.tables {
+		if n, ok := byField[t.RowCount]; ok && t.RowCount != "" {
+			fmt.Printf("%-22s %s\n", t.Name+":", utils.AddUnderscores(n))
+		}
+	}
+}
+
+func init() {
+	for _, err := range []error{
+		RegisterModel(Model[ECommerceRowCounts]{
+			Name:    "ecommerce",
+			Aliases: []string{"ecom", "e-commerce", "e"},
+			Tables:  schema.ECommerceTables,
+			RowCounts: func(targetGB float64, format string) (ECommerceRowCounts, error) {
+				return CalculateECommerceRowCounts(targetGB)
+			},
+			PrintRowCounts: printECommerceRowCounts,
+			Generate:       generateECommerceDataConcurrently,
+		}),
+		RegisterModel(Model[ECommerceDSRowCounts]{
+			Name:                 "ecommerce-ds",
+			Aliases:              []string{"ecom-ds", "e-commerce-ds", "eds"},
+			Formats:              []string{"dsdgen"},
+			PartitionedFacts:     true,
+			Tables:               schema.ECommerceDSTables,
+			RowCounts:            CalculateECommerceDSRowCounts,
+			ScaleFactorRowCounts: ECommerceDSScaleFactorRowCounts,
+			CheckRowCounts:       checkECommerceDSRowCounts,
+			PrintRowCounts:       printECommerceDSRowCounts,
+			Generate:             generateECommerceDSDataConcurrently,
+		}),
+		RegisterModel(Model[financialsimulation.FinancialRowCounts]{
+			Name:    "financial",
+			Aliases: []string{"fin", "f"},
+			Tables:  schema.FinancialTables,
+			RowCounts: func(targetGB float64, format string) (financialsimulation.FinancialRowCounts, error) {
+				return CalculateFinancialRowCounts(targetGB)
+			},
+			PrintRowCounts: printFinancialRowCounts,
+			Generate:       generateFinancialDataConcurrently,
+		}),
+		RegisterModel(Model[medicalsimulation.MedicalRowCounts]{
+			Name:    "medical",
+			Aliases: []string{"med", "m"},
+			Tables:  schema.MedicalTables,
+			RowCounts: func(targetGB float64, format string) (medicalsimulation.MedicalRowCounts, error) {
+				return CalculateMedicalRowCounts(targetGB)
+			},
+			PrintRowCounts: printMedicalRowCounts,
+			Generate:       generateMedicalDataConcurrently,
+		}),
+	} {
+		if err != nil {
+			panic(err)
+		}
+	}
+}
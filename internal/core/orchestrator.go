@@ -1,9 +1,12 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,15 +14,19 @@ import (
 	ecommercemodels "github.com/peekknuf/Gengo/internal/models/ecommerce"
 	financialmodels "github.com/peekknuf/Gengo/internal/models/financial"
 	medicalmodels "github.com/peekknuf/Gengo/internal/models/medical"
+	"github.com/peekknuf/Gengo/internal/schema"
 	"github.com/peekknuf/Gengo/internal/simulation/ecommerce"
 	ecommercedssimulation "github.com/peekknuf/Gengo/internal/simulation/ecommerce-ds"
 	financialsimulation "github.com/peekknuf/Gengo/internal/simulation/financial"
 	medicalsimulation "github.com/peekknuf/Gengo/internal/simulation/medical"
+	"golang.org/x/sync/errgroup"
 )
 
 // ECommerceDSRowCounts defines the number of rows for each table in the TPC-DS model.
-
+// ScaleFactor is set when they are the row counts of a TPC-DS scale factor,
+// which also switches to the dsdgen calendar.
 type ECommerceDSRowCounts struct {
+	ScaleFactor           int `json:",omitempty"`
 	Customers             int
 	CustomerAddresses     int
 	CustomerDemographics  int
@@ -44,33 +51,125 @@ type ECommerceDSRowCounts struct {
 	Inventory             int
 }
 
-// GenerateModelData orchestrates the generation and writing of the relational model.
-func GenerateModelData(modelType string, counts interface{}, format string, outputDir string) error {
-	startTime := time.Now()
+// OutputOptions are the settings of a run's output files beyond their format.
+type OutputOptions struct {
+	// CSV is the dialect CSV output is written in.
+	CSV formats.CSVDialect
+	// Compression is the codec CSV and JSON Lines files, or the buffers of
+	// Arrow IPC files, are compressed with, or "" for none.
+	Compression string
+	// ArrowIPC is the variant Arrow IPC files are written in, file or stream.
+	ArrowIPC string
+	// AvroCodec is the codec the blocks of Avro files are compressed with.
+	AvroCodec string
+	// Parquet are the writer properties of Parquet files.
+	Parquet formats.ParquetOptions
+	// Partitioning splits the files of fact tables into partitions; it has no
+	// keys if they are written unpartitioned.
+	Partitioning formats.Partitioning
+}
+
+// DefaultOutputOptions write uncompressed text files in the default CSV
+// dialect, Parquet files with the default writer properties, Arrow IPC files in
+// the file format and Avro files in deflate blocks.
+var DefaultOutputOptions = OutputOptions{CSV: formats.DefaultCSVDialect, ArrowIPC: "file", AvroCodec: "deflate", Parquet: formats.DefaultParquetOptions}
 
+// GenerateModelData orchestrates the generation and writing of the relational model.
+// Every file is written under a temporary name and renamed into place once
+// complete, and a _SUCCESS marker is written when the whole run has finished.
+// Progress is recorded in the run manifest, so if ctx is cancelled or a table
+// fails the run can be picked up again with ResumeModelData. If ddl names a SQL
+// dialect, the CREATE TABLE statements of the model are written to schema.sql.
+// The files are written with the output options.
+func GenerateModelData(ctx context.Context, modelType string, counts interface{}, format string, outputDir string, seed int64, targetGB float64, ddl string, output OutputOptions) error {
 	err := os.MkdirAll(outputDir, 0755)
 	if err != nil {
 		return fmt.Errorf("error creating output directory %s: %w", outputDir, err)
 	}
 	fmt.Printf("Ensured output directory exists: %s\n", outputDir)
 
-	switch modelType {
-	case "ecommerce":
-		err = generateECommerceDataConcurrently(counts.(ECommerceRowCounts), format, outputDir)
-	case "ecommerce-ds":
-		err = generateECommerceDSDataConcurrently(counts.(ECommerceDSRowCounts), format, outputDir)
-	case "financial":
-		err = generateFinancialDataConcurrently(counts.(financialsimulation.FinancialRowCounts), format, outputDir)
-	case "medical":
-		err = generateMedicalDataConcurrently(counts.(medicalsimulation.MedicalRowCounts), format, outputDir)
-	default:
-		err = fmt.Errorf("unsupported model type: %s", modelType)
+	manifest, err := newRunManifest(outputDir, modelType, format, ddl, seed, targetGB, counts, output)
+	if err != nil {
+		return err
+	}
+	return runModelData(ctx, manifest, counts)
+}
+
+// ResumeModelData continues the interrupted run in outputDir. The model, format,
+// seed and row counts come from the run manifest; files the earlier attempt
+// published are kept and only the missing ones are generated, so the result is
+// identical to an uninterrupted run.
+func ResumeModelData(ctx context.Context, outputDir string) error {
+	manifest, err := loadRunManifest(outputDir)
+	if err != nil {
+		return err
+	}
+	if manifest.Complete {
+		fmt.Printf("The run in %s is already complete.\n", outputDir)
+		return nil
 	}
+	counts, err := manifest.rowCounts()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Resuming %s run in %s (format %s, seed %d): %d tables and %d files already complete.\n",
+		manifest.Model, outputDir, manifest.Format, manifest.Seed, len(manifest.Tables), len(manifest.Files))
+	return runModelData(ctx, manifest, counts)
+}
 
+func runModelData(ctx context.Context, manifest *runManifest, counts interface{}) error {
+	startTime := time.Now()
+	modelType, format, outputDir, seed := manifest.Model, manifest.Format, manifest.dir, manifest.Seed
+	model, err := lookupModel(modelType)
 	if err != nil {
 		return err
 	}
 
+	// A marker left by an earlier run must not vouch for this one.
+	successMarker := filepath.Join(outputDir, formats.SuccessMarker)
+	if err := os.Remove(successMarker); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing stale %s: %w", successMarker, err)
+	}
+	if err := manifest.write(); err != nil {
+		return err
+	}
+	opts := manifest.options()
+	if manifest.DDL != "" {
+		ddl, err := schema.DDL(modelType, format, manifest.DDL, opts.CSV)
+		if err != nil {
+			return err
+		}
+		if err := WriteSchemaFile(outputDir, ddl); err != nil {
+			return err
+		}
+	}
+	tables := newTableTracker(manifest, opts)
+	// The table formats are written by the Parquet writers; the metadata of a
+	// table is committed once it is complete.
+	err = model.generate(ctx, tables, counts, opts, outputDir, seed)
+	if err != nil {
+		// Record the files published since the manifest was last saved, so a
+		// resumed run keeps them. The error that stopped the run is reported.
+		manifest.write()
+		complete, incomplete := tables.split()
+		fmt.Printf("\nGeneration stopped after %s.\n", time.Since(startTime).Round(time.Second))
+		if len(complete) > 0 {
+			fmt.Printf("Complete tables: %s\n", strings.Join(complete, ", "))
+		}
+		if len(incomplete) > 0 {
+			fmt.Printf("Incomplete tables: %s\n", strings.Join(incomplete, ", "))
+		}
+		fmt.Printf("Continue the run with: gengo gen --resume %s\n", outputDir)
+		return err
+	}
+
+	if err := manifest.finish(); err != nil {
+		return err
+	}
+	if err := formats.WriteSuccessMarker(outputDir); err != nil {
+		return err
+	}
+
 	fmt.Printf("\nTotal model generation completed in %s.\n", time.Since(startTime).Round(time.Second))
 
 	fmt.Println("\nGenerated files:")
@@ -93,9 +192,20 @@ func GenerateModelData(modelType string, counts interface{}, format string, outp
 	return nil
 }
 
-func generateECommerceDSDataConcurrently(counts ECommerceDSRowCounts, format string, outputDir string) error {
-	errChan := make(chan error, 30) // Buffer for all goroutines
+// ecommerceDSDates returns the first year of sales, the date dimension and the
+// days sales fall on for ecommerce-ds row counts. Row counts of a TPC-DS scale
+// factor come with the dsdgen calendar.
+func ecommerceDSDates(counts ECommerceDSRowCounts) (firstYear int, dateDim, salesDates []interface{}) {
+	if counts.ScaleFactor > 0 {
+		dateDim = ecommercedssimulation.GenerateTPCDSDateDim()
+		return ecommercedssimulation.TPCDSFirstSalesYear, dateDim, ecommercedssimulation.TPCDSSalesDates(dateDim)
+	}
+	firstYear = 2020
+	dateDim = ecommercedssimulation.GenerateDateDim(firstYear, firstYear+5)
+	return firstYear, dateDim, dateDim
+}
 
+func generateECommerceDSDataConcurrently(ctx context.Context, tables *tableTracker, counts ECommerceDSRowCounts, opts formats.Options, outputDir string, seed int64) error {
 	var items []interface{}
 	var customers []interface{}
 	var customerAddresses []interface{}
@@ -112,101 +222,111 @@ func generateECommerceDSDataConcurrently(counts ECommerceDSRowCounts, format str
 	var shipModes []interface{}
 	var incomeBands []interface{}
 	var timeDim []interface{}
-	var dateDim []interface{}
+
+	firstYear, dateDim, salesDates := ecommerceDSDates(counts)
 
 	// --- Dimension Generation (Serial) ---
-	items = ecommercedssimulation.GenerateItems(counts.Items)
-	customerAddresses = ecommercedssimulation.GenerateCustomerAddresses(counts.CustomerAddresses)
-	customerDemographics = ecommercedssimulation.GenerateCustomerDemographics(counts.CustomerDemographics)
-	incomeBands = ecommercedssimulation.GenerateIncomeBands(counts.IncomeBands)
-	stores = ecommercedssimulation.GenerateStores(counts.Stores)
-	callCenters = ecommercedssimulation.GenerateCallCenters(counts.CallCenters)
-	catalogPages = ecommercedssimulation.GenerateCatalogPages(counts.CatalogPages)
-	webSites = ecommercedssimulation.GenerateWebSites(counts.WebSites)
-	webPages = ecommercedssimulation.GenerateWebPages(counts.WebPages)
-	warehouses = ecommercedssimulation.GenerateWarehouses(counts.Warehouses)
-	reasons = ecommercedssimulation.GenerateReasons(counts.Reasons)
-	shipModes = ecommercedssimulation.GenerateShipModes(counts.ShipModes)
-	timeDim = ecommercedssimulation.GenerateTimeDim()
-	dateDim = ecommercedssimulation.GenerateDateDim(2020, 2025)
-
-	householdDemographics = ecommercedssimulation.GenerateHouseholdDemographics(counts.HouseholdDemographics, getSKsFromSlice(incomeBands))
-	promotions = ecommercedssimulation.GeneratePromotions(counts.Promotions, getSKsFromSlice(items))
-	customers = ecommercedssimulation.GenerateCustomers(counts.Customers, getSKsFromSlice(customerDemographics), getSKsFromSlice(householdDemographics), getSKsFromSlice(customerAddresses))
-
-	var writersWg sync.WaitGroup
-	writersWg.Add(17) // All dimension writers
+	// An interrupted run stops between dimensions.
+	dimensions := []func(){
+		func() { items = ecommercedssimulation.GenerateItems(counts.Items, seed) },
+		func() {
+			customerAddresses = ecommercedssimulation.GenerateCustomerAddresses(counts.CustomerAddresses, seed)
+		},
+		func() {
+			customerDemographics = ecommercedssimulation.GenerateCustomerDemographics(counts.CustomerDemographics, seed)
+		},
+		func() { incomeBands = ecommercedssimulation.GenerateIncomeBands(counts.IncomeBands) },
+		func() { stores = ecommercedssimulation.GenerateStores(counts.Stores, seed) },
+		func() { callCenters = ecommercedssimulation.GenerateCallCenters(counts.CallCenters, seed) },
+		func() {
+			catalogPages = ecommercedssimulation.GenerateCatalogPages(counts.CatalogPages, firstYear, seed)
+		},
+		func() { webSites = ecommercedssimulation.GenerateWebSites(counts.WebSites, seed) },
+		func() { webPages = ecommercedssimulation.GenerateWebPages(counts.WebPages, seed) },
+		func() { warehouses = ecommercedssimulation.GenerateWarehouses(counts.Warehouses, seed) },
+		func() { reasons = ecommercedssimulation.GenerateReasons(counts.Reasons) },
+		func() { shipModes = ecommercedssimulation.GenerateShipModes(counts.ShipModes, seed) },
+		func() { timeDim = ecommercedssimulation.GenerateTimeDim() },
+
+		func() {
+			householdDemographics = ecommercedssimulation.GenerateHouseholdDemographics(counts.HouseholdDemographics, getSKsFromSlice(incomeBands), seed)
+		},
+		func() {
+			promotions = ecommercedssimulation.GeneratePromotions(counts.Promotions, getSKsFromSlice(items), firstYear, seed)
+		},
+		func() {
+			customers = ecommercedssimulation.GenerateCustomers(counts.Customers, getSKsFromSlice(customerDemographics), getSKsFromSlice(householdDemographics), getSKsFromSlice(customerAddresses), seed)
+		},
+	}
+	for _, generate := range dimensions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		generate()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(items, "dim_items", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(customers, "dim_customers", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(customerAddresses, "dim_customer_addresses", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(customerDemographics, "dim_customer_demographics", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(householdDemographics, "dim_household_demographics", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(promotions, "dim_promotions", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(stores, "dim_stores", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(callCenters, "dim_call_centers", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(catalogPages, "dim_catalog_pages", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(webSites, "dim_web_sites", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(webPages, "dim_web_pages", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(warehouses, "dim_warehouses", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(reasons, "dim_reasons", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(shipModes, "dim_ship_modes", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(incomeBands, "dim_income_bands", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(timeDim, "dim_time", format, outputDir)
-	}()
-	go func() {
-		defer writersWg.Done()
-		errChan <- formats.WriteSliceData(dateDim, "dim_date", format, outputDir)
-	}()
+	// Dimension writers and fact generators share one group: the first error
+	// cancels the fact workers and is returned.
+	g, ctx := errgroup.WithContext(ctx)
+
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, items, "dim_items", opts, outputDir)
+	}, "dim_items")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, customers, "dim_customers", opts, outputDir)
+	}, "dim_customers")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, customerAddresses, "dim_customer_addresses", opts, outputDir)
+	}, "dim_customer_addresses")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, customerDemographics, "dim_customer_demographics", opts, outputDir)
+	}, "dim_customer_demographics")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, householdDemographics, "dim_household_demographics", opts, outputDir)
+	}, "dim_household_demographics")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, promotions, "dim_promotions", opts, outputDir)
+	}, "dim_promotions")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, stores, "dim_stores", opts, outputDir)
+	}, "dim_stores")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, callCenters, "dim_call_centers", opts, outputDir)
+	}, "dim_call_centers")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, catalogPages, "dim_catalog_pages", opts, outputDir)
+	}, "dim_catalog_pages")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, webSites, "dim_web_sites", opts, outputDir)
+	}, "dim_web_sites")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, webPages, "dim_web_pages", opts, outputDir)
+	}, "dim_web_pages")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, warehouses, "dim_warehouses", opts, outputDir)
+	}, "dim_warehouses")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, reasons, "dim_reasons", opts, outputDir)
+	}, "dim_reasons")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, shipModes, "dim_ship_modes", opts, outputDir)
+	}, "dim_ship_modes")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, incomeBands, "dim_income_bands", opts, outputDir)
+	}, "dim_income_bands")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, timeDim, "dim_time", opts, outputDir)
+	}, "dim_time")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, dateDim, "dim_date", opts, outputDir)
+	}, "dim_date")
 
 	dimSKs := map[string][]int64{
+		"date":                   getSKsFromSlice(salesDates),
+		"time":                   getSKsFromSlice(timeDim),
 		"items":                  getSKsFromSlice(items),
 		"customers":              getSKsFromSlice(customers),
 		"customer_addresses":     getSKsFromSlice(customerAddresses),
@@ -220,38 +340,37 @@ func generateECommerceDSDataConcurrently(counts ECommerceDSRowCounts, format str
 		"web_pages":              getSKsFromSlice(webPages),
 		"warehouses":             getSKsFromSlice(warehouses),
 		"ship_modes":             getSKsFromSlice(shipModes),
+		"reasons":                getSKsFromSlice(reasons),
 	}
 
-	writersWg.Add(3) // One for each fact table generator
-	go func() {
-		defer writersWg.Done()
-		if err := ecommercedssimulation.GenerateStoreSalesOptimized(counts.StoreSales, dimSKs["items"], dimSKs["customers"], dimSKs["stores"], dimSKs["promotions"], outputDir, format); err != nil {
-			errChan <- fmt.Errorf("failed to generate store sales: %w", err)
+	inventoryDateSKs := ecommercedssimulation.InventorySnapshotDateSKs(salesDates)
+
+	tables.Go(g, func() error {
+		if err := ecommercedssimulation.GenerateStoreSalesOptimized(ctx, counts.StoreSales, counts.StoreReturns, dimSKs["date"], dimSKs["time"], dimSKs["items"], dimSKs["customers"], dimSKs["customer_demographics"], dimSKs["household_demographics"], dimSKs["customer_addresses"], dimSKs["stores"], dimSKs["promotions"], dimSKs["reasons"], outputDir, opts, seed); err != nil {
+			return fmt.Errorf("failed to generate store sales and returns: %w", err)
 		}
-	}()
-	go func() {
-		defer writersWg.Done()
-		if err := ecommercedssimulation.GenerateCatalogSalesOptimized(counts.CatalogSales, dimSKs["items"], dimSKs["customers"], dimSKs["customer_demographics"], dimSKs["household_demographics"], dimSKs["customer_addresses"], dimSKs["call_centers"], dimSKs["catalog_pages"], dimSKs["ship_modes"], dimSKs["warehouses"], dimSKs["promotions"], outputDir, format); err != nil {
-			errChan <- fmt.Errorf("failed to generate catalog sales: %w", err)
+		return nil
+	}, "fact_store_sales", "fact_store_returns")
+	tables.Go(g, func() error {
+		if err := ecommercedssimulation.GenerateCatalogSalesOptimized(ctx, counts.CatalogSales, counts.CatalogReturns, dimSKs["date"], dimSKs["time"], dimSKs["items"], dimSKs["customers"], dimSKs["customer_demographics"], dimSKs["household_demographics"], dimSKs["customer_addresses"], dimSKs["call_centers"], dimSKs["catalog_pages"], dimSKs["ship_modes"], dimSKs["warehouses"], dimSKs["promotions"], dimSKs["reasons"], outputDir, opts, seed); err != nil {
+			return fmt.Errorf("failed to generate catalog sales and returns: %w", err)
 		}
-	}()
-	go func() {
-		defer writersWg.Done()
-		if err := ecommercedssimulation.GenerateWebSalesOptimized(counts.WebSales, dimSKs["items"], dimSKs["customers"], dimSKs["customer_demographics"], dimSKs["household_demographics"], dimSKs["customer_addresses"], dimSKs["web_pages"], dimSKs["web_sites"], dimSKs["ship_modes"], dimSKs["warehouses"], dimSKs["promotions"], outputDir, format); err != nil {
-			errChan <- fmt.Errorf("failed to generate web sales: %w", err)
+		return nil
+	}, "fact_catalog_sales", "fact_catalog_returns")
+	tables.Go(g, func() error {
+		if err := ecommercedssimulation.GenerateWebSalesOptimized(ctx, counts.WebSales, counts.WebReturns, dimSKs["date"], dimSKs["time"], dimSKs["items"], dimSKs["customers"], dimSKs["customer_demographics"], dimSKs["household_demographics"], dimSKs["customer_addresses"], dimSKs["web_pages"], dimSKs["web_sites"], dimSKs["ship_modes"], dimSKs["warehouses"], dimSKs["promotions"], dimSKs["reasons"], outputDir, opts, seed); err != nil {
+			return fmt.Errorf("failed to generate web sales and returns: %w", err)
 		}
-	}()
-
-	writersWg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
-			return err // Return the first error encountered
+		return nil
+	}, "fact_web_sales", "fact_web_returns")
+	tables.Go(g, func() error {
+		if err := ecommercedssimulation.GenerateInventoryOptimized(ctx, counts.Inventory, inventoryDateSKs, dimSKs["items"], dimSKs["warehouses"], outputDir, opts, seed); err != nil {
+			return fmt.Errorf("failed to generate inventory: %w", err)
 		}
-	}
+		return nil
+	}, "fact_inventory")
 
-	return nil
+	return g.Wait()
 }
 
 func getSKsFromSlice(slice []interface{}) []int64 {
@@ -275,10 +394,7 @@ func getSKs(slice interface{}) []int64 {
 	return sks
 }
 
-func generateECommerceDataConcurrently(counts ECommerceRowCounts, format string, outputDir string) error {
-	var writersWg sync.WaitGroup
-	errChan := make(chan error, 10)
-
+func generateECommerceDataConcurrently(ctx context.Context, tables *tableTracker, counts ECommerceRowCounts, opts formats.Options, outputDir string, seed int64) error {
 	var customers []ecommercemodels.Customer
 	var suppliers []ecommercemodels.Supplier
 	var customerAddresses []ecommercemodels.CustomerAddress
@@ -294,12 +410,12 @@ func generateECommerceDataConcurrently(counts ECommerceRowCounts, format string,
 
 	go func() {
 		defer customersWg.Done()
-		customers = ecommerce.GenerateCustomers(counts.Customers)
-		customerAddresses = ecommerce.GenerateCustomerAddresses(customers)
+		customers = ecommerce.GenerateCustomers(counts.Customers, seed)
+		customerAddresses = ecommerce.GenerateCustomerAddresses(customers, seed)
 	}()
 	go func() {
 		defer suppliersWg.Done()
-		suppliers = ecommerce.GenerateSuppliers(counts.Suppliers)
+		suppliers = ecommerce.GenerateSuppliers(counts.Suppliers, seed)
 	}()
 	go func() {
 		defer categoriesWg.Done()
@@ -317,49 +433,32 @@ func generateECommerceDataConcurrently(counts ECommerceRowCounts, format string,
 		for i, pc := range productCategories {
 			categoryIDs[i] = pc.CategoryID
 		}
-		products = ecommerce.GenerateProducts(counts.Products, supplierIDs, categoryIDs)
+		products = ecommerce.GenerateProducts(counts.Products, supplierIDs, categoryIDs, seed)
 	}()
 
-	writersWg.Add(5)
-	go func() {
-		defer writersWg.Done()
+	g, ctx := errgroup.WithContext(ctx)
+	tables.Go(g, func() error {
 		customersWg.Wait()
-		if err := formats.WriteCustomers(customers, outputDir, format); err != nil {
-			errChan <- err
-		}
-	}()
-	go func() {
-		defer writersWg.Done()
+		return formats.WriteCustomers(ctx, customers, outputDir, opts)
+	}, "dim_customers")
+	tables.Go(g, func() error {
 		customersWg.Wait()
-		if err := formats.WriteCustomerAddresses(customerAddresses, outputDir, format); err != nil {
-			errChan <- err
-		}
-	}()
-	go func() {
-		defer writersWg.Done()
+		return formats.WriteCustomerAddresses(ctx, customerAddresses, outputDir, opts)
+	}, "dim_customer_addresses")
+	tables.Go(g, func() error {
 		suppliersWg.Wait()
-		if err := formats.WriteSuppliers(suppliers, outputDir, format); err != nil {
-			errChan <- err
-		}
-	}()
-	go func() {
-		defer writersWg.Done()
+		return formats.WriteSuppliers(ctx, suppliers, outputDir, opts)
+	}, "dim_suppliers")
+	tables.Go(g, func() error {
 		categoriesWg.Wait()
-		if err := formats.WriteProductCategories(productCategories, outputDir, format); err != nil {
-			errChan <- err
-		}
-	}()
-	go func() {
-		defer writersWg.Done()
+		return formats.WriteProductCategories(ctx, productCategories, outputDir, opts)
+	}, "dim_product_categories")
+	tables.Go(g, func() error {
 		productsWg.Wait()
-		if err := formats.WriteProducts(products, outputDir, format); err != nil {
-			errChan <- err
-		}
-	}()
+		return formats.WriteProducts(ctx, products, outputDir, opts)
+	}, "dim_products")
 
-	writersWg.Add(1) // Add fact generation to the wait group
-	go func() {
-		defer writersWg.Done() // Mark fact generation as done
+	tables.Go(g, func() error {
 		// Wait for customer and product data to be ready
 		customersWg.Wait()
 		productsWg.Wait()
@@ -383,26 +482,13 @@ func generateECommerceDataConcurrently(counts ECommerceRowCounts, format string,
 			productIDsForSampling[i] = p.ProductID
 		}
 
-		if err := ecommerce.GenerateECommerceModelData(counts.OrderHeaders, customerIDs, customerAddresses, productDetails, productIDsForSampling, outputDir, format); err != nil {
-			errChan <- err
-		}
-	}()
-
-	writersWg.Wait()
-	close(errChan)
+		return ecommerce.GenerateECommerceModelData(ctx, counts.OrderHeaders, customerIDs, customerAddresses, productDetails, productIDsForSampling, outputDir, opts, seed)
+	}, "fact_orders_header", "fact_order_items")
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return g.Wait()
 }
 
-func generateFinancialDataConcurrently(counts financialsimulation.FinancialRowCounts, format string, outputDir string) error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, 3) // 2 dims + 1 fact operation
-
+func generateFinancialDataConcurrently(ctx context.Context, tables *tableTracker, counts financialsimulation.FinancialRowCounts, opts formats.Options, outputDir string, seed int64) error {
 	var companies []financialmodels.Company
 	var exchanges []financialmodels.Exchange
 
@@ -411,47 +497,32 @@ func generateFinancialDataConcurrently(counts financialsimulation.FinancialRowCo
 
 	go func() {
 		defer genWg.Done()
-		companies = financialsimulation.GenerateCompanies(counts.Companies)
+		companies = financialsimulation.GenerateCompanies(counts.Companies, seed)
 	}()
 
 	go func() {
 		defer genWg.Done()
-		exchanges = financialsimulation.GenerateExchanges(counts.Exchanges)
+		exchanges = financialsimulation.GenerateExchanges(counts.Exchanges, seed)
 	}()
 
 	genWg.Wait()
 
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		errChan <- formats.WriteSliceData(companies, "dim_companies", format, outputDir)
-	}()
-	go func() {
-		defer wg.Done()
-		errChan <- formats.WriteSliceData(exchanges, "dim_exchanges", format, outputDir)
-	}()
-
-	err := financialsimulation.GenerateFinancialModelData(counts, companies, exchanges, format, outputDir)
-	if err != nil {
-		errChan <- err
-	}
-
-	wg.Wait()
-	close(errChan)
+	g, ctx := errgroup.WithContext(ctx)
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, companies, "dim_companies", opts, outputDir)
+	}, "dim_companies")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, exchanges, "dim_exchanges", opts, outputDir)
+	}, "dim_exchanges")
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
-	}
+	tables.Go(g, func() error {
+		return financialsimulation.GenerateFinancialModelData(ctx, counts, companies, exchanges, opts, outputDir, seed)
+	}, "fact_daily_stock_prices")
 
-	return nil
+	return g.Wait()
 }
 
-func generateMedicalDataConcurrently(counts medicalsimulation.MedicalRowCounts, format string, outputDir string) error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, 4) // 3 dims + 1 fact operation
-
+func generateMedicalDataConcurrently(ctx context.Context, tables *tableTracker, counts medicalsimulation.MedicalRowCounts, opts formats.Options, outputDir string, seed int64) error {
 	var patients []medicalmodels.Patient
 	var doctors []medicalmodels.Doctor
 	var clinics []medicalmodels.Clinic
@@ -461,48 +532,35 @@ func generateMedicalDataConcurrently(counts medicalsimulation.MedicalRowCounts,
 
 	go func() {
 		defer genWg.Done()
-		patients = medicalsimulation.GeneratePatients(counts.Patients)
+		patients = medicalsimulation.GeneratePatients(counts.Patients, seed)
 	}()
 
 	go func() {
 		defer genWg.Done()
-		doctors = medicalsimulation.GenerateDoctors(counts.Doctors)
+		doctors = medicalsimulation.GenerateDoctors(counts.Doctors, seed)
 	}()
 
 	go func() {
 		defer genWg.Done()
-		clinics = medicalsimulation.GenerateClinics(counts.Clinics)
+		clinics = medicalsimulation.GenerateClinics(counts.Clinics, seed)
 	}()
 
 	genWg.Wait()
 
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		errChan <- formats.WriteSliceData(patients, "dim_patients", format, outputDir)
-	}()
-	go func() {
-		defer wg.Done()
-		errChan <- formats.WriteSliceData(doctors, "dim_doctors", format, outputDir)
-	}()
-	go func() {
-		defer wg.Done()
-		errChan <- formats.WriteSliceData(clinics, "dim_clinics", format, outputDir)
-	}()
-
-	err := medicalsimulation.GenerateMedicalModelData(counts, patients, doctors, clinics, format, outputDir)
-	if err != nil {
-		errChan <- err
-	}
-
-	wg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	g, ctx := errgroup.WithContext(ctx)
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, patients, "dim_patients", opts, outputDir)
+	}, "dim_patients")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, doctors, "dim_doctors", opts, outputDir)
+	}, "dim_doctors")
+	tables.Go(g, func() error {
+		return formats.WriteSliceData(ctx, clinics, "dim_clinics", opts, outputDir)
+	}, "dim_clinics")
+
+	tables.Go(g, func() error {
+		return medicalsimulation.GenerateMedicalModelData(ctx, counts, patients, doctors, clinics, opts, outputDir, seed)
+	}, "fact_appointments")
+
+	return g.Wait()
 }
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseRowCounts parses row counts given as table=N pairs separated by commas,
+// such as "dim_customers=50000,fact_store_sales=10_000_000".
+func ParseRowCounts(spec string) (map[string]int, error) {
+	rows := make(map[string]int)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		table, count, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid row count %q: expected table=N", pair)
+		}
+		n, err := parseRowCount(count)
+		if err != nil {
+			return nil, fmt.Errorf("invalid row count for %s: %w", strings.TrimSpace(table), err)
+		}
+		rows[strings.TrimSpace(table)] = n
+	}
+	return rows, nil
+}
+
+// ReadRowCountsFile reads row counts from a YAML or JSON file mapping table
+// names to row counts.
+func ReadRowCountsFile(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading row counts file: %w", err)
+	}
+	var counts map[string]string
+	if err := yaml.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("error parsing row counts file %s: %w", path, err)
+	}
+	rows := make(map[string]int, len(counts))
+	for table, count := range counts {
+		n, err := parseRowCount(count)
+		if err != nil {
+			return nil, fmt.Errorf("row counts file %s: invalid row count for %s: %w", path, table, err)
+		}
+		rows[table] = n
+	}
+	return rows, nil
+}
+
+// parseRowCount parses a row count, which may group digits with underscores as
+// the row counts are printed.
+func parseRowCount(s string) (int, error) {
+	n, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(s), "_", ""))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", strings.TrimSpace(s))
+	}
+	return n, nil
+}
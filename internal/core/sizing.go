@@ -3,7 +3,11 @@ package core
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 
+	ecommercedssimulation "github.com/peekknuf/Gengo/internal/simulation/ecommerce-ds"
 	financialsimulation "github.com/peekknuf/Gengo/internal/simulation/financial"
 	medicalsimulation "github.com/peekknuf/Gengo/internal/simulation/medical"
 )
@@ -153,6 +157,106 @@ func CalculateECommerceDSRowCounts(targetGB float64, format string) (ECommerceDS
 	return counts, nil
 }
 
+// tpcdsRowCounts are the row counts dsdgen generates at the TPC-DS scale
+// factors. customer_demographics, household_demographics, income_band and
+// ship_mode do not grow with the scale factor.
+var tpcdsRowCounts = map[int]ECommerceDSRowCounts{
+	1: {
+		Customers: 100_000, CustomerAddresses: 50_000, Items: 18_000, Promotions: 300,
+		Stores: 12, CallCenters: 6, CatalogPages: 11_718, WebSites: 30, WebPages: 60, Warehouses: 5, Reasons: 35,
+		StoreSales: 2_880_404, StoreReturns: 287_514, CatalogSales: 1_441_548, CatalogReturns: 144_067,
+		WebSales: 719_384, WebReturns: 71_763, Inventory: 11_745_000,
+	},
+	10: {
+		Customers: 500_000, CustomerAddresses: 250_000, Items: 102_000, Promotions: 500,
+		Stores: 102, CallCenters: 24, CatalogPages: 12_000, WebSites: 42, WebPages: 200, Warehouses: 10, Reasons: 45,
+		StoreSales: 28_800_991, StoreReturns: 2_875_432, CatalogSales: 14_401_261, CatalogReturns: 1_439_749,
+		WebSales: 7_197_566, WebReturns: 719_217, Inventory: 133_110_000,
+	},
+	100: {
+		Customers: 2_000_000, CustomerAddresses: 1_000_000, Items: 204_000, Promotions: 1_000,
+		Stores: 402, CallCenters: 30, CatalogPages: 20_400, WebSites: 24, WebPages: 2_040, Warehouses: 15, Reasons: 55,
+		StoreSales: 287_997_024, StoreReturns: 28_795_080, CatalogSales: 143_997_065, CatalogReturns: 14_404_374,
+		WebSales: 72_001_237, WebReturns: 7_197_670, Inventory: 399_330_000,
+	},
+	1000: {
+		Customers: 12_000_000, CustomerAddresses: 6_000_000, Items: 300_000, Promotions: 1_500,
+		Stores: 1_002, CallCenters: 42, CatalogPages: 30_000, WebSites: 54, WebPages: 3_000, Warehouses: 20, Reasons: 65,
+		StoreSales: 2_879_987_999, StoreReturns: 287_999_764, CatalogSales: 1_439_980_416, CatalogReturns: 143_996_756,
+		WebSales: 720_000_376, WebReturns: 71_997_522, Inventory: 783_000_000,
+	},
+	3000: {
+		Customers: 30_000_000, CustomerAddresses: 15_000_000, Items: 360_000, Promotions: 1_800,
+		Stores: 1_350, CallCenters: 48, CatalogPages: 36_000, WebSites: 66, WebPages: 3_600, Warehouses: 22, Reasons: 67,
+		StoreSales: 8_639_936_081, StoreReturns: 863_989_652, CatalogSales: 4_320_078_880, CatalogReturns: 432_009_033,
+		WebSales: 2_159_968_881, WebReturns: 216_003_761, Inventory: 1_033_560_000,
+	},
+	10000: {
+		Customers: 65_000_000, CustomerAddresses: 32_500_000, Items: 402_000, Promotions: 2_000,
+		Stores: 1_500, CallCenters: 54, CatalogPages: 40_000, WebSites: 78, WebPages: 4_002, Warehouses: 25, Reasons: 70,
+		StoreSales: 28_799_983_563, StoreReturns: 2_879_970_104, CatalogSales: 14_399_964_710, CatalogReturns: 1_440_033_112,
+		WebSales: 7_199_963_324, WebReturns: 720_020_485, Inventory: 1_311_525_000,
+	},
+}
+
+// ECommerceDSScaleFactorRowCounts returns the row counts dsdgen generates at
+// TPC-DS scale factor sf.
+func ECommerceDSScaleFactorRowCounts(sf int) (ECommerceDSRowCounts, error) {
+	counts, ok := tpcdsRowCounts[sf]
+	if !ok {
+		return ECommerceDSRowCounts{}, fmt.Errorf("unsupported TPC-DS scale factor %d: choose one of %s", sf, joinInts(tpcdsScaleFactors()))
+	}
+	counts.ScaleFactor = sf
+	counts.CustomerDemographics = 1_920_800
+	counts.HouseholdDemographics = 7_200
+	counts.IncomeBands = 20
+	counts.ShipModes = 20
+	return counts, nil
+}
+
+// tpcdsScaleFactors returns the supported TPC-DS scale factors in order.
+func tpcdsScaleFactors() []int {
+	sfs := make([]int, 0, len(tpcdsRowCounts))
+	for sf := range tpcdsRowCounts {
+		sfs = append(sfs, sf)
+	}
+	sort.Ints(sfs)
+	return sfs
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// checkECommerceDSRowCounts rejects more returns than sales in a channel, as
+// returns are drawn from the sales lines, and more inventory rows than a weekly
+// snapshot of every item in every warehouse over the date dimension holds.
+func checkECommerceDSRowCounts(counts ECommerceDSRowCounts) error {
+	for _, c := range []struct {
+		channel        string
+		sales, returns int
+	}{
+		{"store", counts.StoreSales, counts.StoreReturns},
+		{"catalog", counts.CatalogSales, counts.CatalogReturns},
+		{"web", counts.WebSales, counts.WebReturns},
+	} {
+		if c.returns > c.sales {
+			return fmt.Errorf("%s returns (%d) cannot outnumber %s sales (%d)", c.channel, c.returns, c.channel, c.sales)
+		}
+	}
+	_, _, salesDates := ecommerceDSDates(counts)
+	weeks := len(ecommercedssimulation.InventorySnapshotDateSKs(salesDates))
+	if limit := weeks * counts.Items * counts.Warehouses; counts.Inventory > limit {
+		return fmt.Errorf("inventory (%d) cannot exceed %d rows, a weekly snapshot of %d items in %d warehouses over %d weeks",
+			counts.Inventory, limit, counts.Items, counts.Warehouses, weeks)
+	}
+	return nil
+}
+
 // estimateAvgRowSizeBytes estimates the average uncompressed size of a single row for a given table type.
 func estimateAvgRowSizeBytes(tableType string) (int, error) {
 	switch tableType {
@@ -295,3 +399,13 @@ func CalculateMedicalRowCounts(targetGB float64) (medicalsimulation.MedicalRowCo
 
 	return counts, nil
 }
+
+// CalculateRowCounts determines the target number of rows for each table of
+// modelType.
+func CalculateRowCounts(modelType string, targetGB float64, format string) (interface{}, error) {
+	model, err := lookupModel(modelType)
+	if err != nil {
+		return nil, err
+	}
+	return model.rowCounts(targetGB, format)
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peekknuf/Gengo/internal/formats"
+	"golang.org/x/sync/errgroup"
+)
+
+// tableTracker records which tables of a run have been completely written, both
+// in memory, to report them when a run fails or is interrupted, and in the run
+// manifest, so a resumed run can skip them.
+type tableTracker struct {
+	manifest *runManifest
+	opts     formats.Options
+
+	mu       sync.Mutex
+	tables   []string
+	complete map[string]bool
+}
+
+func newTableTracker(manifest *runManifest, opts formats.Options) *tableTracker {
+	return &tableTracker{manifest: manifest, opts: opts, complete: make(map[string]bool)}
+}
+
+// Go runs fn in g and marks tables complete once fn returns without error, after
+// committing the metadata of their table format, if any. If the run being
+// resumed already completed all of the tables, fn is skipped.
+func (t *tableTracker) Go(g *errgroup.Group, fn func() error, tables ...string) {
+	t.mu.Lock()
+	t.tables = append(t.tables, tables...)
+	t.mu.Unlock()
+
+	resumed := true
+	for _, table := range tables {
+		resumed = resumed && t.manifest.tableResumed(table)
+	}
+	if resumed {
+		fmt.Printf("Skipping %s: already complete\n", strings.Join(tables, ", "))
+		t.markComplete(tables)
+		return
+	}
+
+	g.Go(func() error {
+		started := time.Now()
+		if err := fn(); err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if err := t.opts.CommitTable(filepath.Join(t.manifest.dir, table), t.manifest.tableFiles(table), t.manifest.Seed); err != nil {
+				return fmt.Errorf("error committing table %s: %w", table, err)
+			}
+		}
+		t.markComplete(tables)
+		return t.manifest.tableComplete(started, tables...)
+	})
+}
+
+func (t *tableTracker) markComplete(tables []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, table := range tables {
+		t.complete[table] = true
+	}
+}
+
+// split returns the complete and the incomplete tables, in the order they were
+// started.
+func (t *tableTracker) split() (complete, incomplete []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, table := range t.tables {
+		if t.complete[table] {
+			complete = append(complete, table)
+		} else {
+			incomplete = append(incomplete, table)
+		}
+	}
+	return complete, incomplete
+}
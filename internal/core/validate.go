@@ -0,0 +1,738 @@
+package core
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/peekknuf/Gengo/internal/common"
+	"github.com/peekknuf/Gengo/internal/formats"
+	"github.com/peekknuf/Gengo/internal/schema"
+	"github.com/peekknuf/Gengo/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	maxViolationExamples  = 3
+	validateCheckInterval = 4096
+)
+
+// ValidationReport is the result of ValidateDataset.
+type ValidationReport struct {
+	Dir    string
+	Model  string
+	Tables []*TableValidation
+}
+
+// TableValidation is what ValidateDataset found for one table.
+type TableValidation struct {
+	Table string
+	Files int
+	Rows  int64
+	// ExpectedRows is the planned row count, -1 if unknown. Estimated is set
+	// when the generated rows only approximate it.
+	ExpectedRows int64
+	Estimated    bool
+	Violations   []*Violation
+
+	mu sync.Mutex
+}
+
+// Violation is a failed check with a few of the rows that failed it.
+type Violation struct {
+	Check    string
+	Count    int64
+	Examples []string
+}
+
+// Violations returns the number of violations across all tables.
+func (r *ValidationReport) Violations() int64 {
+	var n int64
+	for _, t := range r.Tables {
+		for _, v := range t.Violations {
+			n += v.Count
+		}
+	}
+	return n
+}
+
+// Print writes the report to stdout, one line per table followed by its
+// violations.
+func (r *ValidationReport) Print() {
+	fmt.Printf("\nValidated %s dataset in %s:\n", r.Model, r.Dir)
+	for _, t := range r.Tables {
+		mark := "✓"
+		if len(t.Violations) > 0 {
+			mark = "✗"
+		}
+		line := fmt.Sprintf("  %s %-28s %s rows in %d files", mark, t.Table, utils.AddUnderscores(int(t.Rows)), t.Files)
+		if t.Estimated && t.ExpectedRows >= 0 {
+			line += fmt.Sprintf(" (estimated %s)", utils.AddUnderscores(int(t.ExpectedRows)))
+		}
+		fmt.Println(line)
+		for _, v := range t.Violations {
+			fmt.Printf("      %s: %s\n", v.Check, utils.AddUnderscores(int(v.Count)))
+			for _, example := range v.Examples {
+				fmt.Printf("        %s\n", example)
+			}
+		}
+	}
+	if n := r.Violations(); n > 0 {
+		fmt.Printf("\n%s violations found.\n", utils.AddUnderscores(int(n)))
+	} else {
+		fmt.Println("\nNo violations found.")
+	}
+}
+
+func (t *TableValidation) violation(check string, example string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, v := range t.Violations {
+		if v.Check == check {
+			v.Count++
+			if len(v.Examples) < maxViolationExamples {
+				v.Examples = append(v.Examples, example)
+			}
+			return
+		}
+	}
+	t.Violations = append(t.Violations, &Violation{Check: check, Count: 1, Examples: []string{example}})
+}
+
+// ValidateDataset checks the dataset in dir: primary keys are present and
+// unique, every foreign key refers to an existing row of its table, and the
+// row counts match the run manifest and the planned row counts. The model is
+// read from the manifest; modelType is only needed for datasets without one,
+// whose planned row counts are then estimated from targetGB if it is set. CSV
+// files are read in the dialect recorded in the manifest, or in csv if there is
+// none.
+func ValidateDataset(ctx context.Context, dir string, modelType string, targetGB float64, csv formats.CSVDialect) (*ValidationReport, error) {
+	manifest, err := loadRunManifest(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		manifest = nil
+	} else if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		csv = manifest.csvDialect()
+	}
+
+	if modelType != "" {
+		if modelType, err = matchModelType(modelType); err != nil {
+			return nil, err
+		}
+	}
+	if manifest != nil {
+		if modelType != "" && modelType != manifest.Model {
+			return nil, fmt.Errorf("%s holds a %s dataset, not %s", dir, manifest.Model, modelType)
+		}
+		modelType = manifest.Model
+	} else if modelType == "" {
+		return nil, fmt.Errorf("%s has no %s; the model must be given", dir, ManifestFile)
+	}
+	tables, err := schema.Tables(modelType)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := datasetFiles(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := expectedRowCounts(manifest, modelType, targetGB, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tables referenced by a foreign key keep their primary keys in memory for
+	// the tables after them, unless every reference is from the same shard.
+	referenced := make(map[string]bool)
+	shardReferenced := make(map[string]bool)
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.SameShard {
+				shardReferenced[fk.References] = true
+			} else {
+				referenced[fk.References] = true
+			}
+		}
+	}
+
+	runs, err := os.MkdirTemp("", "gengo-validate-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating a directory for the keys of shards: %w", err)
+	}
+	defer os.RemoveAll(runs)
+
+	report := &ValidationReport{Dir: dir, Model: modelType}
+	v := &validator{
+		seed:      maphash.MakeSeed(),
+		csv:       csv,
+		keys:      make(map[string]*keySet),
+		shardKeys: make(map[string]map[string]keyRun),
+		runs:      runs,
+	}
+	for _, t := range tables {
+		tv := &TableValidation{Table: t.Name, ExpectedRows: -1, Estimated: t.Estimated}
+		if n, ok := expected[t.RowCount]; ok && t.RowCount != "" {
+			tv.ExpectedRows = n
+		}
+		report.Tables = append(report.Tables, tv)
+
+		if err := v.validateTable(ctx, dir, t, tv, files[t.Name], manifest, referenced[t.Name], shardReferenced[t.Name]); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// datasetFiles returns the data files of each table: those listed in the run
+// manifest, or those found in dir and the partition directories under it if
+// there is none.
+func datasetFiles(dir string, manifest *runManifest) (map[string][]string, error) {
+	files := make(map[string][]string)
+	if manifest != nil {
+		for _, f := range manifest.Files {
+			files[f.Table] = append(files[f.Table], f.Name)
+		}
+		return files, nil
+	}
+
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if strings.HasPrefix(e.Name(), ".") {
+			if e.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if e.IsDir() && e.Name() == "metadata" && filepath.Dir(path) != dir {
+			// The metadata of an Iceberg table holds Avro manifests, not rows.
+			return filepath.SkipDir
+		}
+		if e.IsDir() || e.Name() == formats.SuccessMarker {
+			return nil
+		}
+		switch formats.DataExtension(e.Name()) {
+		case ".csv", ".jsonl", ".parquet", ".arrow", ".arrows", ".avro", ".dat":
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+			table := formats.TableOfFile(name)
+			files[table] = append(files[table], name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	for _, names := range files {
+		sort.Strings(names)
+	}
+	return files, nil
+}
+
+// fileFormat returns the output format of a data file.
+func fileFormat(name string) string {
+	switch formats.DataExtension(name) {
+	case ".jsonl":
+		return "json"
+	case ".parquet":
+		return "parquet"
+	case ".arrow", ".arrows":
+		return "arrow"
+	case ".avro":
+		return "avro"
+	case ".dat":
+		return "dsdgen"
+	}
+	return "csv"
+}
+
+// expectedRowCounts returns the planned row counts keyed by field name, from
+// the manifest or estimated from targetGB.
+func expectedRowCounts(manifest *runManifest, modelType string, targetGB float64, files map[string][]string) (map[string]int64, error) {
+	var data []byte
+	if manifest != nil {
+		data = manifest.RowCounts
+	} else if targetGB > 0 {
+		format := "csv"
+		for _, names := range files {
+			format = fileFormat(names[0])
+			break
+		}
+		counts, err := CalculateRowCounts(modelType, targetGB, format)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(counts); err != nil {
+			return nil, fmt.Errorf("error encoding row counts: %w", err)
+		}
+	} else {
+		return nil, nil
+	}
+
+	var expected map[string]int64
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("error parsing row counts: %w", err)
+	}
+	return expected, nil
+}
+
+type validator struct {
+	seed      maphash.Seed
+	csv       formats.CSVDialect           // dialect the CSV files are read in
+	keys      map[string]*keySet           // primary keys of the validated tables that are referenced
+	shardKeys map[string]map[string]keyRun // runs of the tables referenced by shard, by shard
+	runs      string                       // directory of the runs
+}
+
+// tableCheck is what checking the rows of a table needs.
+type tableCheck struct {
+	tv       *TableValidation
+	manifest *runManifest
+	header   []string // columns of files that do not name them
+	columns  []string // key columns read
+	pk       []int
+	pkCheck  string
+	fks      []fkCheck
+}
+
+type fkCheck struct {
+	pos   []int
+	keys  *keySet           // keys of the referenced table, if held in memory
+	runs  map[string]keyRun // otherwise its runs, by shard
+	check string
+}
+
+// validateTable reads the key columns of every file of table t. The primary
+// keys of a table referenced as a whole are held in memory and kept in v.keys
+// for the tables after it. Any other table is checked shard by shard, holding
+// the keys of a shard at a time: each shard's keys are written to a run,
+// sorted, and the runs are merged to find keys repeated across shards. The runs
+// of a table referenced by shard are kept in v.shardKeys.
+func (v *validator) validateTable(ctx context.Context, dir string, t schema.Table, tv *TableValidation, names []string, manifest *runManifest, referenced, shardReferenced bool) error {
+	if len(names) == 0 {
+		if tv.ExpectedRows != 0 {
+			tv.violation("missing table", "no files found")
+		}
+		return nil
+	}
+	tv.Files = len(names)
+
+	// The key columns of the table, each read once.
+	c := &tableCheck{tv: tv, manifest: manifest}
+	position := make(map[string]int)
+	positions := func(names []string) []int {
+		pos := make([]int, len(names))
+		for i, name := range names {
+			p, ok := position[name]
+			if !ok {
+				p = len(c.columns)
+				position[name] = p
+				c.columns = append(c.columns, name)
+			}
+			pos[i] = p
+		}
+		return pos
+	}
+	c.pk = positions(t.PrimaryKey)
+	c.pkCheck = fmt.Sprintf("duplicate primary key (%s)", strings.Join(t.PrimaryKey, ", "))
+	for _, fk := range t.ForeignKeys {
+		check := fkCheck{check: fmt.Sprintf("foreign key (%s) not in %s", strings.Join(fk.Columns, ", "), fk.References)}
+		if check.keys = v.keys[fk.References]; check.keys == nil {
+			if check.runs = v.shardKeys[fk.References]; check.runs == nil || !fk.SameShard {
+				continue // the referenced table is missing, which is reported there
+			}
+		}
+		check.pos = positions(fk.Columns)
+		c.fks = append(c.fks, check)
+	}
+
+	// Files that do not name their columns lay them out as the model does.
+	if !formats.NamesColumns(names[0], v.csv) {
+		var err error
+		if c.header, err = columnNames(t, fileFormat(names[0])); err != nil {
+			return err
+		}
+	}
+
+	var set *keySet
+	var groups [][]string
+	if referenced {
+		set = newKeySet()
+		for _, name := range names {
+			groups = append(groups, []string{name})
+		}
+	} else {
+		groups = shardGroups(names)
+	}
+	writeRuns := set == nil && (len(groups) > 1 || shardReferenced)
+	runs := make([]keyRun, len(groups))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
+	for i, group := range groups {
+		g.Go(func() error {
+			keys := set
+			if keys == nil {
+				keys = newKeySet()
+			}
+			shard := formats.ShardOfFile(group[0])
+			lookups, err := v.lookups(c.fks, shard)
+			if err != nil {
+				return err
+			}
+			for _, name := range group {
+				if err := v.validateFile(ctx, dir, name, c, keys, lookups); err != nil {
+					return err
+				}
+			}
+			if writeRuns {
+				runs[i], err = v.writeRun(keys, t.Name, shard, group[0])
+			}
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	if set != nil {
+		v.keys[t.Name] = set
+	}
+	if writeRuns && len(runs) > 1 {
+		if err := mergeRuns(runs, tv, c.pkCheck); err != nil {
+			return err
+		}
+	}
+	if writeRuns && shardReferenced {
+		v.shardKeys[t.Name] = make(map[string]keyRun, len(runs))
+		for _, run := range runs {
+			v.shardKeys[t.Name][run.shard] = run
+		}
+	} else if writeRuns {
+		for _, run := range runs {
+			os.Remove(run.path)
+		}
+	}
+
+	if manifest != nil {
+		if mt, ok := manifest.tables[t.Name]; ok && mt.Rows != tv.Rows {
+			tv.violation("row count differs from manifest", fmt.Sprintf("all files: %d rows, manifest lists %d", tv.Rows, mt.Rows))
+		}
+	}
+	if tv.ExpectedRows >= 0 && !tv.Estimated && tv.ExpectedRows != tv.Rows {
+		tv.violation("row count differs from plan", fmt.Sprintf("%d rows, planned %d", tv.Rows, tv.ExpectedRows))
+	}
+	return nil
+}
+
+// shardGroups groups the files of a table by the shard that wrote them.
+func shardGroups(names []string) [][]string {
+	index := make(map[string]int)
+	var groups [][]string
+	for _, name := range names {
+		shard := formats.ShardOfFile(name)
+		i, ok := index[shard]
+		if !ok {
+			i = len(groups)
+			index[shard] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], name)
+	}
+	return groups
+}
+
+// lookups returns the keys each foreign key of fks is looked up in for the
+// rows of shard.
+func (v *validator) lookups(fks []fkCheck, shard string) ([]keyLookup, error) {
+	lookups := make([]keyLookup, len(fks))
+	for i, fk := range fks {
+		if fk.keys != nil {
+			lookups[i] = fk.keys
+			continue
+		}
+		keys := runKeys{}
+		if run, ok := fk.runs[shard]; ok {
+			var err error
+			if keys, err = readRun(run.path); err != nil {
+				return nil, err
+			}
+		}
+		lookups[i] = keys
+	}
+	return lookups, nil
+}
+
+// validateFile checks the rows of the file name, adding their primary keys to
+// keys and looking up their foreign keys in lookups.
+func (v *validator) validateFile(ctx context.Context, dir, name string, c *tableCheck, keys *keySet, lookups []keyLookup) error {
+	tv := c.tv
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		tv.violation("missing file", name)
+		return nil
+	}
+	var h maphash.Hash
+	h.SetSeed(v.seed)
+	var row int64
+	rows, err := formats.ReadColumns(ctx, path, v.csv, c.header, c.columns, func(values []string) error {
+		row++
+		if row%validateCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		key, null := hashKey(&h, values, c.pk)
+		if null {
+			tv.violation("null primary key", describeRow(name, row, c.columns, values, c.pk))
+		} else if !keys.add(key) {
+			tv.violation(c.pkCheck, describeRow(name, row, c.columns, values, c.pk))
+		}
+		for i, fk := range c.fks {
+			if key, null := hashKey(&h, values, fk.pos); !null && !lookups[i].has(key) {
+				tv.violation(fk.check, describeRow(name, row, c.columns, values, fk.pos))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if c.manifest != nil {
+		if f, ok := c.manifest.files[name]; ok && f.Rows != rows {
+			tv.violation("row count differs from manifest", fmt.Sprintf("%s: %d rows, manifest lists %d", name, rows, f.Rows))
+		}
+	}
+	tv.mu.Lock()
+	tv.Rows += rows
+	tv.mu.Unlock()
+	return nil
+}
+
+// columnNames returns the names of the columns of t as they are written in format.
+func columnNames(t schema.Table, format string) ([]string, error) {
+	columns, err := t.Columns(format)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the columns of %s: %w", t.Name, err)
+	}
+	names := make([]string, columns.NumFields())
+	for i, field := range columns.Fields() {
+		names[i] = field.Name
+	}
+	return names, nil
+}
+
+// hashKey hashes the values at pos; null is set if any of them is NULL.
+func hashKey(h *maphash.Hash, values []string, pos []int) (key uint64, null bool) {
+	h.Reset()
+	for _, p := range pos {
+		if values[p] == "" {
+			return 0, true
+		}
+		h.WriteString(values[p])
+		h.WriteByte(0)
+	}
+	return h.Sum64(), false
+}
+
+func describeRow(file string, row int64, columns []string, values []string, pos []int) string {
+	fields := make([]string, len(pos))
+	for i, p := range pos {
+		fields[i] = columns[p] + "=" + values[p]
+	}
+	return fmt.Sprintf("%s row %d: %s", file, row, strings.Join(fields, ", "))
+}
+
+// keySet is a set of hashed keys, sharded to let the readers of a table's files
+// add keys concurrently. Keys are 64-bit hashes, so on tables of billions of
+// rows a false duplicate is possible but unlikely.
+type keySet struct {
+	shards [64]struct {
+		mu   sync.Mutex
+		keys map[uint64]struct{}
+	}
+}
+
+func newKeySet() *keySet {
+	s := &keySet{}
+	for i := range s.shards {
+		s.shards[i].keys = make(map[uint64]struct{})
+	}
+	return s
+}
+
+// add adds key and reports whether it was new.
+func (s *keySet) add(key uint64) bool {
+	shard := &s.shards[key%uint64(len(s.shards))]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, ok := shard.keys[key]; ok {
+		return false
+	}
+	shard.keys[key] = struct{}{}
+	return true
+}
+
+// has reports whether key is in the set. It is only called once the set is
+// complete, so it does not lock.
+func (s *keySet) has(key uint64) bool {
+	_, ok := s.shards[key%uint64(len(s.shards))].keys[key]
+	return ok
+}
+
+// keyLookup is a set of hashed keys foreign keys are looked up in.
+type keyLookup interface {
+	has(key uint64) bool
+}
+
+// runKeys is a set of hashed keys read from a run.
+type runKeys []uint64
+
+func (s runKeys) has(key uint64) bool {
+	_, ok := slices.BinarySearch(s, key)
+	return ok
+}
+
+// keyRun is a file holding the hashed primary keys of a shard of a table,
+// sorted, as little-endian uint64s. file is the first file of the shard, named
+// when its keys are found in another shard.
+type keyRun struct {
+	shard string
+	file  string
+	path  string
+}
+
+// writeRun writes the keys of shard to a run.
+func (v *validator) writeRun(keys *keySet, table, shard, file string) (keyRun, error) {
+	var sorted []uint64
+	for i := range keys.shards {
+		for key := range keys.shards[i].keys {
+			sorted = append(sorted, key)
+		}
+	}
+	slices.Sort(sorted)
+	f, err := os.CreateTemp(v.runs, table+"-*")
+	if err != nil {
+		return keyRun{}, fmt.Errorf("error creating a run of the keys of %s: %w", file, err)
+	}
+	w := bufio.NewWriter(f)
+	var b [8]byte
+	for _, key := range sorted {
+		binary.LittleEndian.PutUint64(b[:], key)
+		w.Write(b[:])
+	}
+	err = w.Flush()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return keyRun{}, fmt.Errorf("error writing the keys of %s: %w", file, err)
+	}
+	return keyRun{shard: shard, file: file, path: f.Name()}, nil
+}
+
+// readRun reads the keys of a run.
+func readRun(path string) (runKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the keys of a shard: %w", err)
+	}
+	keys := make(runKeys, len(data)/8)
+	for i := range keys {
+		keys[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return keys, nil
+}
+
+// mergeRuns merges the runs of the shards of a table and reports the keys
+// found in more than one of them as violations of check.
+func mergeRuns(runs []keyRun, tv *TableValidation, check string) error {
+	h := make(runHeap, 0, len(runs))
+	for i, run := range runs {
+		f, err := os.Open(run.path)
+		if err != nil {
+			return fmt.Errorf("error reading the keys of %s: %w", run.file, err)
+		}
+		defer f.Close()
+		r := &runReader{run: i, r: bufio.NewReader(f)}
+		if ok, err := r.next(); err != nil {
+			return fmt.Errorf("error reading the keys of %s: %w", run.file, err)
+		} else if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+	last := -1
+	var lastKey uint64
+	for len(h) > 0 {
+		r := h[0]
+		if last >= 0 && r.key == lastKey {
+			tv.violation(check, fmt.Sprintf("%s and %s hold the same key", runs[last].file, runs[r.run].file))
+		}
+		last, lastKey = r.run, r.key
+		ok, err := r.next()
+		if err != nil {
+			return fmt.Errorf("error reading the keys of %s: %w", runs[r.run].file, err)
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// runReader reads the keys of a run in order.
+type runReader struct {
+	run int
+	r   *bufio.Reader
+	key uint64
+	b   [8]byte
+}
+
+// next reads the next key, reporting false at the end of the run.
+func (r *runReader) next() (bool, error) {
+	if _, err := io.ReadFull(r.r, r.b[:]); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	r.key = binary.LittleEndian.Uint64(r.b[:])
+	return true, nil
+}
+
+// runHeap orders runReaders by their next key.
+type runHeap []*runReader
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
@@ -0,0 +1,30 @@
+package core
+
+import "runtime/debug"
+
+// Version is the gengo version. Release builds set it with
+// -ldflags "-X github.com/peekknuf/Gengo/internal/core.Version=v1.2.3".
+var Version string
+
+// GengoVersion returns Version, or the module version the binary was built from
+// when Version is not set, falling back to the VCS revision for development
+// builds.
+func GengoVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	if version != "(devel)" {
+		return version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			return "devel+" + setting.Value[:12]
+		}
+	}
+	return version
+}
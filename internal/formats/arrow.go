@@ -0,0 +1,311 @@
+package formats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// ArrowIPCFormats are the variants of Arrow IPC output: the file format, with a
+// footer indexing the record batches so readers can memory-map them, and the
+// streaming format.
+var ArrowIPCFormats = []string{"file", "stream"}
+
+// arrowIPCExtensions are the extensions of the Arrow IPC variants.
+var arrowIPCExtensions = map[string]string{
+	"file":   ".arrow",
+	"stream": ".arrows",
+}
+
+// CheckArrowIPC returns an error unless variant is an Arrow IPC variant.
+func CheckArrowIPC(variant string) error {
+	if _, ok := arrowIPCExtensions[variant]; !ok {
+		return fmt.Errorf("unsupported Arrow IPC variant: %s (choose %s)", variant, strings.Join(ArrowIPCFormats, ", "))
+	}
+	return nil
+}
+
+// Columnar reports whether format is written from Arrow record batches, with
+// typed columns: Parquet, Arrow IPC, Avro and the table formats.
+func Columnar(format string) bool {
+	return DataFormat(format) == "parquet" || format == "arrow" || format == "avro"
+}
+
+// RecordWriter writes Arrow record batches to a columnar file.
+type RecordWriter interface {
+	Write(record arrow.Record) error
+	Close() error
+}
+
+// openRecordWriter opens the writer of record batches of schema in the format
+// of opts on the temporary file of targetFilename: a Parquet writer, also for
+// the table formats, an Arrow IPC writer or an Avro writer. dictionary is the
+// Parquet writer's default for dictionary encoding. Closing the writer closes
+// the file.
+func openRecordWriter(schema *arrow.Schema, targetFilename string, dictionary bool, opts Options) (*OutputFile, RecordWriter, error) {
+	file, err := CreateOutputFile(targetFilename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s: %w", targetFilename, err)
+	}
+	var writer RecordWriter
+	switch opts.Format {
+	case "arrow":
+		writer, err = newIPCRecordWriter(schema, file, opts.ArrowIPC == "stream", opts.Compression)
+	case "avro":
+		writer, err = newAvroRecordWriter(schema, file, targetFilename, opts.AvroCodec)
+	case "parquet":
+		var pw *pqarrow.FileWriter
+		// The Parquet writer gets the file without its Close, so that the
+		// footer can still be rewritten when the writer is closed.
+		pw, err = pqarrow.NewFileWriter(schema, struct{ io.Writer }{file}, opts.Parquet.writerProperties(dictionary), pqarrow.NewArrowWriterProperties())
+		writer = parquetRecordWriter{pw, file, opts.Parquet.RowGroupBytes}
+	default:
+		err = fmt.Errorf("%s is not written as record batches", opts.Format)
+	}
+	if err != nil {
+		file.Close()
+		DiscardFile(targetFilename)
+		return nil, nil, fmt.Errorf("failed to create writer for %s: %w", targetFilename, err)
+	}
+	return file, rowCountingWriter{writer, file}, nil
+}
+
+// rowCountingWriter counts the rows written to file, which is described by
+// them once published.
+type rowCountingWriter struct {
+	RecordWriter
+	file *OutputFile
+}
+
+func (w rowCountingWriter) Write(record arrow.Record) error {
+	if err := w.RecordWriter.Write(record); err != nil {
+		return err
+	}
+	w.file.AddRows(record.NumRows())
+	return nil
+}
+
+// parquetRecordWriter writes record batches into the row groups of a Parquet
+// file: each batch as a row group of its own, or, with a target row group
+// size, into the current row group until it has reached that size.
+type parquetRecordWriter struct {
+	*pqarrow.FileWriter
+	file          *OutputFile
+	rowGroupBytes int64
+}
+
+func (w parquetRecordWriter) Write(record arrow.Record) error {
+	if w.rowGroupBytes <= 0 {
+		return w.FileWriter.Write(record)
+	}
+	if w.RowGroupTotalCompressedBytes()+w.RowGroupTotalBytesWritten() >= w.rowGroupBytes {
+		w.NewBufferedRowGroup()
+	}
+	return w.WriteBuffered(record)
+}
+
+// Close writes the footer, puts its encoding stats in a fixed order and closes
+// the file.
+func (w parquetRecordWriter) Close() error {
+	err := w.FileWriter.Close()
+	if err == nil {
+		err = sortParquetEncodingStats(w.file)
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ipcRecordWriter writes record batches to an Arrow IPC file or stream, with
+// their buffers compressed with the run's compression, if any.
+type ipcRecordWriter struct {
+	writer interface {
+		Write(record arrow.Record) error
+		Close() error
+	}
+	buf  *bufio.Writer
+	file *OutputFile
+}
+
+func newIPCRecordWriter(schema *arrow.Schema, file *OutputFile, stream bool, codec string) (*ipcRecordWriter, error) {
+	w := &ipcRecordWriter{buf: bufio.NewWriterSize(file, 1<<20), file: file}
+	opts := []ipc.Option{ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator)}
+	switch codec {
+	case "lz4":
+		opts = append(opts, ipc.WithLZ4())
+	case "zstd":
+		opts = append(opts, ipc.WithZstd())
+	}
+	if stream {
+		w.writer = ipc.NewWriter(w.buf, opts...)
+		return w, nil
+	}
+	fw, err := ipc.NewFileWriter(w.buf, opts...)
+	if err != nil {
+		return nil, err
+	}
+	w.writer = fw
+	return w, nil
+}
+
+func (w *ipcRecordWriter) Write(record arrow.Record) error {
+	return w.writer.Write(record)
+}
+
+// Close writes the end of the stream, or the footer of the file, and closes
+// the file.
+func (w *ipcRecordWriter) Close() error {
+	err := w.writer.Close()
+	if flushErr := w.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// arrowRecordReader reads the record batches of an Arrow IPC file or stream.
+type arrowRecordReader struct {
+	file   *os.File
+	schema *arrow.Schema
+	next   func() (arrow.Record, error)
+	close  func()
+}
+
+// openArrowFile opens filename for reading its record batches in order.
+func openArrowFile(filename string) (*arrowRecordReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	r := &arrowRecordReader{file: f}
+	if DataExtension(filename) == arrowIPCExtensions["stream"] {
+		sr, err := ipc.NewReader(bufio.NewReaderSize(f, 1<<20), ipc.WithAllocator(memory.DefaultAllocator))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read the Arrow stream %s: %w", filename, err)
+		}
+		r.schema = sr.Schema()
+		r.next = func() (arrow.Record, error) {
+			if !sr.Next() {
+				if err := sr.Err(); err != nil && err != io.EOF {
+					return nil, err
+				}
+				return nil, io.EOF
+			}
+			return sr.Record(), nil
+		}
+		r.close = sr.Release
+		return r, nil
+	}
+
+	fr, err := ipc.NewFileReader(f, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read the Arrow file %s: %w", filename, err)
+	}
+	r.schema = fr.Schema()
+	i := 0
+	r.next = func() (arrow.Record, error) {
+		if i == fr.NumRecords() {
+			return nil, io.EOF
+		}
+		i++
+		return fr.Record(i - 1)
+	}
+	r.close = func() { fr.Close() }
+	return r, nil
+}
+
+func (r *arrowRecordReader) Close() error {
+	r.close()
+	return r.file.Close()
+}
+
+// arrowRows returns the number of rows of an Arrow IPC file.
+func arrowRows(filename string) (int64, error) {
+	r, err := openArrowFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	var rows int64
+	for {
+		rec, err := r.next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		rows += rec.NumRows()
+	}
+}
+
+func arrowColumns(filename string) ([]Column, error) {
+	r, err := openArrowFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	columns := make([]Column, r.schema.NumFields())
+	for i, field := range r.schema.Fields() {
+		columns[i] = Column{Name: field.Name, Type: field.Type.String()}
+	}
+	return columns, nil
+}
+
+func readArrowColumns(ctx context.Context, filename string, columns []string, fn func(row []string) error) (int64, error) {
+	r, err := openArrowFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	indices := make([]int, len(columns))
+	for i, column := range columns {
+		fields := r.schema.FieldIndices(column)
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("%s has no column %s", filename, column)
+		}
+		indices[i] = fields[0]
+	}
+
+	row := make([]string, len(columns))
+	var rows int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, err
+		}
+		rec, err := r.next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		for j := 0; j < int(rec.NumRows()); j++ {
+			for i, c := range indices {
+				col := rec.Column(c)
+				if col.IsNull(j) {
+					row[i] = ""
+				} else {
+					row[i] = col.ValueStr(j)
+				}
+			}
+			if err := fn(row); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+}
@@ -0,0 +1,742 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/klauspost/compress/snappy"
+)
+
+// avroMagic starts every Avro object container file.
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+// AvroCodecs are the codecs the blocks of Avro output can be compressed with.
+var AvroCodecs = []string{"deflate", "snappy", "null"}
+
+// CheckAvroCodec returns an error unless codec is an Avro codec.
+func CheckAvroCodec(codec string) error {
+	for _, c := range AvroCodecs {
+		if c == codec {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported Avro codec: %s (choose %s)", codec, strings.Join(AvroCodecs, ", "))
+}
+
+// avroBlockBytes is the size at which a block of an Avro container file is
+// written out.
+const avroBlockBytes = 1 << 20
+
+// avroWriter writes an Avro object container file: a header with the schema and
+// metadata, then blocks of encoded records, each compressed with the codec and
+// followed by the file's sync marker.
+type avroWriter struct {
+	w      io.Writer
+	codec  string
+	sync   [16]byte
+	block  []byte
+	count  int64
+	buf    bytes.Buffer
+	flate  *flate.Writer
+	snappy []byte
+}
+
+// newAvroWriter writes the header of a container file of records of schema to
+// w. The sync marker is derived from name, so the same records written to the
+// same file produce the same bytes.
+func newAvroWriter(w io.Writer, name, schema, codec string, meta map[string]string) (*avroWriter, error) {
+	a := &avroWriter{w: w, codec: codec}
+	switch codec {
+	case "null":
+	case "deflate":
+		a.flate, _ = flate.NewWriter(&a.buf, flate.DefaultCompression)
+	case "snappy":
+	default:
+		return nil, fmt.Errorf("unsupported Avro codec: %s", codec)
+	}
+	sum := sha256.Sum256([]byte(name))
+	copy(a.sync[:], sum[:])
+
+	all := map[string]string{"avro.schema": schema, "avro.codec": codec}
+	for k, v := range meta {
+		all[k] = v
+	}
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	header := append([]byte(nil), avroMagic...)
+	header = appendAvroLong(header, int64(len(keys)))
+	for _, k := range keys {
+		header = appendAvroString(header, k)
+		header = appendAvroString(header, all[k])
+	}
+	header = appendAvroLong(header, 0)
+	header = append(header, a.sync[:]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// append adds an encoded record to the current block.
+func (a *avroWriter) append(record []byte) error {
+	a.block = append(a.block, record...)
+	a.count++
+	if len(a.block) >= avroBlockBytes {
+		return a.flush()
+	}
+	return nil
+}
+
+// flush writes out the current block.
+func (a *avroWriter) flush() error {
+	if a.count == 0 {
+		return nil
+	}
+	data := a.block
+	switch a.codec {
+	case "deflate":
+		a.buf.Reset()
+		a.flate.Reset(&a.buf)
+		if _, err := a.flate.Write(a.block); err != nil {
+			return err
+		}
+		if err := a.flate.Close(); err != nil {
+			return err
+		}
+		data = a.buf.Bytes()
+	case "snappy":
+		// Snappy blocks are followed by the CRC-32 of the uncompressed data.
+		encoded := snappy.Encode(a.snappy[:cap(a.snappy)], a.block)
+		a.snappy = binary.BigEndian.AppendUint32(encoded, crc32.ChecksumIEEE(a.block))
+		data = a.snappy
+	}
+	head := appendAvroLong(nil, a.count)
+	head = appendAvroLong(head, int64(len(data)))
+	for _, p := range [][]byte{head, data, a.sync[:]} {
+		if _, err := a.w.Write(p); err != nil {
+			return err
+		}
+	}
+	a.block = a.block[:0]
+	a.count = 0
+	return nil
+}
+
+// close writes out the last block. The underlying writer is left open.
+func (a *avroWriter) close() error {
+	return a.flush()
+}
+
+// appendAvroLong appends v as an Avro int or long: zig-zag encoded, then as a
+// variable-length integer.
+func appendAvroLong(buf []byte, v int64) []byte {
+	return binary.AppendUvarint(buf, uint64(v<<1)^uint64(v>>63))
+}
+
+func appendAvroString(buf []byte, s string) []byte {
+	buf = appendAvroLong(buf, int64(len(s)))
+	return append(buf, s...)
+}
+
+func appendAvroBytes(buf []byte, b []byte) []byte {
+	buf = appendAvroLong(buf, int64(len(b)))
+	return append(buf, b...)
+}
+
+func appendAvroBoolean(buf []byte, v bool) []byte {
+	if v {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+func appendAvroFloat(buf []byte, v float32) []byte {
+	return binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+}
+
+func appendAvroDouble(buf []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+}
+
+// avroField is a field of the record schema of Avro output. Nullable columns
+// are unions of null and their type, defaulting to null.
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type avroRecordSchema struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Fields []avroField `json:"fields"`
+}
+
+// avroLogicalType annotates a primitive type, e.g. a long holding microseconds
+// since the epoch.
+type avroLogicalType struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+// avroType returns the Avro type of an Arrow column type.
+func avroType(t arrow.DataType) (interface{}, error) {
+	switch t := t.(type) {
+	case *arrow.BooleanType:
+		return "boolean", nil
+	case *arrow.Int32Type:
+		return "int", nil
+	case *arrow.Int64Type:
+		return "long", nil
+	case *arrow.Float32Type:
+		return "float", nil
+	case *arrow.Float64Type:
+		return "double", nil
+	case *arrow.StringType:
+		return "string", nil
+	case *arrow.Date32Type:
+		return avroLogicalType{Type: "int", LogicalType: "date"}, nil
+	case *arrow.TimestampType:
+		if t.Unit != arrow.Microsecond {
+			return nil, fmt.Errorf("unsupported timestamp unit %s", t.Unit)
+		}
+		if t.TimeZone == "" {
+			return avroLogicalType{Type: "long", LogicalType: "local-timestamp-micros"}, nil
+		}
+		return avroLogicalType{Type: "long", LogicalType: "timestamp-micros"}, nil
+	}
+	return nil, fmt.Errorf("unsupported column type %s", t)
+}
+
+// avroSchemaOf returns the JSON of the Avro record schema named name whose
+// fields are the columns of schema.
+func avroSchemaOf(schema *arrow.Schema, name string) (string, error) {
+	record := avroRecordSchema{Type: "record", Name: name}
+	for _, field := range schema.Fields() {
+		if !isAvroName(field.Name) {
+			return "", fmt.Errorf("column %s is not a valid Avro name", field.Name)
+		}
+		typ, err := avroType(field.Type)
+		if err != nil {
+			return "", fmt.Errorf("column %s: %w", field.Name, err)
+		}
+		f := avroField{Name: field.Name, Type: typ}
+		if field.Nullable {
+			f.Type = []interface{}{"null", typ}
+			f.Default = json.RawMessage("null")
+		}
+		record.Fields = append(record.Fields, f)
+	}
+	b, err := json.Marshal(record)
+	return string(b), err
+}
+
+// isAvroName reports whether name can name an Avro record or field: a letter
+// or underscore, followed by letters, digits and underscores.
+func isAvroName(name string) bool {
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// avroRecordName returns the name of the records of the Avro file filename:
+// its table, which is the directory above the partition directories of
+// partitioned files.
+func avroRecordName(filename string) string {
+	name := TableOfFile(filepath.Base(filename))
+	for dir := filepath.Dir(filename); strings.Contains(filepath.Base(dir), "="); dir = filepath.Dir(dir) {
+		name = filepath.Base(filepath.Dir(dir))
+	}
+	var b strings.Builder
+	for i, r := range name {
+		if !isAvroName(string(r)) && !(i > 0 && r >= '0' && r <= '9') {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// avroRecordWriter writes record batches as the records of an Avro object
+// container file, with the schema derived from their Arrow schema.
+type avroRecordWriter struct {
+	avro     *avroWriter
+	buf      *bufio.Writer
+	file     *OutputFile
+	nullable []bool
+	record   []byte
+}
+
+func newAvroRecordWriter(schema *arrow.Schema, file *OutputFile, targetFilename, codec string) (*avroRecordWriter, error) {
+	avroSchema, err := avroSchemaOf(schema, avroRecordName(targetFilename))
+	if err != nil {
+		return nil, err
+	}
+	w := &avroRecordWriter{buf: bufio.NewWriterSize(file, 1<<20), file: file}
+	if w.avro, err = newAvroWriter(w.buf, filepath.Base(targetFilename), avroSchema, codec, nil); err != nil {
+		return nil, err
+	}
+	for _, field := range schema.Fields() {
+		w.nullable = append(w.nullable, field.Nullable)
+	}
+	return w, nil
+}
+
+func (w *avroRecordWriter) Write(record arrow.Record) error {
+	columns := record.Columns()
+	for j := 0; j < int(record.NumRows()); j++ {
+		w.record = w.record[:0]
+		for i, col := range columns {
+			if w.nullable[i] {
+				if col.IsNull(j) {
+					w.record = appendAvroLong(w.record, 0)
+					continue
+				}
+				w.record = appendAvroLong(w.record, 1)
+			} else if col.IsNull(j) {
+				return fmt.Errorf("null in non-nullable column %s", record.ColumnName(i))
+			}
+			var err error
+			if w.record, err = appendAvroValue(w.record, col, j); err != nil {
+				return fmt.Errorf("column %s: %w", record.ColumnName(i), err)
+			}
+		}
+		if err := w.avro.append(w.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes out the last block and closes the file.
+func (w *avroRecordWriter) Close() error {
+	err := w.avro.close()
+	if flushErr := w.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// appendAvroValue appends the value of row j of col in the encoding of its Avro
+// type.
+func appendAvroValue(buf []byte, col arrow.Array, j int) ([]byte, error) {
+	switch col := col.(type) {
+	case *array.Boolean:
+		return appendAvroBoolean(buf, col.Value(j)), nil
+	case *array.Int32:
+		return appendAvroLong(buf, int64(col.Value(j))), nil
+	case *array.Int64:
+		return appendAvroLong(buf, col.Value(j)), nil
+	case *array.Float32:
+		return appendAvroFloat(buf, col.Value(j)), nil
+	case *array.Float64:
+		return appendAvroDouble(buf, col.Value(j)), nil
+	case *array.String:
+		return appendAvroString(buf, col.Value(j)), nil
+	case *array.Date32:
+		return appendAvroLong(buf, int64(col.Value(j))), nil
+	case *array.Timestamp:
+		return appendAvroLong(buf, int64(col.Value(j))), nil
+	}
+	return buf, fmt.Errorf("unsupported column type %s", col.DataType())
+}
+
+// avroColumn is a field of the record schema of an Avro file being read.
+type avroColumn struct {
+	name    string
+	typ     string
+	logical string
+	// nullIndex is the branch of null in the union of a nullable field, or -1.
+	nullIndex int64
+}
+
+// parseAvroSchema returns the fields of a record schema of primitive types,
+// optionally annotated with a logical type or in a union with null.
+func parseAvroSchema(schema string) ([]avroColumn, error) {
+	var record struct {
+		Type   string `json:"type"`
+		Fields []struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schema), &record); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	if record.Type != "record" {
+		return nil, fmt.Errorf("unsupported Avro schema of type %s", record.Type)
+	}
+	columns := make([]avroColumn, len(record.Fields))
+	for i, f := range record.Fields {
+		c, err := parseAvroType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		c.name = f.Name
+		columns[i] = c
+	}
+	return columns, nil
+}
+
+func parseAvroType(raw json.RawMessage) (avroColumn, error) {
+	c := avroColumn{nullIndex: -1}
+	var union []json.RawMessage
+	if json.Unmarshal(raw, &union) == nil {
+		if len(union) != 2 {
+			return c, fmt.Errorf("unsupported union of %d types", len(union))
+		}
+		for i, branch := range union {
+			var name string
+			if json.Unmarshal(branch, &name) == nil && name == "null" {
+				value, err := parseAvroType(union[1-i])
+				if err != nil || value.nullIndex >= 0 {
+					return c, fmt.Errorf("unsupported union %s", raw)
+				}
+				value.nullIndex = int64(i)
+				return value, nil
+			}
+		}
+		return c, fmt.Errorf("unsupported union %s", raw)
+	}
+	if json.Unmarshal(raw, &c.typ) != nil {
+		var logical avroLogicalType
+		if err := json.Unmarshal(raw, &logical); err != nil {
+			return c, fmt.Errorf("unsupported type %s", raw)
+		}
+		c.typ, c.logical = logical.Type, logical.LogicalType
+	}
+	switch c.typ {
+	case "boolean", "int", "long", "float", "double", "string", "bytes":
+		return c, nil
+	}
+	return c, fmt.Errorf("unsupported type %s", raw)
+}
+
+// avroReader reads the blocks of an Avro object container file.
+type avroReader struct {
+	file    *os.File
+	r       *bufio.Reader
+	codec   string
+	sync    [16]byte
+	columns []avroColumn
+	data    []byte
+}
+
+// openAvroFile opens filename and reads its header.
+func openAvroFile(filename string) (*avroReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	a := &avroReader{file: f, r: bufio.NewReaderSize(f, 1<<20)}
+	if err := a.readHeader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read the Avro file %s: %w", filename, err)
+	}
+	return a, nil
+}
+
+func (a *avroReader) readHeader() error {
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(a.r, magic); err != nil || !bytes.Equal(magic, avroMagic) {
+		return fmt.Errorf("not an Avro object container file")
+	}
+	meta := make(map[string]string)
+	for {
+		count, err := readAvroLong(a.r)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			// A negative count is followed by the size of the block in bytes.
+			count = -count
+			if _, err := readAvroLong(a.r); err != nil {
+				return err
+			}
+		}
+		for ; count > 0; count-- {
+			k, err := readAvroBytes(a.r)
+			if err != nil {
+				return err
+			}
+			v, err := readAvroBytes(a.r)
+			if err != nil {
+				return err
+			}
+			meta[string(k)] = string(v)
+		}
+	}
+	if _, err := io.ReadFull(a.r, a.sync[:]); err != nil {
+		return err
+	}
+
+	a.codec = meta["avro.codec"]
+	switch a.codec {
+	case "":
+		a.codec = "null"
+	case "null", "deflate", "snappy":
+	default:
+		return fmt.Errorf("unsupported codec %s", a.codec)
+	}
+	var err error
+	a.columns, err = parseAvroSchema(meta["avro.schema"])
+	return err
+}
+
+// next reads the next block, returning its record count and, if decode is set,
+// its decompressed records. It returns io.EOF after the last block.
+func (a *avroReader) next(decode bool) (int64, []byte, error) {
+	count, err := readAvroLong(a.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := readAvroLong(a.r)
+	if err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if count < 0 || size < 0 {
+		return 0, nil, fmt.Errorf("invalid block header")
+	}
+	var data []byte
+	if decode {
+		if int64(cap(a.data)) < size {
+			a.data = make([]byte, size)
+		}
+		data = a.data[:size]
+		if _, err := io.ReadFull(a.r, data); err != nil {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		if data, err = a.decompress(data); err != nil {
+			return 0, nil, err
+		}
+	} else if _, err := a.r.Discard(int(size)); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	var sync [16]byte
+	if _, err := io.ReadFull(a.r, sync[:]); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if sync != a.sync {
+		return 0, nil, fmt.Errorf("invalid sync marker")
+	}
+	return count, data, nil
+}
+
+func (a *avroReader) decompress(data []byte) ([]byte, error) {
+	switch a.codec {
+	case "deflate":
+		return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	case "snappy":
+		if len(data) < 4 {
+			return nil, fmt.Errorf("invalid snappy block")
+		}
+		block, err := snappy.Decode(nil, data[:len(data)-4])
+		if err != nil {
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(block) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+			return nil, fmt.Errorf("snappy block checksum mismatch")
+		}
+		return block, nil
+	}
+	return data, nil
+}
+
+func (a *avroReader) Close() error {
+	return a.file.Close()
+}
+
+func readAvroLong(r io.ByteReader) (int64, error) {
+	v, err := binary.ReadUvarint(r)
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func readAvroBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := readAvroLong(r)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+// avroDecoder decodes the records of a block.
+type avroDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *avroDecoder) long() int64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *avroDecoder) bytes(n int64) []byte {
+	if n < 0 || n > int64(len(d.buf)) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+// value decodes a value of column c as text; null is "".
+func (d *avroDecoder) value(c avroColumn) string {
+	if c.nullIndex >= 0 && d.long() == c.nullIndex {
+		return ""
+	}
+	switch c.typ {
+	case "boolean":
+		b := d.bytes(1)
+		return strconv.FormatBool(len(b) == 1 && b[0] != 0)
+	case "int", "long":
+		v := d.long()
+		switch c.logical {
+		case "date":
+			return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+		case "timestamp-micros", "local-timestamp-micros":
+			return time.UnixMicro(v).UTC().Format("2006-01-02 15:04:05.999999")
+		}
+		return strconv.FormatInt(v, 10)
+	case "float":
+		if b := d.bytes(4); b != nil {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32)
+		}
+	case "double":
+		if b := d.bytes(8); b != nil {
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64)
+		}
+	case "string", "bytes":
+		return string(d.bytes(d.long()))
+	}
+	return ""
+}
+
+// avroRows returns the number of records of an Avro file, from the counts of
+// its blocks.
+func avroRows(filename string) (int64, error) {
+	a, err := openAvroFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer a.Close()
+	var rows int64
+	for {
+		count, _, err := a.next(false)
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		rows += count
+	}
+}
+
+func avroColumns(filename string) ([]Column, error) {
+	a, err := openAvroFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+	columns := make([]Column, len(a.columns))
+	for i, c := range a.columns {
+		columns[i] = Column{Name: c.name, Type: c.typ}
+		if c.logical != "" {
+			columns[i].Type = c.logical
+		}
+	}
+	return columns, nil
+}
+
+func readAvroColumns(ctx context.Context, filename string, columns []string, fn func(row []string) error) (int64, error) {
+	a, err := openAvroFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer a.Close()
+	indices := make([]int, len(columns))
+	for i, column := range columns {
+		indices[i] = -1
+		for j, c := range a.columns {
+			if c.name == column {
+				indices[i] = j
+				break
+			}
+		}
+		if indices[i] < 0 {
+			return 0, fmt.Errorf("%s has no column %s", filename, column)
+		}
+	}
+
+	values := make([]string, len(a.columns))
+	row := make([]string, len(columns))
+	var rows int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, err
+		}
+		count, data, err := a.next(true)
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		d := avroDecoder{buf: data}
+		for ; count > 0; count-- {
+			for j, c := range a.columns {
+				values[j] = d.value(c)
+			}
+			if d.err != nil {
+				return rows, fmt.Errorf("failed to read %s: %w", filename, d.err)
+			}
+			for i, j := range indices {
+				row[i] = values[j]
+			}
+			if err := fn(row); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+}
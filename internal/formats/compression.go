@@ -0,0 +1,171 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Compressions are the codecs text output (CSV and JSON Lines) can be
+// compressed with. Arrow IPC files compress their buffers with zstd or lz4.
+var Compressions = []string{"gzip", "zstd", "lz4"}
+
+// compressionExtensions are the extensions compressed files get after the
+// extension of their format, e.g. fact_store_sales_0.csv.gz.
+var compressionExtensions = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+	"lz4":  ".lz4",
+}
+
+// CheckCompression returns an error unless files of format can be compressed
+// with codec.
+func CheckCompression(codec, format string) error {
+	if codec == "" {
+		return nil
+	}
+	if _, ok := compressionExtensions[codec]; !ok {
+		return fmt.Errorf("unsupported compression: %s (choose %s)", codec, strings.Join(Compressions, ", "))
+	}
+	if format == "arrow" {
+		if codec == "gzip" {
+			return fmt.Errorf("Arrow IPC buffers cannot be compressed with gzip (choose zstd or lz4)")
+		}
+		return nil
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("--compression only applies to csv, json and arrow output, not %s", format)
+	}
+	return nil
+}
+
+// DataExtension returns the extension naming the format of filename, without
+// the extension of its compression: .csv for fact_store_sales_0.csv.gz.
+func DataExtension(filename string) string {
+	ext := filepath.Ext(filename)
+	if codecOf(filename) != "" {
+		return filepath.Ext(strings.TrimSuffix(filename, ext))
+	}
+	return ext
+}
+
+// codecOf returns the codec filename is compressed with, by its extension.
+func codecOf(filename string) string {
+	ext := filepath.Ext(filename)
+	for codec, e := range compressionExtensions {
+		if ext == e {
+			return codec
+		}
+	}
+	return ""
+}
+
+// CreateTextFile creates the temporary file for filename like CreateOutputFile,
+// compressing what is written to it with the compression of o, which
+// FileExtension names in the extension. Closing it closes the compressor and
+// then the file. Each shard is compressed by the worker writing it, so
+// compression runs in parallel across shards. The lines written are counted
+// as the rows of the file.
+func (o Options) CreateTextFile(filename string) (io.WriteCloser, error) {
+	f, err := CreateOutputFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var enc io.WriteCloser
+	switch o.Compression {
+	case "gzip":
+		enc = gzip.NewWriter(f)
+	case "zstd":
+		enc, err = zstd.NewWriter(f, zstd.WithEncoderConcurrency(1))
+	case "lz4":
+		enc = lz4.NewWriter(f)
+	default:
+		return &textFile{Writer: f, file: f}, nil
+	}
+	if err != nil {
+		f.Close()
+		DiscardFile(filename)
+		return nil, fmt.Errorf("failed to create compressor for %s: %w", filename, err)
+	}
+	return &textFile{Writer: enc, enc: enc, file: f}, nil
+}
+
+// textFile is a text file, written through a compressor if enc is set.
+type textFile struct {
+	io.Writer
+	enc  io.Closer
+	file *OutputFile
+}
+
+func (t *textFile) Write(p []byte) (int, error) {
+	n, err := t.Writer.Write(p)
+	t.file.AddRows(int64(bytes.Count(p[:n], []byte{'\n'})))
+	return n, err
+}
+
+func (t *textFile) Close() error {
+	var encErr error
+	if t.enc != nil {
+		encErr = t.enc.Close()
+	}
+	fileErr := t.file.Close()
+	if encErr != nil {
+		return encErr
+	}
+	return fileErr
+}
+
+// OpenTextFile opens filename for reading, decompressing it if its extension
+// names a codec.
+func OpenTextFile(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(filename, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &decompressedFile{ReadCloser: r, file: f}, nil
+}
+
+// decompress returns a reader of the contents of filename, read compressed
+// from r.
+func decompress(filename string, r io.Reader) (io.ReadCloser, error) {
+	switch codecOf(filename) {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header of %s: %w", filename, err)
+		}
+		return zr, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd reader for %s: %w", filename, err)
+		}
+		return zr.IOReadCloser(), nil
+	case "lz4":
+		return io.NopCloser(lz4.NewReader(r)), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// decompressedFile is a file read through a decompressor.
+type decompressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (d *decompressedFile) Close() error {
+	d.ReadCloser.Close()
+	return d.file.Close()
+}
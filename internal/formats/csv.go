@@ -2,9 +2,9 @@ package formats
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -50,78 +50,63 @@ func fastItoa(buf []byte, v int64) []byte {
 }
 
 // WriteStream writes data from a channel to a CSV file.
-func WriteStream(data <-chan interface{}, filename, format, outputDir string) error {
-	switch format {
+func WriteStream(data <-chan interface{}, filename string, opts Options, outputDir string) error {
+	switch opts.Format {
 	case "csv":
-		return writeStreamToCSV(data, filepath.Join(outputDir, filename+".csv"))
+		return writeStreamToCSV(data, opts.TablePath(outputDir, filename)+opts.FileExtension(), opts)
 	// Add other formats here if needed
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
-func writeStreamToCSV(data <-chan interface{}, targetFilename string) error {
-	file, err := os.Create(targetFilename)
+func writeStreamToCSV(data <-chan interface{}, targetFilename string, opts Options) (err error) {
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	bufferedWriter := bufio.NewWriterSize(file, 1024*1024) // 1MB buffer
-	defer bufferedWriter.Flush()
+	defer opts.CloseBufferedFile(bufferedWriter, file, targetFilename, &err)
 
+	d := opts.CSV
 	first := true
+	rowBuf := make([]byte, 0, 1024)
 	for v := range data {
 		if first {
-			headers := getCSVHeaders(v)
-			for i, header := range headers {
-				if i > 0 {
-					bufferedWriter.WriteByte(',')
-				}
-				bufferedWriter.WriteString(header)
-			}
-			bufferedWriter.WriteByte('\n')
+			bufferedWriter.Write(d.HeaderLine(getCSVHeaders(v)))
 			first = false
 		}
-
-		record := toCSVRecord(v)
-		for i, field := range record {
-			if i > 0 {
-				bufferedWriter.WriteByte(',')
-			}
-			if needsQuoting(field) {
-				bufferedWriter.WriteByte('"')
-				for _, r := range field {
-					if r == '"' {
-						bufferedWriter.WriteString("\"")
-					} else {
-						bufferedWriter.WriteRune(r)
-					}
-				}
-				bufferedWriter.WriteByte('"')
-			} else {
-				bufferedWriter.WriteString(field)
-			}
+		if rowBuf, err = d.appendRecord(rowBuf[:0], reflect.ValueOf(v)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
 		}
-		bufferedWriter.WriteByte('\n')
+		bufferedWriter.Write(rowBuf)
 	}
 
 	return nil
 }
 
-// WriteSliceData writes any slice of structs to a CSV file.
-func WriteSliceData(data interface{}, filename, format, outputDir string) error {
-	switch format {
+// ctxCheckInterval is the number of rows slice writers write between checks for
+// a cancelled run.
+const ctxCheckInterval = 4096
+
+// WriteSliceData writes any slice of structs to a file in the format of opts.
+func WriteSliceData(ctx context.Context, data interface{}, filename string, opts Options, outputDir string) error {
+	switch opts.Format {
 	case "csv":
-		return writeSliceToCSV(data, filepath.Join(outputDir, filename+".csv"))
-	case "parquet":
-		return WriteSliceToParquet(data, filepath.Join(outputDir, filename+".parquet"))
+		return writeSliceToCSV(ctx, data, opts.TablePath(outputDir, filename)+opts.FileExtension(), opts)
+	case "parquet", "arrow", "avro":
+		return WriteSliceRecords(ctx, data, opts.TablePath(outputDir, filename)+opts.FileExtension(), opts)
+	case "json":
+		return writeSliceToJSON(ctx, data, opts.TablePath(outputDir, filename)+opts.FileExtension(), opts)
+	case "dsdgen":
+		return writeSliceToDat(ctx, data, filename, opts.TablePath(outputDir, filename)+opts.FileExtension(), opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
-func writeSliceToCSV(data interface{}, targetFilename string) error {
+func writeSliceToCSV(ctx context.Context, data interface{}, targetFilename string, opts Options) (err error) {
 	slice := reflect.ValueOf(data)
 	if slice.Kind() != reflect.Slice {
 		return fmt.Errorf("data is not a slice")
@@ -131,14 +116,32 @@ func writeSliceToCSV(data interface{}, targetFilename string) error {
 		return nil // Nothing to write
 	}
 
-	headers := getCSVHeaders(slice.Index(0).Interface())
-	records := make([][]string, slice.Len())
+	startTime := time.Now()
+	file, err := opts.CreateTextFile(targetFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
+	}
+	bufferedWriter := bufio.NewWriterSize(file, 16*1024*1024) // 16MB buffer
+	defer opts.CloseBufferedFile(bufferedWriter, file, targetFilename, &err)
 
+	d := opts.CSV
+	bufferedWriter.Write(d.HeaderLine(getCSVHeaders(slice.Index(0).Interface())))
+	rowBuf := make([]byte, 0, 1024)
 	for i := 0; i < slice.Len(); i++ {
-		records[i] = toCSVRecord(slice.Index(i).Interface())
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if rowBuf, err = d.appendRecord(rowBuf[:0], reflect.ValueOf(slice.Index(i).Interface())); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		bufferedWriter.Write(rowBuf)
 	}
 
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	duration := time.Since(startTime)
+	fmt.Printf("Successfully wrote %d records to %s in %s\n", slice.Len(), targetFilename, duration.Round(time.Millisecond))
+	return nil
 }
 
 func getCSVHeaders(v interface{}) []string {
@@ -160,72 +163,96 @@ func getCSVHeaders(v interface{}) []string {
 	return headers
 }
 
-func toCSVRecord(v interface{}) []string {
-	val := reflect.ValueOf(v)
-	record := make([]string, val.NumField())
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		switch field.Kind() {
-		case reflect.Int, reflect.Int64:
-			record[i] = strconv.FormatInt(field.Int(), 10)
-		case reflect.Float64:
-			record[i] = strconv.FormatFloat(field.Float(), 'f', -1, 64)
-		case reflect.String:
-			record[i] = field.String()
-		case reflect.Struct:
-			if t, ok := field.Interface().(time.Time); ok {
-				record[i] = t.Format(time.RFC3339)
-			}
+// appendRecord appends the fields of the struct v as a row.
+func (d CSVDialect) appendRecord(buf []byte, v reflect.Value) ([]byte, error) {
+	for i := 0; i < v.NumField(); i++ {
+		if i > 0 {
+			buf = append(buf, d.Delimiter...)
+		}
+		var err error
+		if buf, err = d.appendValue(buf, v.Field(i)); err != nil {
+			return buf, err
 		}
 	}
-	return record
+	return append(buf, d.lineEnd()...), nil
+}
+
+// appendValue appends a struct field. Timestamps are RFC 3339; nil pointers,
+// NaN and infinities are written as NULL.
+func (d CSVDialect) appendValue(buf []byte, v reflect.Value) ([]byte, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return append(buf, d.Null...), nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return d.appendField(buf, v.String())
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return append(buf, d.Null...), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Bool:
+	default:
+		if _, ok := v.Interface().(time.Time); !ok {
+			return append(buf, d.Null...), nil
+		}
+	}
+
+	// Numbers, booleans and timestamps never need quoting.
+	if d.Quoting == "all" {
+		buf = append(buf, '"')
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		buf = strconv.AppendFloat(buf, v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		buf = strconv.AppendBool(buf, v.Bool())
+	case reflect.Struct:
+		buf = v.Interface().(time.Time).AppendFormat(buf, time.RFC3339)
+	default:
+		buf = strconv.AppendInt(buf, v.Int(), 10)
+	}
+	if d.Quoting == "all" {
+		buf = append(buf, '"')
+	}
+	return buf, nil
 }
 
 // writeCSVHeaderAndRecords writes CSV data using direct byte formatting instead of encoding/csv
 // for better performance with large datasets
-func writeCSVHeaderAndRecords(targetFilename string, headers []string, records [][]string) error {
+func writeCSVHeaderAndRecords(ctx context.Context, targetFilename string, headers []string, records [][]string, opts Options) (err error) {
 	startTime := time.Now()
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	// Use large buffer for better I/O performance
 	bufferedWriter := bufio.NewWriterSize(file, 16*1024*1024) // 16MB buffer
-	defer bufferedWriter.Flush()
-
-	// Write header directly with byte operations
-	for i, header := range headers {
-		if i > 0 {
-			bufferedWriter.WriteByte(',')
+	defer opts.CloseBufferedFile(bufferedWriter, file, targetFilename, &err)
+
+	d := opts.CSV
+	bufferedWriter.Write(d.HeaderLine(headers))
+	rowBuf := make([]byte, 0, 1024)
+	for n, record := range records {
+		if n%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
 		}
-		bufferedWriter.WriteString(header)
-	}
-	bufferedWriter.WriteByte('\n')
-
-	// Write records directly with byte operations
-	for _, record := range records {
+		rowBuf = rowBuf[:0]
 		for i, field := range record {
 			if i > 0 {
-				bufferedWriter.WriteByte(',')
+				rowBuf = append(rowBuf, d.Delimiter...)
 			}
-			// Simple field writing - escape if contains comma or quote
-			if needsQuoting(field) {
-				bufferedWriter.WriteByte('"')
-				for _, r := range field {
-					if r == '"' {
-						bufferedWriter.WriteString("\"")
-					} else {
-						bufferedWriter.WriteRune(r)
-					}
-				}
-				bufferedWriter.WriteByte('"')
-			} else {
-				bufferedWriter.WriteString(field)
+			if rowBuf, err = d.appendField(rowBuf, field); err != nil {
+				return fmt.Errorf("failed to write %s: %w", targetFilename, err)
 			}
 		}
-		bufferedWriter.WriteByte('\n')
+		rowBuf = append(rowBuf, d.lineEnd()...)
+		bufferedWriter.Write(rowBuf)
 	}
 
 	duration := time.Since(startTime)
@@ -233,60 +260,44 @@ func writeCSVHeaderAndRecords(targetFilename string, headers []string, records [
 	return nil
 }
 
-// needsQuoting checks if a field needs CSV quoting
-func needsQuoting(field string) bool {
-	for i := 0; i < len(field); i++ {
-		c := field[i]
-		if c == ',' || c == '"' || c == '\n' || c == '\r' {
-			return true
-		}
-	}
-	return false
-}
-
-func appendCSVField(buf []byte, field string) []byte {
-	if needsQuoting(field) {
-		buf = append(buf, '"')
-		for i := 0; i < len(field); i++ {
-			if field[i] == '"' {
-				buf = append(buf, '"', '"')
-			} else {
-				buf = append(buf, field[i])
-			}
-		}
-		buf = append(buf, '"')
-	} else {
-		buf = append(buf, field...)
-	}
-	return buf
-}
-
 // --- Dimension Writers (kept as original for focus) ---
 
-func WriteCustomersToCSV(customers []ecommercemodels.Customer, targetFilename string) error {
+func WriteCustomersToCSV(ctx context.Context, customers []ecommercemodels.Customer, targetFilename string, opts Options) (err error) {
 	startTime := time.Now()
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	bw := bufio.NewWriterSize(file, 16*1024*1024)
-	defer bw.Flush()
+	defer opts.CloseBufferedFile(bw, file, targetFilename, &err)
 
-	bw.WriteString("customer_id,first_name,last_name,email\n")
+	d := opts.CSV
+	bw.Write(d.HeaderLine([]string{"customer_id", "first_name", "last_name", "email"}))
+	fields, end := d.Framing(4)
 
 	rowBuf := make([]byte, 0, 256)
-	for _, c := range customers {
-		rowBuf = rowBuf[:0]
+	for i, c := range customers {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rowBuf = append(rowBuf[:0], fields[0]...)
 		rowBuf = fastItoa(rowBuf, int64(c.CustomerID))
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendCSVField(rowBuf, c.FirstName)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendCSVField(rowBuf, c.LastName)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendCSVField(rowBuf, c.Email)
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, fields[1]...)
+		if rowBuf, err = d.AppendText(rowBuf, c.FirstName); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[2]...)
+		if rowBuf, err = d.AppendText(rowBuf, c.LastName); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[3]...)
+		if rowBuf, err = d.AppendText(rowBuf, c.Email); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, end...)
 		bw.Write(rowBuf)
 	}
 
@@ -295,38 +306,56 @@ func WriteCustomersToCSV(customers []ecommercemodels.Customer, targetFilename st
 	return nil
 }
 
-func WriteCustomerAddressesToCSV(addresses []ecommercemodels.CustomerAddress, targetFilename string) error {
+func WriteCustomerAddressesToCSV(ctx context.Context, addresses []ecommercemodels.CustomerAddress, targetFilename string, opts Options) (err error) {
 	startTime := time.Now()
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	bw := bufio.NewWriterSize(file, 16*1024*1024)
-	defer bw.Flush()
+	defer opts.CloseBufferedFile(bw, file, targetFilename, &err)
 
-	bw.WriteString("address_id,customer_id,address_type,address,city,state,zip,country\n")
+	d := opts.CSV
+	bw.Write(d.HeaderLine([]string{"address_id", "customer_id", "address_type", "address", "city", "state", "zip", "country"}))
+	fields, end := d.Framing(8)
 
 	rowBuf := make([]byte, 0, 512)
-	for _, a := range addresses {
-		rowBuf = rowBuf[:0]
+	for i, a := range addresses {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rowBuf = append(rowBuf[:0], fields[0]...)
 		rowBuf = fastItoa(rowBuf, int64(a.AddressID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[1]...)
 		rowBuf = fastItoa(rowBuf, int64(a.CustomerID))
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, a.AddressType...)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendCSVField(rowBuf, a.Address)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, a.City...)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, a.State...)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, a.Zip...)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, a.Country...)
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, fields[2]...)
+		if rowBuf, err = d.AppendText(rowBuf, a.AddressType); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[3]...)
+		if rowBuf, err = d.AppendText(rowBuf, a.Address); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[4]...)
+		if rowBuf, err = d.AppendText(rowBuf, a.City); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[5]...)
+		if rowBuf, err = d.AppendText(rowBuf, a.State); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[6]...)
+		if rowBuf, err = d.AppendText(rowBuf, a.Zip); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[7]...)
+		if rowBuf, err = d.AppendText(rowBuf, a.Country); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, end...)
 		bw.Write(rowBuf)
 	}
 
@@ -335,28 +364,38 @@ func WriteCustomerAddressesToCSV(addresses []ecommercemodels.CustomerAddress, ta
 	return nil
 }
 
-func WriteSuppliersToCSV(suppliers []ecommercemodels.Supplier, targetFilename string) error {
+func WriteSuppliersToCSV(ctx context.Context, suppliers []ecommercemodels.Supplier, targetFilename string, opts Options) (err error) {
 	startTime := time.Now()
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	bw := bufio.NewWriterSize(file, 16*1024*1024)
-	defer bw.Flush()
+	defer opts.CloseBufferedFile(bw, file, targetFilename, &err)
 
-	bw.WriteString("supplier_id,supplier_name,country\n")
+	d := opts.CSV
+	bw.Write(d.HeaderLine([]string{"supplier_id", "supplier_name", "country"}))
+	fields, end := d.Framing(3)
 
 	rowBuf := make([]byte, 0, 256)
-	for _, s := range suppliers {
-		rowBuf = rowBuf[:0]
+	for i, s := range suppliers {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rowBuf = append(rowBuf[:0], fields[0]...)
 		rowBuf = fastItoa(rowBuf, int64(s.SupplierID))
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendCSVField(rowBuf, s.SupplierName)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, s.Country...)
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, fields[1]...)
+		if rowBuf, err = d.AppendText(rowBuf, s.SupplierName); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[2]...)
+		if rowBuf, err = d.AppendText(rowBuf, s.Country); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, end...)
 		bw.Write(rowBuf)
 	}
 
@@ -365,26 +404,34 @@ func WriteSuppliersToCSV(suppliers []ecommercemodels.Supplier, targetFilename st
 	return nil
 }
 
-func WriteProductCategoriesToCSV(categories []ecommercemodels.ProductCategory, targetFilename string) error {
+func WriteProductCategoriesToCSV(ctx context.Context, categories []ecommercemodels.ProductCategory, targetFilename string, opts Options) (err error) {
 	startTime := time.Now()
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	bw := bufio.NewWriterSize(file, 16*1024*1024)
-	defer bw.Flush()
+	defer opts.CloseBufferedFile(bw, file, targetFilename, &err)
 
-	bw.WriteString("category_id,category_name\n")
+	d := opts.CSV
+	bw.Write(d.HeaderLine([]string{"category_id", "category_name"}))
+	fields, end := d.Framing(2)
 
 	rowBuf := make([]byte, 0, 128)
-	for _, c := range categories {
-		rowBuf = rowBuf[:0]
+	for i, c := range categories {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rowBuf = append(rowBuf[:0], fields[0]...)
 		rowBuf = fastItoa(rowBuf, int64(c.CategoryID))
-		rowBuf = append(rowBuf, ',')
-		rowBuf = append(rowBuf, c.CategoryName...)
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, fields[1]...)
+		if rowBuf, err = d.AppendText(rowBuf, c.CategoryName); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, end...)
 		bw.Write(rowBuf)
 	}
 
@@ -393,32 +440,40 @@ func WriteProductCategoriesToCSV(categories []ecommercemodels.ProductCategory, t
 	return nil
 }
 
-func WriteProductsToCSV(products []ecommercemodels.Product, targetFilename string) error {
+func WriteProductsToCSV(ctx context.Context, products []ecommercemodels.Product, targetFilename string, opts Options) (err error) {
 	startTime := time.Now()
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	bw := bufio.NewWriterSize(file, 16*1024*1024)
-	defer bw.Flush()
+	defer opts.CloseBufferedFile(bw, file, targetFilename, &err)
 
-	bw.WriteString("product_id,supplier_id,product_name,category_id,base_price\n")
+	d := opts.CSV
+	bw.Write(d.HeaderLine([]string{"product_id", "supplier_id", "product_name", "category_id", "base_price"}))
+	fields, end := d.Framing(5)
 
 	rowBuf := make([]byte, 0, 256)
-	for _, p := range products {
-		rowBuf = rowBuf[:0]
+	for i, p := range products {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rowBuf = append(rowBuf[:0], fields[0]...)
 		rowBuf = fastItoa(rowBuf, int64(p.ProductID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[1]...)
 		rowBuf = fastItoa(rowBuf, int64(p.SupplierID))
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendCSVField(rowBuf, p.ProductName)
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[2]...)
+		if rowBuf, err = d.AppendText(rowBuf, p.ProductName); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
+		}
+		rowBuf = append(rowBuf, fields[3]...)
 		rowBuf = fastItoa(rowBuf, int64(p.CategoryID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[4]...)
 		rowBuf = strconv.AppendFloat(rowBuf, p.BasePrice, 'f', 2, 64)
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, end...)
 		bw.Write(rowBuf)
 	}
 
@@ -431,19 +486,20 @@ func WriteProductsToCSV(products []ecommercemodels.Product, targetFilename strin
 
 // WriteStreamOrderHeadersToCSV writes OrderHeader structs from a channel to a CSV file using a buffered writer.
 // WriteStreamOrderHeadersToCSV writes order headers using direct byte formatting for better performance
-func WriteStreamOrderHeadersToCSV(headerChan <-chan ecommercemodels.OrderHeader, targetFilename string) error {
-	file, err := os.Create(targetFilename)
+func WriteStreamOrderHeadersToCSV(headerChan <-chan ecommercemodels.OrderHeader, targetFilename string, opts Options) (err error) {
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	// Use large buffered writer for better performance with high-volume data
 	bufferedWriter := bufio.NewWriterSize(file, 2*1024*1024) // 2MB buffer
-	defer bufferedWriter.Flush()
+	defer opts.CloseBufferedFile(bufferedWriter, file, targetFilename, &err)
 
 	// Write header directly
-	bufferedWriter.WriteString("order_id,customer_id,shipping_address_id,billing_address_id,order_timestamp,order_status\n")
+	d := opts.CSV
+	bufferedWriter.Write(d.HeaderLine([]string{"order_id", "customer_id", "shipping_address_id", "billing_address_id", "order_timestamp", "order_status"}))
+	fields, end := d.Framing(6)
 
 	// Pre-allocate buffer for row construction to reduce allocations
 	rowBuf := make([]byte, 0, 1024) // 1KB buffer per row for better performance
@@ -451,38 +507,28 @@ func WriteStreamOrderHeadersToCSV(headerChan <-chan ecommercemodels.OrderHeader,
 
 	for h := range headerChan {
 		// Reset buffer for reuse
-		rowBuf = rowBuf[:0]
+		rowBuf = append(rowBuf[:0], fields[0]...)
 
 		// Build row with direct byte operations
 		rowBuf = fastItoa(rowBuf, int64(h.OrderID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[1]...)
 		rowBuf = fastItoa(rowBuf, int64(h.CustomerID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[2]...)
 		rowBuf = fastItoa(rowBuf, int64(h.ShippingAddressID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[3]...)
 		rowBuf = fastItoa(rowBuf, int64(h.BillingAddressID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[4]...)
 
 		// Format timestamp
 		timestamp := h.OrderTimestamp.Format(time.RFC3339)
 		rowBuf = append(rowBuf, timestamp...)
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[5]...)
 
 		// Add order status (escape if needed)
-		if needsQuoting(h.OrderStatus) {
-			rowBuf = append(rowBuf, '"')
-			for _, r := range h.OrderStatus {
-				if r == '"' {
-					rowBuf = append(rowBuf, '"', '"')
-				} else {
-					rowBuf = append(rowBuf, byte(r))
-				}
-			}
-			rowBuf = append(rowBuf, '"')
-		} else {
-			rowBuf = append(rowBuf, h.OrderStatus...)
+		if rowBuf, err = d.AppendText(rowBuf, h.OrderStatus); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetFilename, err)
 		}
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, end...)
 
 		// Write the row
 		if _, err := bufferedWriter.Write(rowBuf); err != nil {
@@ -492,7 +538,9 @@ func WriteStreamOrderHeadersToCSV(headerChan <-chan ecommercemodels.OrderHeader,
 
 		// Periodically flush to avoid memory buildup
 		if recordCount%20000 == 0 {
-			bufferedWriter.Flush()
+			if err := bufferedWriter.Flush(); err != nil {
+				return fmt.Errorf("failed to flush %s: %w", targetFilename, err)
+			}
 		}
 	}
 
@@ -502,19 +550,20 @@ func WriteStreamOrderHeadersToCSV(headerChan <-chan ecommercemodels.OrderHeader,
 
 // WriteStreamOrderItemsToCSV writes OrderItem structs from a channel to a CSV file using a buffered writer.
 // WriteStreamOrderItemsToCSV writes order items using direct byte formatting for better performance
-func WriteStreamOrderItemsToCSV(itemChan <-chan ecommercemodels.OrderItem, targetFilename string) error {
-	file, err := os.Create(targetFilename)
+func WriteStreamOrderItemsToCSV(itemChan <-chan ecommercemodels.OrderItem, targetFilename string, opts Options) (err error) {
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create csv file %s: %w", targetFilename, err)
 	}
-	defer file.Close()
 
 	// Use large buffered writer for better performance with high-volume data
 	bufferedWriter := bufio.NewWriterSize(file, 2*1024*1024) // 2MB buffer
-	defer bufferedWriter.Flush()
+	defer opts.CloseBufferedFile(bufferedWriter, file, targetFilename, &err)
 
 	// Write header directly
-	bufferedWriter.WriteString("order_item_id,order_id,product_id,quantity,unit_price,discount\n")
+	d := opts.CSV
+	bufferedWriter.Write(d.HeaderLine([]string{"order_item_id", "order_id", "product_id", "quantity", "unit_price", "discount"}))
+	fields, end := d.Framing(6)
 
 	// Pre-allocate buffer for row construction to reduce allocations
 	rowBuf := make([]byte, 0, 1024) // 1KB buffer per row for better performance
@@ -522,21 +571,21 @@ func WriteStreamOrderItemsToCSV(itemChan <-chan ecommercemodels.OrderItem, targe
 
 	for item := range itemChan {
 		// Reset buffer for reuse
-		rowBuf = rowBuf[:0]
+		rowBuf = append(rowBuf[:0], fields[0]...)
 
 		// Build row with direct byte operations
 		rowBuf = fastItoa(rowBuf, int64(item.OrderItemID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[1]...)
 		rowBuf = fastItoa(rowBuf, int64(item.OrderID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[2]...)
 		rowBuf = fastItoa(rowBuf, int64(item.ProductID))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[3]...)
 		rowBuf = fastItoa(rowBuf, int64(item.Quantity))
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[4]...)
 		rowBuf = strconv.AppendFloat(rowBuf, item.UnitPrice, 'f', 2, 64)
-		rowBuf = append(rowBuf, ',')
+		rowBuf = append(rowBuf, fields[5]...)
 		rowBuf = strconv.AppendFloat(rowBuf, item.Discount, 'f', 4, 64)
-		rowBuf = append(rowBuf, '\n')
+		rowBuf = append(rowBuf, end...)
 
 		// Write the row
 		if _, err := bufferedWriter.Write(rowBuf); err != nil {
@@ -546,7 +595,9 @@ func WriteStreamOrderItemsToCSV(itemChan <-chan ecommercemodels.OrderItem, targe
 
 		// Periodically flush to avoid memory buildup
 		if recordCount%20000 == 0 {
-			bufferedWriter.Flush()
+			if err := bufferedWriter.Flush(); err != nil {
+				return fmt.Errorf("failed to flush %s: %w", targetFilename, err)
+			}
 		}
 	}
 
@@ -556,7 +607,7 @@ func WriteStreamOrderItemsToCSV(itemChan <-chan ecommercemodels.OrderItem, targe
 
 // --- Other Model Writers (kept as original for focus) ---
 
-func WriteDailyStockPricesToCSV(prices []financialmodels.DailyStockPrice, targetFilename string) error {
+func WriteDailyStockPricesToCSV(ctx context.Context, prices []financialmodels.DailyStockPrice, targetFilename string, opts Options) error {
 	headers := []string{"price_id", "date", "company_id", "exchange_id", "open_price", "high_price", "low_price", "close_price", "volume"}
 	records := make([][]string, len(prices))
 	for i, p := range prices {
@@ -567,10 +618,10 @@ func WriteDailyStockPricesToCSV(prices []financialmodels.DailyStockPrice, target
 			strconv.FormatFloat(p.ClosePrice, 'f', 4, 64), strconv.Itoa(p.Volume),
 		}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
 
-func WriteAppointmentsToCSV(appointments []medicalmodels.Appointment, targetFilename string) error {
+func WriteAppointmentsToCSV(ctx context.Context, appointments []medicalmodels.Appointment, targetFilename string, opts Options) error {
 	headers := []string{"appointment_id", "patient_id", "doctor_id", "clinic_id", "appointment_date", "diagnosis"}
 	records := make([][]string, len(appointments))
 	for i, a := range appointments {
@@ -579,41 +630,41 @@ func WriteAppointmentsToCSV(appointments []medicalmodels.Appointment, targetFile
 			strconv.Itoa(a.ClinicID), a.AppointmentDate.Format(time.RFC3339), a.Diagnosis,
 		}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
 
-func WritePatientsToCSV(patients []medicalmodels.Patient, targetFilename string) error {
+func WritePatientsToCSV(ctx context.Context, patients []medicalmodels.Patient, targetFilename string, opts Options) error {
 	headers := []string{"patient_id", "patient_name", "date_of_birth", "gender"}
 	records := make([][]string, len(patients))
 	for i, p := range patients {
 		records[i] = []string{strconv.Itoa(p.PatientID), p.PatientName, p.DateOfBirth.Format(time.RFC3339), p.Gender}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
 
-func WriteDoctorsToCSV(doctors []medicalmodels.Doctor, targetFilename string) error {
+func WriteDoctorsToCSV(ctx context.Context, doctors []medicalmodels.Doctor, targetFilename string, opts Options) error {
 	headers := []string{"doctor_id", "doctor_name", "specialization"}
 	records := make([][]string, len(doctors))
 	for i, d := range doctors {
 		records[i] = []string{strconv.Itoa(d.DoctorID), d.DoctorName, d.Specialization}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
 
-func WriteClinicsToCSV(clinics []medicalmodels.Clinic, targetFilename string) error {
+func WriteClinicsToCSV(ctx context.Context, clinics []medicalmodels.Clinic, targetFilename string, opts Options) error {
 	headers := []string{"clinic_id", "clinic_name", "address"}
 	records := make([][]string, len(clinics))
 	for i, c := range clinics {
 		records[i] = []string{strconv.Itoa(c.ClinicID), c.ClinicName, c.Address}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
 
-func WriteCompaniesToCSV(companies []financialmodels.Company, targetFilename string) error {
+func WriteCompaniesToCSV(ctx context.Context, companies []financialmodels.Company, targetFilename string, opts Options) error {
 	headers := []string{"company_id", "company_name", "ticker_symbol", "sector"}
 	records := make([][]string, len(companies))
 	for i, c := range companies {
 		records[i] = []string{strconv.Itoa(c.CompanyID), c.CompanyName, c.TickerSymbol, c.Sector}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
@@ -3,25 +3,22 @@ package formats
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"time"
 )
 
-func WriteCSVChunks(header string, chunk <-chan []byte, targetFilename string) error {
+// WriteCSVChunks writes the header line naming columns, if the CSV dialect has
+// one, followed by chunks of rows already formatted in the dialect.
+func WriteCSVChunks(columns []string, chunk <-chan []byte, targetFilename string, opts Options) (err error) {
 	startTime := time.Now()
-	f, err := os.Create(targetFilename)
+	f, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("create %s: %w", targetFilename, err)
 	}
-	defer f.Close()
 
 	bw := bufio.NewWriterSize(f, 16*1024*1024) // 16MB
-	defer bw.Flush()
+	defer opts.CloseBufferedFile(bw, f, targetFilename, &err)
 
-	if _, err := bw.WriteString(header); err != nil {
-		return err
-	}
-	if err := bw.WriteByte('\n'); err != nil {
+	if _, err := bw.Write(opts.CSV.HeaderLine(columns)); err != nil {
 		return err
 	}
 
@@ -0,0 +1,166 @@
+package formats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CSVDialect is how CSV files are written: the field delimiter, when fields are
+// quoted, the token written for NULLs, whether a header line comes first and
+// the line ending. Quotes are always double quotes, doubled inside a field.
+type CSVDialect struct {
+	Delimiter string `json:"delimiter"`
+	// Quoting is minimal (only fields holding the delimiter, a quote, a line
+	// break or the NULL token), all (every field but NULLs) or none, which fails
+	// on fields that would need quoting.
+	Quoting string `json:"quoting"`
+	Null    string `json:"null"`
+	Header  bool   `json:"header"`
+	CRLF    bool   `json:"crlf"`
+}
+
+// DefaultCSVDialect is RFC 4180 CSV with a header line and Unix line endings.
+var DefaultCSVDialect = CSVDialect{Delimiter: ",", Quoting: "minimal", Header: true}
+
+// CSVQuotings are the quoting modes of a CSVDialect.
+var CSVQuotings = []string{"minimal", "all", "none"}
+
+// ParseCSVDelimiter returns the delimiter s names: a single character, or tab
+// given as "tab" or `\t`.
+func ParseCSVDelimiter(s string) string {
+	switch s {
+	case "tab", `\t`:
+		return "\t"
+	}
+	return s
+}
+
+// Check returns an error unless d can be written and read back.
+func (d CSVDialect) Check() error {
+	if len(d.Delimiter) != 1 || strings.ContainsAny(d.Delimiter, "\"\r\n") {
+		return fmt.Errorf("invalid CSV delimiter %q: it must be a single character other than a quote or line break", d.Delimiter)
+	}
+	quoting := false
+	for _, q := range CSVQuotings {
+		quoting = quoting || d.Quoting == q
+	}
+	if !quoting {
+		return fmt.Errorf("unsupported CSV quoting: %s (choose %s)", d.Quoting, strings.Join(CSVQuotings, ", "))
+	}
+	if d.Quoting == "none" && isIdentifierChar(d.Delimiter[0]) {
+		return fmt.Errorf("invalid CSV delimiter %q: without quoting it cannot be a letter, digit or underscore, which column names hold", d.Delimiter)
+	}
+	if strings.ContainsAny(d.Null, d.Delimiter+"\"\r\n") {
+		return fmt.Errorf("invalid CSV NULL token %q: it cannot hold the delimiter, a quote or a line break", d.Null)
+	}
+	return nil
+}
+
+func (d CSVDialect) lineEnd() string {
+	if d.CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+func isIdentifierChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// HeaderLine returns the header line naming columns, or nil if d has none.
+// Column names are identifiers, so Check makes sure they can be written in
+// every quoting mode.
+func (d CSVDialect) HeaderLine(columns []string) []byte {
+	if !d.Header {
+		return nil
+	}
+	var buf []byte
+	for i, c := range columns {
+		if i > 0 {
+			buf = append(buf, d.Delimiter...)
+		}
+		buf, _ = d.appendField(buf, c)
+	}
+	return append(buf, d.lineEnd()...)
+}
+
+// Framing returns what byte-level writers write before each of n fields of a
+// row and after the last one. When every field is quoted the framing holds the
+// quotes, so numbers are appended as they are and text with AppendText.
+func (d CSVDialect) Framing(n int) (prefix [][]byte, end []byte) {
+	quote := ""
+	if d.Quoting == "all" {
+		quote = `"`
+	}
+	prefix = make([][]byte, n)
+	for i := range prefix {
+		if i == 0 {
+			prefix[i] = []byte(quote)
+		} else {
+			prefix[i] = []byte(quote + d.Delimiter + quote)
+		}
+	}
+	return prefix, []byte(quote + d.lineEnd())
+}
+
+// AppendText appends a text field framed by Framing, quoting or escaping it as
+// d requires. Without quoting, a field holding the delimiter, a quote or a line
+// break cannot be read back, so it is an error.
+func (d CSVDialect) AppendText(buf []byte, s string) ([]byte, error) {
+	switch d.Quoting {
+	case "none":
+		if strings.ContainsAny(s, d.Delimiter+"\"\r\n") {
+			return buf, fmt.Errorf("CSV field %q holds the delimiter, a quote or a line break and cannot be written with --csv-quoting none", s)
+		}
+		return append(buf, s...), nil
+	case "all":
+		return appendCSVEscaped(buf, s), nil
+	}
+	if !d.needsQuoting(s) {
+		return append(buf, s...), nil
+	}
+	buf = append(buf, '"')
+	buf = appendCSVEscaped(buf, s)
+	return append(buf, '"'), nil
+}
+
+// appendField appends a text field that is not framed by Framing.
+func (d CSVDialect) appendField(buf []byte, s string) ([]byte, error) {
+	if d.Quoting != "all" {
+		return d.AppendText(buf, s)
+	}
+	buf = append(buf, '"')
+	buf = appendCSVEscaped(buf, s)
+	return append(buf, '"'), nil
+}
+
+// needsQuoting reports whether a field must be quoted to be read back: it holds
+// the delimiter, a quote or a line break, or it would read as NULL.
+func (d CSVDialect) needsQuoting(field string) bool {
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c == d.Delimiter[0] || c == '"' || c == '\n' || c == '\r' {
+			return true
+		}
+	}
+	return d.Null != "" && field == d.Null
+}
+
+// value returns a field as read back: "" if it is the NULL token.
+func (d CSVDialect) value(field string) string {
+	if field == d.Null {
+		return ""
+	}
+	return field
+}
+
+func appendCSVEscaped(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			buf = append(buf, '"', '"')
+		} else {
+			buf = append(buf, s[i])
+		}
+	}
+	return buf
+}
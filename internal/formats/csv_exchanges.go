@@ -1,16 +1,17 @@
 package formats
 
 import (
+	"context"
 	"strconv"
 
 	financialmodels "github.com/peekknuf/Gengo/internal/models/financial"
 )
 
-func WriteExchangesToCSV(exchanges []financialmodels.Exchange, targetFilename string) error {
+func WriteExchangesToCSV(ctx context.Context, exchanges []financialmodels.Exchange, targetFilename string, opts Options) error {
 	headers := []string{"exchange_id", "exchange_name", "country"}
 	records := make([][]string, len(exchanges))
 	for i, e := range exchanges {
 		records[i] = []string{strconv.Itoa(e.ExchangeID), e.ExchangeName, e.Country}
 	}
-	return writeCSVHeaderAndRecords(targetFilename, headers, records)
+	return writeCSVHeaderAndRecords(ctx, targetFilename, headers, records, opts)
 }
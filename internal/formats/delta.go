@@ -0,0 +1,275 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/google/uuid"
+	"github.com/peekknuf/Gengo/internal/common"
+)
+
+// Delta tables are written as a single commit, _delta_log/00000000000000000000.json,
+// that creates the table and adds all data files, with the row count and the
+// column statistics of every file read from its Parquet footer. The table uses
+// no table features, so protocol version 1/2 readers can read it.
+
+// deltaLogName is the name of the first commit of a Delta table's log.
+const deltaLogName = "00000000000000000000.json"
+
+type deltaField struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Nullable bool              `json:"nullable"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type deltaSchema struct {
+	Type   string       `json:"type"`
+	Fields []deltaField `json:"fields"`
+}
+
+type deltaCommitInfo struct {
+	Timestamp           int64             `json:"timestamp"`
+	Operation           string            `json:"operation"`
+	OperationParameters map[string]string `json:"operationParameters"`
+	IsBlindAppend       bool              `json:"isBlindAppend"`
+}
+
+type deltaProtocol struct {
+	MinReaderVersion int `json:"minReaderVersion"`
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+type deltaFormat struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+type deltaMetaData struct {
+	ID               string            `json:"id"`
+	Format           deltaFormat       `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      int64             `json:"createdTime"`
+}
+
+type deltaAdd struct {
+	Path             string            `json:"path"`
+	PartitionValues  map[string]string `json:"partitionValues"`
+	Size             int64             `json:"size"`
+	ModificationTime int64             `json:"modificationTime"`
+	DataChange       bool              `json:"dataChange"`
+	Stats            string            `json:"stats"`
+}
+
+// deltaStats are the file statistics of an add action, which Delta readers
+// use to skip files.
+type deltaStats struct {
+	NumRecords int64                  `json:"numRecords"`
+	MinValues  map[string]interface{} `json:"minValues"`
+	MaxValues  map[string]interface{} `json:"maxValues"`
+	NullCount  map[string]int64       `json:"nullCount"`
+}
+
+// deltaAction is a line of a commit: exactly one of its fields is set.
+type deltaAction struct {
+	CommitInfo *deltaCommitInfo `json:"commitInfo,omitempty"`
+	Protocol   *deltaProtocol   `json:"protocol,omitempty"`
+	MetaData   *deltaMetaData   `json:"metaData,omitempty"`
+	Add        *deltaAdd        `json:"add,omitempty"`
+}
+
+// deltaType returns the Delta type of an Arrow column type.
+func deltaType(t arrow.DataType) (string, error) {
+	switch t := t.(type) {
+	case *arrow.BooleanType:
+		return "boolean", nil
+	case *arrow.Int32Type:
+		return "integer", nil
+	case *arrow.Int64Type:
+		return "long", nil
+	case *arrow.Float32Type:
+		return "float", nil
+	case *arrow.Float64Type:
+		return "double", nil
+	case *arrow.StringType, *arrow.LargeStringType:
+		return "string", nil
+	case *arrow.Date32Type:
+		return "date", nil
+	case *arrow.TimestampType:
+		// timestamp_ntz would need a table feature; gengo writes UTC timestamps.
+		if t.TimeZone == "" {
+			return "", fmt.Errorf("unsupported timestamp without time zone")
+		}
+		return "timestamp", nil
+	}
+	return "", fmt.Errorf("unsupported column type %s", t)
+}
+
+// deltaStatValue returns the JSON value of a bound in Parquet's plain encoding,
+// or false if the bound cannot be used for skipping. Timestamps are written
+// with millisecond precision, so an upper bound is rounded up.
+func deltaStatValue(t arrow.DataType, b []byte, upper bool) (interface{}, bool) {
+	switch t := t.(type) {
+	case *arrow.BooleanType:
+		return len(b) > 0 && b[0] != 0, len(b) == 1
+	case *arrow.Int32Type:
+		if len(b) != 4 {
+			return nil, false
+		}
+		return int32(binary.LittleEndian.Uint32(b)), true
+	case *arrow.Int64Type:
+		if len(b) != 8 {
+			return nil, false
+		}
+		return int64(binary.LittleEndian.Uint64(b)), true
+	case *arrow.Float32Type:
+		if len(b) != 4 {
+			return nil, false
+		}
+		v := math.Float32frombits(binary.LittleEndian.Uint32(b))
+		return v, !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0)
+	case *arrow.Float64Type:
+		if len(b) != 8 {
+			return nil, false
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(b))
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case *arrow.StringType, *arrow.LargeStringType:
+		return string(b), true
+	case *arrow.Date32Type:
+		if len(b) != 4 {
+			return nil, false
+		}
+		days := int64(int32(binary.LittleEndian.Uint32(b)))
+		return time.Unix(days*86400, 0).UTC().Format("2006-01-02"), true
+	case *arrow.TimestampType:
+		if len(b) != 8 || t.Unit != arrow.Microsecond {
+			return nil, false
+		}
+		v := time.UnixMicro(int64(binary.LittleEndian.Uint64(b))).UTC()
+		if ms := v.Truncate(time.Millisecond); upper && ms.Before(v) {
+			v = ms.Add(time.Millisecond)
+		}
+		return v.Format("2006-01-02T15:04:05.000Z07:00"), true
+	}
+	return nil, false
+}
+
+// commitDeltaTable writes the log of the Delta table in dir, whose data files
+// are files, replacing any left by an earlier attempt. The table ID is drawn
+// from seed and the table name.
+func commitDeltaTable(dir string, files []string, seed int64) error {
+	if len(files) == 0 {
+		return nil
+	}
+	logDir := filepath.Join(dir, "_delta_log")
+	if err := os.RemoveAll(logDir); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", logDir, err)
+	}
+
+	now := tableCommitTime.UnixMilli()
+	id, err := uuid.NewRandomFromReader(common.NewByteSource(seed, filepath.Base(dir), 0))
+	if err != nil {
+		return err
+	}
+	var adds []deltaAction
+	var schema *arrow.Schema
+	for _, f := range files {
+		stats, fileSchema, err := readParquetStats(f)
+		if err != nil {
+			return err
+		}
+		if schema == nil {
+			schema = fileSchema
+		}
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return err
+		}
+
+		fileStats := deltaStats{
+			NumRecords: stats.rows,
+			MinValues:  make(map[string]interface{}),
+			MaxValues:  make(map[string]interface{}),
+			NullCount:  make(map[string]int64),
+		}
+		for i, field := range fileSchema.Fields() {
+			c := stats.columns[i]
+			if c.nulls >= 0 {
+				fileStats.NullCount[field.Name] = c.nulls
+			}
+			if c.min == nil || c.max == nil {
+				continue
+			}
+			min, okMin := deltaStatValue(field.Type, c.min, false)
+			max, okMax := deltaStatValue(field.Type, c.max, true)
+			if okMin && okMax {
+				fileStats.MinValues[field.Name] = min
+				fileStats.MaxValues[field.Name] = max
+			}
+		}
+		statsJSON, err := json.Marshal(fileStats)
+		if err != nil {
+			return err
+		}
+		adds = append(adds, deltaAction{Add: &deltaAdd{
+			Path:             (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath(),
+			PartitionValues:  map[string]string{},
+			Size:             stats.bytes,
+			ModificationTime: now,
+			DataChange:       true,
+			Stats:            string(statsJSON),
+		}})
+	}
+
+	table := deltaSchema{Type: "struct"}
+	for _, field := range schema.Fields() {
+		typ, err := deltaType(field.Type)
+		if err != nil {
+			return fmt.Errorf("column %s of %s: %w", field.Name, filepath.Base(dir), err)
+		}
+		table.Fields = append(table.Fields, deltaField{Name: field.Name, Type: typ, Nullable: field.Nullable, Metadata: map[string]string{}})
+	}
+	schemaJSON, err := json.Marshal(table)
+	if err != nil {
+		return err
+	}
+
+	actions := append([]deltaAction{
+		{CommitInfo: &deltaCommitInfo{
+			Timestamp:           now,
+			Operation:           "WRITE",
+			OperationParameters: map[string]string{"mode": "ErrorIfExists", "partitionBy": "[]"},
+			IsBlindAppend:       true,
+		}},
+		{Protocol: &deltaProtocol{MinReaderVersion: 1, MinWriterVersion: 2}},
+		{MetaData: &deltaMetaData{
+			ID:               id.String(),
+			Format:           deltaFormat{Provider: "parquet", Options: map[string]string{}},
+			SchemaString:     string(schemaJSON),
+			PartitionColumns: []string{},
+			Configuration:    map[string]string{},
+			CreatedTime:      now,
+		}},
+	}, adds...)
+	var commit bytes.Buffer
+	for _, a := range actions {
+		line, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		commit.Write(line)
+		commit.WriteByte('\n')
+	}
+	return writeFileAtomically(filepath.Join(logDir, deltaLogName), commit.Bytes())
+}
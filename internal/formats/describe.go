@@ -0,0 +1,320 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// FileInfo describes a published output file.
+type FileInfo struct {
+	Rows   int64
+	Bytes  int64
+	SHA256 string
+}
+
+// Column is a column of an output file. Type is only known for self-describing
+// formats (Parquet, Arrow IPC and Avro); text formats carry column names only.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// OutputFile is the temporary file of an output file being written. It hashes
+// and counts the bytes written to it, and the rows its writer reports, so that
+// publishing it need not read it back.
+type OutputFile struct {
+	*os.File
+	filename string
+	hash     hash.Hash
+	bytes    int64
+	rows     int64
+	// marks are the offsets and hash states at the start of the last writes,
+	// from which the hash is brought up to date when the end of the file is
+	// rewritten in place, as the Parquet footer is. lost is set if it cannot
+	// be, and the file is read back when published.
+	marks [outputFileMarks]hashMark
+	next  int
+	lost  bool
+}
+
+// outputFileMarks is the number of writes an OutputFile can rewrite in place.
+// The Parquet footer is the last three: the metadata, its length and the
+// magic number.
+const outputFileMarks = 4
+
+type hashMark struct {
+	offset int64
+	state  []byte
+}
+
+// outputFiles are the OutputFiles being written, by the name of the output file.
+var outputFiles = struct {
+	sync.Mutex
+	files map[string]*OutputFile
+}{files: make(map[string]*OutputFile)}
+
+func newOutputFile(f *os.File, filename string) *OutputFile {
+	o := &OutputFile{File: f, filename: filename, hash: sha256.New()}
+	outputFiles.Lock()
+	outputFiles.files[filename] = o
+	outputFiles.Unlock()
+	return o
+}
+
+// takeOutputFile returns the OutputFile filename was written through, if any,
+// and forgets it.
+func takeOutputFile(filename string) *OutputFile {
+	outputFiles.Lock()
+	defer outputFiles.Unlock()
+	o := outputFiles.files[filename]
+	delete(outputFiles.files, filename)
+	return o
+}
+
+func (o *OutputFile) Write(p []byte) (int, error) {
+	m := &o.marks[o.next%outputFileMarks]
+	m.offset = o.bytes
+	m.state, _ = o.hash.(encoding.BinaryAppender).AppendBinary(m.state[:0])
+	o.next++
+	n, err := o.File.Write(p)
+	o.hash.Write(p[:n])
+	o.bytes += int64(n)
+	return n, err
+}
+
+// WriteAt rewrites part of what was written, which must lie within the last
+// outputFileMarks writes for the hash to be kept up to date.
+func (o *OutputFile) WriteAt(p []byte, off int64) (int, error) {
+	n, err := o.File.WriteAt(p, off)
+	if err != nil || o.lost {
+		return n, err
+	}
+	var from *hashMark
+	for i := range o.marks {
+		m := &o.marks[i]
+		if m.state != nil && m.offset <= off && (from == nil || m.offset > from.offset) {
+			from = m
+		}
+	}
+	h := sha256.New()
+	if from == nil || h.(encoding.BinaryUnmarshaler).UnmarshalBinary(from.state) != nil {
+		o.lost = true
+		return n, nil
+	}
+	if _, err := io.Copy(h, io.NewSectionReader(o.File, from.offset, o.bytes-from.offset)); err != nil {
+		o.lost = true
+		return n, nil
+	}
+	o.hash = h
+	// The marks hold the states of the hash before the rewrite.
+	o.marks = [outputFileMarks]hashMark{}
+	return n, nil
+}
+
+// AddRows counts rows written to the file.
+func (o *OutputFile) AddRows(n int64) {
+	o.rows += n
+}
+
+// info returns the FileInfo of the file as it was written. Text files count
+// their lines, less the CSV header line if dialect d has one, like DescribeFile.
+func (o *OutputFile) info(d CSVDialect) (FileInfo, bool) {
+	if o.lost {
+		return FileInfo{}, false
+	}
+	rows := o.rows
+	if DataExtension(o.filename) == ".csv" && d.Header && rows > 0 {
+		rows--
+	}
+	return FileInfo{Rows: rows, Bytes: o.bytes, SHA256: hex.EncodeToString(o.hash.Sum(nil))}, true
+}
+
+// DescribeFile reads filename back and returns its size, SHA-256 checksum and
+// number of rows, for files not written through an OutputFile. Text formats hold one row per line (CSV after its header line,
+// if dialect d has one), counted after decompressing compressed files; size
+// and checksum are those of the file on disk. Parquet row counts come from the
+// file footer, Arrow IPC row counts from its record batches and Avro row counts
+// from the counts of its blocks.
+func DescribeFile(filename string, d CSVDialect) (FileInfo, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	h := &countingHash{Hash: sha256.New()}
+	compressed := io.TeeReader(f, h)
+	r, err := decompress(filename, compressed)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer r.Close()
+	lc := &lineCounter{}
+	if _, err := io.Copy(lc, r); err != nil {
+		return FileInfo{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	// Hash whatever the decompressor left unread.
+	if _, err := io.Copy(io.Discard, compressed); err != nil {
+		return FileInfo{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	info := FileInfo{Bytes: h.n, SHA256: hex.EncodeToString(h.Sum(nil))}
+
+	switch DataExtension(filename) {
+	case ".parquet":
+		rdr, err := file.OpenParquetFile(filename, false)
+		if err != nil {
+			return FileInfo{}, fmt.Errorf("failed to read parquet footer of %s: %w", filename, err)
+		}
+		info.Rows = rdr.NumRows()
+		rdr.Close()
+	case ".arrow", ".arrows":
+		if info.Rows, err = arrowRows(filename); err != nil {
+			return FileInfo{}, err
+		}
+	case ".avro":
+		if info.Rows, err = avroRows(filename); err != nil {
+			return FileInfo{}, err
+		}
+	case ".csv":
+		info.Rows = lc.lines
+		if d.Header && lc.lines > 0 {
+			info.Rows--
+		}
+	default:
+		info.Rows = lc.lines
+	}
+	return info, nil
+}
+
+// countingHash hashes everything written to it and counts the bytes.
+type countingHash struct {
+	hash.Hash
+	n int64
+}
+
+func (h *countingHash) Write(p []byte) (int, error) {
+	h.n += int64(len(p))
+	return h.Hash.Write(p)
+}
+
+// lineCounter counts the newlines written to it.
+type lineCounter struct {
+	lines int64
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.lines += int64(bytes.Count(p, []byte{'\n'}))
+	return len(p), nil
+}
+
+// NamesColumns reports whether filename names its columns. dsdgen files do
+// not, and neither does CSV in a dialect d without a header line.
+func NamesColumns(filename string, d CSVDialect) bool {
+	switch DataExtension(filename) {
+	case ".dat":
+		return false
+	case ".csv":
+		return d.Header
+	}
+	return true
+}
+
+// FileColumns returns the columns of filename: the Parquet, Arrow or Avro schema, the CSV header
+// or the keys of the first JSON Lines object. CSV files are read in dialect d.
+// Files that do not name their columns have none.
+func FileColumns(filename string, d CSVDialect) ([]Column, error) {
+	if !NamesColumns(filename, d) {
+		return nil, nil
+	}
+	switch DataExtension(filename) {
+	case ".parquet":
+		return parquetColumns(filename)
+	case ".arrow", ".arrows":
+		return arrowColumns(filename)
+	case ".avro":
+		return avroColumns(filename)
+	}
+
+	f, err := OpenTextFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, nil
+	}
+
+	var names []string
+	if DataExtension(filename) == ".jsonl" {
+		names, err = jsonObjectKeys(line)
+	} else {
+		r := csv.NewReader(strings.NewReader(line))
+		r.Comma = rune(d.Delimiter[0])
+		names, err = r.Read()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the first line of %s: %w", filename, err)
+	}
+	columns := make([]Column, len(names))
+	for i, name := range names {
+		columns[i] = Column{Name: name}
+	}
+	return columns, nil
+}
+
+func parquetColumns(filename string) ([]Column, error) {
+	rdr, err := file.OpenParquetFile(filename, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet footer of %s: %w", filename, err)
+	}
+	defer rdr.Close()
+	schema, err := pqarrow.FromParquet(rdr.MetaData().Schema, nil, rdr.MetaData().KeyValueMetadata())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet schema of %s: %w", filename, err)
+	}
+	columns := make([]Column, schema.NumFields())
+	for i, field := range schema.Fields() {
+		columns[i] = Column{Name: field.Name, Type: field.Type.String()}
+	}
+	return columns, nil
+}
+
+// jsonObjectKeys returns the keys of the JSON object in line, in order.
+func jsonObjectKeys(line string) ([]string, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
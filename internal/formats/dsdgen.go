@@ -0,0 +1,234 @@
+package formats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// dsdgenTables maps the ecommerce-ds tables to the names TPC-DS dsdgen writes
+// them under.
+var dsdgenTables = map[string]string{
+	"dim_date":                   "date_dim",
+	"dim_time":                   "time_dim",
+	"dim_income_bands":           "income_band",
+	"dim_customer_demographics":  "customer_demographics",
+	"dim_household_demographics": "household_demographics",
+	"dim_customer_addresses":     "customer_address",
+	"dim_customers":              "customer",
+	"dim_items":                  "item",
+	"dim_promotions":             "promotion",
+	"dim_stores":                 "store",
+	"dim_call_centers":           "call_center",
+	"dim_catalog_pages":          "catalog_page",
+	"dim_web_sites":              "web_site",
+	"dim_web_pages":              "web_page",
+	"dim_warehouses":             "warehouse",
+	"dim_reasons":                "reason",
+	"dim_ship_modes":             "ship_mode",
+	"fact_store_sales":           "store_sales",
+	"fact_store_returns":         "store_returns",
+	"fact_catalog_sales":         "catalog_sales",
+	"fact_catalog_returns":       "catalog_returns",
+	"fact_web_sales":             "web_sales",
+	"fact_web_returns":           "web_returns",
+	"fact_inventory":             "inventory",
+}
+
+// dsdgenColumns lays out the tables whose model columns differ from their TPC-DS
+// table: dsdgen files of these tables hold exactly these columns, in this order.
+// Model columns TPC-DS does not have are dropped, and the TPC-DS columns the
+// model does not generate, those of dsdgenNullColumns, are left NULL. The other
+// tables are written as the model lays them out, which is the TPC-DS layout.
+var dsdgenColumns = map[string][]string{
+	"dim_time": {
+		"t_time_sk", "t_time_id", "t_time", "t_hour", "t_minute", "t_second", "t_am_pm", "t_shift", "t_sub_shift", "t_meal_time",
+	},
+	"dim_customer_demographics": {
+		"cd_demo_sk", "cd_gender", "cd_marital_status", "cd_education_status", "cd_purchase_estimate", "cd_credit_rating",
+		"cd_dep_count", "cd_dep_employed_count", "cd_dep_college_count",
+	},
+	"dim_customer_addresses": {
+		"ca_address_sk", "ca_address_id", "ca_street_number", "ca_street_name", "ca_street_type", "ca_suite_number",
+		"ca_city", "ca_county", "ca_state", "ca_zip", "ca_country", "ca_gmt_offset", "ca_location_type",
+	},
+	"dim_promotions": {
+		"p_promo_sk", "p_promo_id", "p_start_date_sk", "p_end_date_sk", "p_item_sk", "p_cost", "p_response_target",
+		"p_promo_name", "p_channel_dmail", "p_channel_email", "p_channel_catalog", "p_channel_tv", "p_channel_radio",
+		"p_channel_press", "p_channel_event", "p_channel_demo", "p_channel_details", "p_purpose", "p_discount_active",
+	},
+	"dim_stores": {
+		"s_store_sk", "s_store_id", "s_rec_start_date", "s_rec_end_date", "s_closed_date_sk", "s_store_name",
+		"s_number_employees", "s_floor_space", "s_hours", "s_manager", "s_market_id", "s_geography_class",
+		"s_market_desc", "s_market_manager", "s_division_id", "s_division_name", "s_company_id", "s_company_name",
+		"s_street_number", "s_street_name", "s_street_type", "s_suite_number", "s_city", "s_county", "s_state",
+		"s_zip", "s_country", "s_gmt_offset", "s_tax_precentage",
+	},
+	"dim_warehouses": {
+		"w_warehouse_sk", "w_warehouse_id", "w_warehouse_name", "w_warehouse_sq_ft", "w_street_number",
+		"w_street_name", "w_street_type", "w_suite_number", "w_city", "w_county", "w_state", "w_zip", "w_country",
+		"w_gmt_offset",
+	},
+}
+
+// dsdgenNullColumns are the TPC-DS columns of dsdgenColumns the model does not
+// generate, with the types they are declared with.
+var dsdgenNullColumns = map[string]arrow.DataType{
+	"s_rec_start_date":   arrow.BinaryTypes.String,
+	"s_rec_end_date":     arrow.BinaryTypes.String,
+	"s_closed_date_sk":   arrow.PrimitiveTypes.Int64,
+	"s_number_employees": arrow.PrimitiveTypes.Int32,
+	"p_response_target":  arrow.PrimitiveTypes.Int32,
+	"p_channel_details":  arrow.BinaryTypes.String,
+}
+
+// DsdgenSchema returns the columns of the dsdgen files of table, whose model
+// columns are schema.
+func DsdgenSchema(table string, schema *arrow.Schema) (*arrow.Schema, error) {
+	columns, ok := dsdgenColumns[table]
+	if !ok {
+		return schema, nil
+	}
+	fields := make([]arrow.Field, len(columns))
+	for i, name := range columns {
+		if typ, ok := dsdgenNullColumns[name]; ok {
+			fields[i] = arrow.Field{Name: name, Type: typ, Nullable: true}
+			continue
+		}
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("%s has no column %s", table, name)
+		}
+		fields[i] = schema.Field(indices[0])
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// dsdgenDialect is the layout of dsdgen files, less the delimiter dsdgen writes
+// after the last field.
+var dsdgenDialect = CSVDialect{Delimiter: "|", Quoting: "none"}
+
+// dsdgenSources maps the dsdgen table names back to the tables.
+var dsdgenSources = func() map[string]string {
+	sources := make(map[string]string, len(dsdgenTables))
+	for table, name := range dsdgenTables {
+		sources[name] = table
+	}
+	return sources
+}()
+
+// TableFileName returns the name the files of table are written under in
+// format, before any shard number and extension: the dsdgen table name for
+// dsdgen, e.g. store_sales for fact_store_sales, and the table name otherwise.
+func TableFileName(table, format string) string {
+	if name, ok := dsdgenTables[table]; ok && format == "dsdgen" {
+		return name
+	}
+	return table
+}
+
+// writeSliceToDat writes a slice of the structs of table the way dsdgen does:
+// in the columns of the TPC-DS table, no header, a '|' after every field,
+// including the last, and no quoting, so NULLs are empty fields. Dates are
+// written without a time of day and decimals with two digits after the point,
+// the scale of every TPC-DS decimal column.
+func writeSliceToDat(ctx context.Context, data interface{}, table, targetFilename string, opts Options) (err error) {
+	slice := reflect.ValueOf(data)
+	if slice.Kind() != reflect.Slice {
+		return fmt.Errorf("data is not a slice")
+	}
+	if slice.Len() == 0 {
+		return nil // Nothing to write
+	}
+	fields, err := datFields(table, reflect.TypeOf(slice.Index(0).Interface()))
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	file, err := opts.CreateTextFile(targetFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create dat file %s: %w", targetFilename, err)
+	}
+	bw := bufio.NewWriterSize(file, 16*1024*1024)
+	defer opts.CloseBufferedFile(bw, file, targetFilename, &err)
+
+	rowBuf := make([]byte, 0, 512)
+	for i := 0; i < slice.Len(); i++ {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rowBuf, err = appendDatRow(rowBuf[:0], reflect.ValueOf(slice.Index(i).Interface()), fields)
+		if err != nil {
+			return fmt.Errorf("row %d of %s: %w", i+1, targetFilename, err)
+		}
+		bw.Write(rowBuf)
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("Successfully wrote %d records to %s in %s\n", slice.Len(), targetFilename, duration.Round(time.Millisecond))
+	return nil
+}
+
+// datFields returns the index of the field of the struct type row written in
+// each column of the dsdgen files of table, or -1 for a column left NULL.
+func datFields(table string, row reflect.Type) ([]int, error) {
+	headers := getCSVHeaders(reflect.Zero(row).Interface())
+	columns, ok := dsdgenColumns[table]
+	if !ok {
+		columns = headers
+	}
+	fields := make([]int, len(columns))
+	for i, name := range columns {
+		fields[i] = slices.Index(headers, name)
+		if _, null := dsdgenNullColumns[name]; fields[i] < 0 && !null {
+			return nil, fmt.Errorf("%s has no column %s", table, name)
+		}
+	}
+	return fields, nil
+}
+
+// appendDatRow appends the fields of the struct row at indices fields as a
+// dsdgen line; an index of -1 is a NULL.
+func appendDatRow(buf []byte, row reflect.Value, fields []int) ([]byte, error) {
+	for _, i := range fields {
+		if i < 0 {
+			buf = append(buf, '|')
+			continue
+		}
+		field := row.Field(i)
+		switch field.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			buf = strconv.AppendInt(buf, field.Int(), 10)
+		case reflect.Float64:
+			buf = strconv.AppendFloat(buf, field.Float(), 'f', 2, 64)
+		case reflect.String:
+			s := field.String()
+			if strings.ContainsAny(s, "|\r\n") {
+				return nil, fmt.Errorf("value %q of %s cannot be written without quoting", s, row.Type().Field(i).Name)
+			}
+			buf = append(buf, s...)
+		case reflect.Bool:
+			buf = strconv.AppendBool(buf, field.Bool())
+		case reflect.Struct:
+			if t, ok := field.Interface().(time.Time); ok {
+				if t.Equal(t.Truncate(24 * time.Hour)) {
+					buf = t.AppendFormat(buf, time.DateOnly)
+				} else {
+					buf = t.AppendFormat(buf, time.DateTime)
+				}
+			}
+		}
+		buf = append(buf, '|')
+	}
+	return append(buf, '\n'), nil
+}
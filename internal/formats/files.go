@@ -0,0 +1,218 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checkpoint is told about every file a run publishes and knows which files an
+// earlier, interrupted attempt of the same run already completed.
+type Checkpoint interface {
+	Completed(filename string) bool
+	// CompletedMatching reports whether the earlier attempt published files
+	// matching pattern, a path.Match pattern of names relative to the output
+	// directory, and they are all still there.
+	CompletedMatching(pattern string) bool
+	// Published records files published together, by name.
+	Published(files map[string]FileInfo) error
+}
+
+// Completed reports whether filename was already published by the run being
+// resumed, in which case writers skip it.
+func (o Options) Completed(filename string) bool {
+	return o.Checkpoint != nil && o.Checkpoint.Completed(filename)
+}
+
+// CompletedMatching reports whether the run being resumed already published the
+// files matching pattern, such as the partition files of a shard, which are
+// published together.
+func (o Options) CompletedMatching(pattern string) bool {
+	return o.Checkpoint != nil && o.Checkpoint.CompletedMatching(pattern)
+}
+
+// SuccessMarker is the empty file written to the output directory once every
+// table of a run has been published.
+const SuccessMarker = "_SUCCESS"
+
+// TempName returns the name a file is written under until it is complete. The
+// leading dot hides it from loaders and globs that watch the output directory.
+func TempName(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+}
+
+// CreateOutputFile creates the temporary file for filename, and the directory
+// it goes in if need be. Writers must hand it to PublishFile (or
+// CloseBufferedFile) once they are done with it, or to DiscardFile.
+func CreateOutputFile(filename string) (*OutputFile, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(TempName(filename))
+	if err != nil {
+		return nil, err
+	}
+	return newOutputFile(f, filename), nil
+}
+
+// DiscardFile removes the temporary file of filename.
+func DiscardFile(filename string) {
+	takeOutputFile(filename)
+	os.Remove(TempName(filename))
+}
+
+// PublishFile renames the closed temporary file of filename into place, or
+// removes it if *err is set, and records it with its FileInfo in the checkpoint.
+// Writers defer it with their named error result.
+func (o Options) PublishFile(filename string, err *error) {
+	o.PublishFiles([]string{filename}, err)
+}
+
+// PublishFiles publishes the closed temporary files of filenames like
+// PublishFile and records them in the checkpoint together, so a resumed run
+// finds either all or none of them. Files are described as they were written,
+// and only read back if that is not known.
+func (o Options) PublishFiles(filenames []string, err *error) {
+	if *err != nil {
+		for _, filename := range filenames {
+			DiscardFile(filename)
+		}
+		return
+	}
+	written := make([]*OutputFile, len(filenames))
+	for i, filename := range filenames {
+		written[i] = takeOutputFile(filename)
+	}
+	for i, filename := range filenames {
+		if renameErr := os.Rename(TempName(filename), filename); renameErr != nil {
+			*err = fmt.Errorf("failed to publish %s: %w", filename, renameErr)
+			for _, filename := range filenames[i:] {
+				os.Remove(TempName(filename))
+			}
+			return
+		}
+	}
+	if o.Checkpoint != nil {
+		infos := make(map[string]FileInfo, len(filenames))
+		for i, filename := range filenames {
+			var info FileInfo
+			known := false
+			if written[i] != nil {
+				info, known = written[i].info(o.CSV)
+			}
+			if !known {
+				var descErr error
+				if info, descErr = DescribeFile(filename, o.CSV); descErr != nil {
+					*err = descErr
+					return
+				}
+			}
+			infos[filename] = info
+		}
+		if cpErr := o.Checkpoint.Published(infos); cpErr != nil {
+			*err = fmt.Errorf("failed to record %s in the checkpoint: %w", strings.Join(filenames, ", "), cpErr)
+		}
+	}
+}
+
+// CloseBufferedFile flushes bw, closes f and publishes it as filename. Writers
+// defer it with their named error result so a failed flush (e.g. a full disk)
+// is reported and the partial file removed instead of leaving a silently
+// truncated file; the first error wins.
+func (o Options) CloseBufferedFile(bw *bufio.Writer, f io.Closer, filename string, err *error) {
+	FinishBufferedFile(bw, f, filename, err)
+	o.PublishFile(filename, err)
+}
+
+// FinishBufferedFile flushes bw and closes f like CloseBufferedFile, but leaves
+// the temporary file of filename to be published later with PublishFiles. It
+// is removed if *err is set.
+func FinishBufferedFile(bw *bufio.Writer, f io.Closer, filename string, err *error) {
+	flushErr := bw.Flush()
+	closeErr := f.Close()
+	if *err == nil {
+		if flushErr != nil {
+			*err = fmt.Errorf("failed to flush %s: %w", filename, flushErr)
+		} else if closeErr != nil {
+			*err = fmt.Errorf("failed to close %s: %w", filename, closeErr)
+		}
+	}
+	if *err != nil {
+		DiscardFile(filename)
+	}
+}
+
+// WriteSuccessMarker marks dir as a complete run.
+func WriteSuccessMarker(dir string) error {
+	if err := os.WriteFile(filepath.Join(dir, SuccessMarker), nil, 0644); err != nil {
+		return fmt.Errorf("failed to write %s marker: %w", SuccessMarker, err)
+	}
+	return nil
+}
+
+// TableOfFile returns the table an output file belongs to: its name without
+// extension and shard number, e.g. fact_store_sales for fact_store_sales_3.csv,
+// or the directory of a partitioned table, relative to the output directory,
+// e.g. fact_store_sales for fact_store_sales/year=2001/part-00003.csv. dsdgen
+// table names are mapped back after dropping both numbers of a parallel file,
+// e.g. store_sales_3_8.dat to fact_store_sales.
+func TableOfFile(name string) string {
+	table := strings.SplitN(filepath.Base(name), ".", 2)[0]
+	numbers := 1
+	if DataExtension(name) == ".dat" {
+		numbers = 2
+	}
+	for ; numbers > 0; numbers-- {
+		if i := strings.LastIndexByte(table, '_'); i >= 0 && isDigits(table[i+1:]) {
+			table = table[:i]
+		}
+	}
+	if dir, _, partitioned := strings.Cut(filepath.ToSlash(name), "/"); partitioned {
+		table = dir
+	}
+	if source, ok := dsdgenSources[table]; ok && DataExtension(name) == ".dat" {
+		return source
+	}
+	return table
+}
+
+// ShardOfFile returns the shard of a fact table an output file was written by,
+// named alike for every table the shard writes: 3 for fact_store_sales_3.csv,
+// 4_8 for the dsdgen file store_sales_4_8.dat and part-00003 for the
+// partition file fact_store_sales/year=2001/part-00003.csv. The single file of
+// a table written as one has the shard "".
+func ShardOfFile(name string) string {
+	base := strings.SplitN(filepath.Base(name), ".", 2)[0]
+	if strings.HasPrefix(base, "part-") {
+		return base
+	}
+	shard := ""
+	numbers := 1
+	if DataExtension(name) == ".dat" {
+		numbers = 2
+	}
+	for ; numbers > 0; numbers-- {
+		i := strings.LastIndexByte(base, '_')
+		if i < 0 || !isDigits(base[i+1:]) {
+			break
+		}
+		shard = strings.TrimSuffix(base[i+1:]+"_"+shard, "_")
+		base = base[:i]
+	}
+	return shard
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,8 @@
 package formats
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	ecommercemodels "github.com/peekknuf/Gengo/internal/models/ecommerce"
@@ -11,230 +11,251 @@ import (
 )
 
 // WriteCustomers writes customer data to the specified format
-func WriteCustomers(customers interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_customers"+ext)
+func WriteCustomers(ctx context.Context, customers interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_customers") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteCustomersToCSV(customers.([]ecommercemodels.Customer), filename)
-	case "parquet":
-		return WriteCustomersToParquet(customers.([]ecommercemodels.Customer), filename)
+		return WriteCustomersToCSV(ctx, customers.([]ecommercemodels.Customer), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteCustomerRecords(ctx, customers.([]ecommercemodels.Customer), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, customers, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteCustomerAddresses writes customer address data to the specified format
-func WriteCustomerAddresses(addresses interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_customer_addresses"+ext)
+func WriteCustomerAddresses(ctx context.Context, addresses interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_customer_addresses") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteCustomerAddressesToCSV(addresses.([]ecommercemodels.CustomerAddress), filename)
-	case "parquet":
-		return WriteCustomerAddressesToParquet(addresses.([]ecommercemodels.CustomerAddress), filename)
+		return WriteCustomerAddressesToCSV(ctx, addresses.([]ecommercemodels.CustomerAddress), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteCustomerAddressRecords(ctx, addresses.([]ecommercemodels.CustomerAddress), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, addresses, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteSuppliers writes supplier data to the specified format
-func WriteSuppliers(suppliers interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_suppliers"+ext)
+func WriteSuppliers(ctx context.Context, suppliers interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_suppliers") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteSuppliersToCSV(suppliers.([]ecommercemodels.Supplier), filename)
-	case "parquet":
-		return WriteSuppliersToParquet(suppliers.([]ecommercemodels.Supplier), filename)
+		return WriteSuppliersToCSV(ctx, suppliers.([]ecommercemodels.Supplier), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteSupplierRecords(ctx, suppliers.([]ecommercemodels.Supplier), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, suppliers, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteProductCategories writes product category data to the specified format
-func WriteProductCategories(categories interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_product_categories"+ext)
+func WriteProductCategories(ctx context.Context, categories interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_product_categories") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteProductCategoriesToCSV(categories.([]ecommercemodels.ProductCategory), filename)
-	case "parquet":
-		return WriteProductCategoriesToParquet(categories.([]ecommercemodels.ProductCategory), filename)
+		return WriteProductCategoriesToCSV(ctx, categories.([]ecommercemodels.ProductCategory), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteProductCategoryRecords(ctx, categories.([]ecommercemodels.ProductCategory), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, categories, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteProducts writes product data to the specified format
-func WriteProducts(products interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_products"+ext)
+func WriteProducts(ctx context.Context, products interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_products") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteProductsToCSV(products.([]ecommercemodels.Product), filename)
-	case "parquet":
-		return WriteProductsToParquet(products.([]ecommercemodels.Product), filename)
+		return WriteProductsToCSV(ctx, products.([]ecommercemodels.Product), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteProductRecords(ctx, products.([]ecommercemodels.Product), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, products, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteOrderHeaders writes order header data to the specified format
-func WriteOrderHeaders(headers interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "fact_orders_header"+ext)
+func WriteOrderHeaders(ctx context.Context, headers interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "fact_orders_header") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteStreamOrderHeadersToCSV(headers.(<-chan ecommercemodels.OrderHeader), filename)
-	case "parquet":
+		return WriteStreamOrderHeadersToCSV(headers.(<-chan ecommercemodels.OrderHeader), filename, opts)
+	case "parquet", "arrow", "avro":
 		// For parquet, we expect a slice instead of a channel
-		return WriteOrderHeadersToParquet(headers.([]ecommercemodels.OrderHeader), filename)
+		return WriteOrderHeaderRecords(headers.([]ecommercemodels.OrderHeader), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, headers, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteOrderItems writes order item data to the specified format
-func WriteOrderItems(items interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "fact_order_items"+ext)
+func WriteOrderItems(ctx context.Context, items interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "fact_order_items") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteStreamOrderItemsToCSV(items.(<-chan ecommercemodels.OrderItem), filename)
-	case "parquet":
+		return WriteStreamOrderItemsToCSV(items.(<-chan ecommercemodels.OrderItem), filename, opts)
+	case "parquet", "arrow", "avro":
 		// For parquet, we expect a slice instead of a channel
-		return WriteOrderItemsToParquet(items.([]ecommercemodels.OrderItem), filename)
+		return WriteOrderItemRecords(items.([]ecommercemodels.OrderItem), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, items, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // Financial model writers
 
 // WriteCompanies writes company data to the specified format
-func WriteCompanies(companies interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_companies"+ext)
+func WriteCompanies(ctx context.Context, companies interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_companies") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteCompaniesToCSV(companies.([]financialmodels.Company), filename)
-	case "parquet":
-		return WriteCompaniesToParquet(companies.([]financialmodels.Company), filename)
+		return WriteCompaniesToCSV(ctx, companies.([]financialmodels.Company), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteCompanyRecords(ctx, companies.([]financialmodels.Company), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, companies, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteExchanges writes exchange data to the specified format
-func WriteExchanges(exchanges interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_exchanges"+ext)
+func WriteExchanges(ctx context.Context, exchanges interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_exchanges") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteExchangesToCSV(exchanges.([]financialmodels.Exchange), filename)
-	case "parquet":
-		return WriteExchangesToParquet(exchanges.([]financialmodels.Exchange), filename)
+		return WriteExchangesToCSV(ctx, exchanges.([]financialmodels.Exchange), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteExchangeRecords(ctx, exchanges.([]financialmodels.Exchange), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, exchanges, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteDailyStockPrices writes stock price data to the specified format
-func WriteDailyStockPrices(prices interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "fact_stock_prices"+ext)
+func WriteDailyStockPrices(ctx context.Context, prices interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "fact_stock_prices") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteDailyStockPricesToCSV(prices.([]financialmodels.DailyStockPrice), filename)
-	case "parquet":
-		return WriteDailyStockPricesToParquet(prices.([]financialmodels.DailyStockPrice), filename)
+		return WriteDailyStockPricesToCSV(ctx, prices.([]financialmodels.DailyStockPrice), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteDailyStockPriceRecords(prices.([]financialmodels.DailyStockPrice), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, prices, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // Medical model writers
 
 // WritePatients writes patient data to the specified format
-func WritePatients(patients interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_patients"+ext)
+func WritePatients(ctx context.Context, patients interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_patients") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WritePatientsToCSV(patients.([]medicalmodels.Patient), filename)
-	case "parquet":
-		return WritePatientsToParquet(patients.([]medicalmodels.Patient), filename)
+		return WritePatientsToCSV(ctx, patients.([]medicalmodels.Patient), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WritePatientRecords(ctx, patients.([]medicalmodels.Patient), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, patients, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteDoctors writes doctor data to the specified format
-func WriteDoctors(doctors interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_doctors"+ext)
+func WriteDoctors(ctx context.Context, doctors interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_doctors") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteDoctorsToCSV(doctors.([]medicalmodels.Doctor), filename)
-	case "parquet":
-		return WriteDoctorsToParquet(doctors.([]medicalmodels.Doctor), filename)
+		return WriteDoctorsToCSV(ctx, doctors.([]medicalmodels.Doctor), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteDoctorRecords(ctx, doctors.([]medicalmodels.Doctor), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, doctors, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteClinics writes clinic data to the specified format
-func WriteClinics(clinics interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "dim_clinics"+ext)
+func WriteClinics(ctx context.Context, clinics interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "dim_clinics") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteClinicsToCSV(clinics.([]medicalmodels.Clinic), filename)
-	case "parquet":
-		return WriteClinicsToParquet(clinics.([]medicalmodels.Clinic), filename)
+		return WriteClinicsToCSV(ctx, clinics.([]medicalmodels.Clinic), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteClinicRecords(ctx, clinics.([]medicalmodels.Clinic), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, clinics, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // WriteAppointments writes appointment data to the specified format
-func WriteAppointments(appointments interface{}, outputDir string, format string) error {
-	ext := getFileExtension(format)
-	filename := filepath.Join(outputDir, "fact_appointments"+ext)
+func WriteAppointments(ctx context.Context, appointments interface{}, outputDir string, opts Options) error {
+	filename := opts.TablePath(outputDir, "fact_appointments") + opts.FileExtension()
 
-	switch format {
+	switch opts.Format {
 	case "csv":
-		return WriteAppointmentsToCSV(appointments.([]medicalmodels.Appointment), filename)
-	case "parquet":
-		return WriteAppointmentsToParquet(appointments.([]medicalmodels.Appointment), filename)
+		return WriteAppointmentsToCSV(ctx, appointments.([]medicalmodels.Appointment), filename, opts)
+	case "parquet", "arrow", "avro":
+		return WriteAppointmentRecords(appointments.([]medicalmodels.Appointment), filename, opts)
+	case "json":
+		return writeSliceToJSON(ctx, appointments, filename, opts)
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
-// getFileExtension returns the appropriate file extension for the format
-func getFileExtension(format string) string {
-	switch strings.ToLower(format) {
+// FileExtension returns the file extension written for the format of o,
+// followed by the extension of its compression for text formats, e.g. .csv.gz.
+func (o Options) FileExtension() string {
+	switch strings.ToLower(o.Format) {
 	case "csv":
-		return ".csv"
+		return ".csv" + compressionExtensions[o.Compression]
 	case "parquet":
 		return ".parquet"
+	case "arrow":
+		return arrowIPCExtensions[o.ArrowIPC]
+	case "avro":
+		return ".avro"
 	case "json":
-		return ".jsonl"
+		return ".jsonl" + compressionExtensions[o.Compression]
+	case "dsdgen":
+		return ".dat" + compressionExtensions[o.Compression]
 	default:
 		return ".csv" // default fallback
 	}
@@ -0,0 +1,513 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/metadata"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/google/uuid"
+	"github.com/peekknuf/Gengo/internal/common"
+)
+
+// Iceberg tables are written as format version 2 tables of a Hadoop
+// (filesystem) catalog: a single append snapshot of all data files, recorded in
+// metadata/v1.metadata.json, a manifest list and one manifest, with the column
+// statistics of every file read from its Parquet footer.
+
+// icebergManifestSchema is the Avro schema of the entries of a version 2
+// manifest of an unpartitioned table.
+const icebergManifestSchema = `{"type":"record","name":"manifest_entry","fields":[` +
+	`{"name":"status","type":"int","field-id":0},` +
+	`{"name":"snapshot_id","type":["null","long"],"default":null,"field-id":1},` +
+	`{"name":"sequence_number","type":["null","long"],"default":null,"field-id":3},` +
+	`{"name":"file_sequence_number","type":["null","long"],"default":null,"field-id":4},` +
+	`{"name":"data_file","type":{"type":"record","name":"r2","fields":[` +
+	`{"name":"content","type":"int","field-id":134},` +
+	`{"name":"file_path","type":"string","field-id":100},` +
+	`{"name":"file_format","type":"string","field-id":101},` +
+	`{"name":"partition","type":{"type":"record","name":"r102","fields":[]},"field-id":102},` +
+	`{"name":"record_count","type":"long","field-id":103},` +
+	`{"name":"file_size_in_bytes","type":"long","field-id":104},` +
+	`{"name":"column_sizes","type":["null",{"type":"array","items":{"type":"record","name":"k117_v118","fields":[{"name":"key","type":"int","field-id":117},{"name":"value","type":"long","field-id":118}]},"logicalType":"map"}],"default":null,"field-id":108},` +
+	`{"name":"value_counts","type":["null",{"type":"array","items":{"type":"record","name":"k119_v120","fields":[{"name":"key","type":"int","field-id":119},{"name":"value","type":"long","field-id":120}]},"logicalType":"map"}],"default":null,"field-id":109},` +
+	`{"name":"null_value_counts","type":["null",{"type":"array","items":{"type":"record","name":"k121_v122","fields":[{"name":"key","type":"int","field-id":121},{"name":"value","type":"long","field-id":122}]},"logicalType":"map"}],"default":null,"field-id":110},` +
+	`{"name":"lower_bounds","type":["null",{"type":"array","items":{"type":"record","name":"k126_v127","fields":[{"name":"key","type":"int","field-id":126},{"name":"value","type":"bytes","field-id":127}]},"logicalType":"map"}],"default":null,"field-id":125},` +
+	`{"name":"upper_bounds","type":["null",{"type":"array","items":{"type":"record","name":"k129_v130","fields":[{"name":"key","type":"int","field-id":129},{"name":"value","type":"bytes","field-id":130}]},"logicalType":"map"}],"default":null,"field-id":128}` +
+	`]},"field-id":2}]}`
+
+// icebergManifestListSchema is the Avro schema of a version 2 manifest list.
+const icebergManifestListSchema = `{"type":"record","name":"manifest_file","fields":[` +
+	`{"name":"manifest_path","type":"string","field-id":500},` +
+	`{"name":"manifest_length","type":"long","field-id":501},` +
+	`{"name":"partition_spec_id","type":"int","field-id":502},` +
+	`{"name":"content","type":"int","field-id":517},` +
+	`{"name":"sequence_number","type":"long","field-id":515},` +
+	`{"name":"min_sequence_number","type":"long","field-id":516},` +
+	`{"name":"added_snapshot_id","type":"long","field-id":503},` +
+	`{"name":"added_files_count","type":"int","field-id":504},` +
+	`{"name":"existing_files_count","type":"int","field-id":505},` +
+	`{"name":"deleted_files_count","type":"int","field-id":506},` +
+	`{"name":"added_rows_count","type":"long","field-id":512},` +
+	`{"name":"existing_rows_count","type":"long","field-id":513},` +
+	`{"name":"deleted_rows_count","type":"long","field-id":514},` +
+	`{"name":"partitions","type":["null",{"type":"array","items":{"type":"record","name":"r508","fields":[` +
+	`{"name":"contains_null","type":"boolean","field-id":509},` +
+	`{"name":"contains_nan","type":["null","boolean"],"default":null,"field-id":518},` +
+	`{"name":"lower_bound","type":["null","bytes"],"default":null,"field-id":510},` +
+	`{"name":"upper_bound","type":["null","bytes"],"default":null,"field-id":511}]},"element-id":508}],"default":null,"field-id":507}]}`
+
+type icebergField struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+}
+
+type icebergSchema struct {
+	Type     string         `json:"type"`
+	SchemaID int            `json:"schema-id"`
+	Fields   []icebergField `json:"fields"`
+}
+
+type icebergSnapshot struct {
+	SequenceNumber int64             `json:"sequence-number"`
+	SnapshotID     int64             `json:"snapshot-id"`
+	TimestampMs    int64             `json:"timestamp-ms"`
+	Summary        map[string]string `json:"summary"`
+	ManifestList   string            `json:"manifest-list"`
+	SchemaID       int               `json:"schema-id"`
+}
+
+type icebergSnapshotLog struct {
+	TimestampMs int64 `json:"timestamp-ms"`
+	SnapshotID  int64 `json:"snapshot-id"`
+}
+
+type icebergRef struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+type icebergPartitionSpec struct {
+	SpecID int        `json:"spec-id"`
+	Fields []struct{} `json:"fields"`
+}
+
+type icebergSortOrder struct {
+	OrderID int        `json:"order-id"`
+	Fields  []struct{} `json:"fields"`
+}
+
+// icebergMetadata is the table metadata file of a version 2 table.
+type icebergMetadata struct {
+	FormatVersion      int                    `json:"format-version"`
+	TableUUID          string                 `json:"table-uuid"`
+	Location           string                 `json:"location"`
+	LastSequenceNumber int64                  `json:"last-sequence-number"`
+	LastUpdatedMs      int64                  `json:"last-updated-ms"`
+	LastColumnID       int                    `json:"last-column-id"`
+	CurrentSchemaID    int                    `json:"current-schema-id"`
+	Schemas            []icebergSchema        `json:"schemas"`
+	DefaultSpecID      int                    `json:"default-spec-id"`
+	PartitionSpecs     []icebergPartitionSpec `json:"partition-specs"`
+	LastPartitionID    int                    `json:"last-partition-id"`
+	DefaultSortOrderID int                    `json:"default-sort-order-id"`
+	SortOrders         []icebergSortOrder     `json:"sort-orders"`
+	Properties         map[string]string      `json:"properties"`
+	CurrentSnapshotID  int64                  `json:"current-snapshot-id"`
+	Refs               map[string]icebergRef  `json:"refs"`
+	Snapshots          []icebergSnapshot      `json:"snapshots"`
+	SnapshotLog        []icebergSnapshotLog   `json:"snapshot-log"`
+	MetadataLog        []struct{}             `json:"metadata-log"`
+}
+
+// parquetFileStats are the row count, size and column statistics of a Parquet
+// file, by column in schema order.
+type parquetFileStats struct {
+	rows    int64
+	bytes   int64
+	columns []parquetColumnStats
+}
+
+type parquetColumnStats struct {
+	bytes  int64
+	values int64
+	// nulls is -1 if the file has no null count for the column.
+	nulls int64
+	// min and max are the bounds in Parquet's plain encoding, which for the
+	// types gengo writes is Iceberg's single-value serialization; nil if the
+	// file has no statistics for the column.
+	min, max []byte
+}
+
+// readParquetStats returns the statistics of filename, merged over its row
+// groups, and its Arrow schema.
+func readParquetStats(filename string) (parquetFileStats, *arrow.Schema, error) {
+	rdr, err := file.OpenParquetFile(filename, false)
+	if err != nil {
+		return parquetFileStats{}, nil, fmt.Errorf("failed to read parquet footer of %s: %w", filename, err)
+	}
+	defer rdr.Close()
+	md := rdr.MetaData()
+	schema, err := pqarrow.FromParquet(md.Schema, nil, md.KeyValueMetadata())
+	if err != nil {
+		return parquetFileStats{}, nil, fmt.Errorf("failed to read parquet schema of %s: %w", filename, err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return parquetFileStats{}, nil, err
+	}
+
+	stats := parquetFileStats{rows: md.NumRows, bytes: info.Size(), columns: make([]parquetColumnStats, md.Schema.NumColumns())}
+	for c := range stats.columns {
+		merged := metadata.NewStatistics(md.Schema.Column(c), memory.DefaultAllocator)
+		complete := true
+		for g := 0; g < md.NumRowGroups(); g++ {
+			chunk, err := md.RowGroup(g).ColumnChunk(c)
+			if err != nil {
+				return parquetFileStats{}, nil, fmt.Errorf("failed to read column chunk of %s: %w", filename, err)
+			}
+			stats.columns[c].bytes += chunk.TotalCompressedSize()
+			stats.columns[c].values += chunk.NumValues()
+			set, err := chunk.StatsSet()
+			if err != nil || !set {
+				complete = false
+				continue
+			}
+			s, err := chunk.Statistics()
+			if err != nil || s == nil {
+				complete = false
+				continue
+			}
+			merged.Merge(s)
+		}
+		stats.columns[c].nulls = -1
+		if complete && merged.HasNullCount() {
+			stats.columns[c].nulls = merged.NullCount()
+		}
+		if complete && merged.HasMinMax() {
+			stats.columns[c].min = merged.EncodeMin()
+			stats.columns[c].max = merged.EncodeMax()
+		}
+	}
+	return stats, schema, nil
+}
+
+// icebergType returns the Iceberg type of an Arrow column type.
+func icebergType(t arrow.DataType) (string, error) {
+	switch t := t.(type) {
+	case *arrow.BooleanType:
+		return "boolean", nil
+	case *arrow.Int32Type:
+		return "int", nil
+	case *arrow.Int64Type:
+		return "long", nil
+	case *arrow.Float32Type:
+		return "float", nil
+	case *arrow.Float64Type:
+		return "double", nil
+	case *arrow.StringType, *arrow.LargeStringType:
+		return "string", nil
+	case *arrow.Date32Type:
+		return "date", nil
+	case *arrow.TimestampType:
+		if t.Unit != arrow.Microsecond {
+			return "", fmt.Errorf("unsupported timestamp unit %s", t.Unit)
+		}
+		if t.TimeZone == "" {
+			return "timestamp", nil
+		}
+		return "timestamptz", nil
+	}
+	return "", fmt.Errorf("unsupported column type %s", t)
+}
+
+// commitIcebergTable writes the metadata of the Iceberg table in dir, whose
+// data files are files, replacing any left by an earlier attempt. Its IDs and
+// file names are drawn from seed and the table name. codec is the codec the
+// data files are compressed with.
+func commitIcebergTable(dir string, files []string, seed int64, codec string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	metadataDir := filepath.Join(dir, "metadata")
+	if err := os.RemoveAll(metadataDir); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", metadataDir, err)
+	}
+
+	stats := make([]parquetFileStats, len(files))
+	var schema *arrow.Schema
+	paths := make([]string, len(files))
+	for i, f := range files {
+		if paths[i], err = filepath.Abs(f); err != nil {
+			return err
+		}
+		var fileSchema *arrow.Schema
+		if stats[i], fileSchema, err = readParquetStats(f); err != nil {
+			return err
+		}
+		if schema == nil {
+			schema = fileSchema
+		}
+	}
+	table := icebergSchema{Type: "struct"}
+	for i, field := range schema.Fields() {
+		typ, err := icebergType(field.Type)
+		if err != nil {
+			return fmt.Errorf("column %s of %s: %w", field.Name, filepath.Base(dir), err)
+		}
+		table.Fields = append(table.Fields, icebergField{ID: i + 1, Name: field.Name, Required: !field.Nullable, Type: typ})
+	}
+	schemaJSON, err := json.Marshal(table)
+	if err != nil {
+		return err
+	}
+
+	now := tableCommitTime.UnixMilli()
+	ids := common.NewByteSource(seed, filepath.Base(dir), 0)
+	id, err := uuid.NewRandomFromReader(ids)
+	if err != nil {
+		return err
+	}
+	snapshotID := int64(binary.BigEndian.Uint64(id[:8]) & math.MaxInt64)
+	manifestID, err := uuid.NewRandomFromReader(ids)
+	if err != nil {
+		return err
+	}
+	listID, err := uuid.NewRandomFromReader(ids)
+	if err != nil {
+		return err
+	}
+	tableID, err := uuid.NewRandomFromReader(ids)
+	if err != nil {
+		return err
+	}
+	var rows, size int64
+	for _, s := range stats {
+		rows += s.rows
+		size += s.bytes
+	}
+
+	// The manifest lists every data file as added by the snapshot.
+	manifestName := filepath.Join(metadataDir, manifestID.String()+"-m0.avro")
+	var manifest bytes.Buffer
+	w, err := newAvroWriter(&manifest, manifestName, icebergManifestSchema, "deflate", map[string]string{
+		"schema":            string(schemaJSON),
+		"schema-id":         "0",
+		"partition-spec":    "[]",
+		"partition-spec-id": "0",
+		"format-version":    "2",
+		"content":           "data",
+	})
+	if err != nil {
+		return err
+	}
+	for i, s := range stats {
+		if err := w.append(appendIcebergManifestEntry(nil, snapshotID, fileURI(paths[i]), s)); err != nil {
+			return err
+		}
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	if err := writeFileAtomically(manifestName, manifest.Bytes()); err != nil {
+		return err
+	}
+
+	listName := filepath.Join(metadataDir, fmt.Sprintf("snap-%d-1-%s.avro", snapshotID, listID))
+	var list bytes.Buffer
+	if w, err = newAvroWriter(&list, listName, icebergManifestListSchema, "deflate", map[string]string{
+		"snapshot-id":        strconv.FormatInt(snapshotID, 10),
+		"parent-snapshot-id": "null",
+		"sequence-number":    "1",
+		"format-version":     "2",
+	}); err != nil {
+		return err
+	}
+	entry := appendAvroString(nil, fileURI(manifestName))
+	entry = appendAvroLong(entry, int64(manifest.Len()))
+	entry = appendAvroLong(entry, 0) // partition_spec_id
+	entry = appendAvroLong(entry, 0) // content: data
+	entry = appendAvroLong(entry, 1) // sequence_number
+	entry = appendAvroLong(entry, 1) // min_sequence_number
+	entry = appendAvroLong(entry, snapshotID)
+	entry = appendAvroLong(entry, int64(len(files)))
+	entry = appendAvroLong(entry, 0)
+	entry = appendAvroLong(entry, 0)
+	entry = appendAvroLong(entry, rows)
+	entry = appendAvroLong(entry, 0)
+	entry = appendAvroLong(entry, 0)
+	entry = appendAvroLong(entry, 1) // partitions: an empty array
+	entry = appendAvroLong(entry, 0)
+	if err := w.append(entry); err != nil {
+		return err
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	if err := writeFileAtomically(listName, list.Bytes()); err != nil {
+		return err
+	}
+
+	total := strconv.Itoa(len(files))
+	meta := icebergMetadata{
+		FormatVersion:      2,
+		TableUUID:          tableID.String(),
+		Location:           fileURI(dir),
+		LastSequenceNumber: 1,
+		LastUpdatedMs:      now,
+		LastColumnID:       len(table.Fields),
+		Schemas:            []icebergSchema{table},
+		PartitionSpecs:     []icebergPartitionSpec{{Fields: []struct{}{}}},
+		LastPartitionID:    999,
+		SortOrders:         []icebergSortOrder{{Fields: []struct{}{}}},
+		Properties:         map[string]string{"write.parquet.compression-codec": codec},
+		CurrentSnapshotID:  snapshotID,
+		Refs:               map[string]icebergRef{"main": {SnapshotID: snapshotID, Type: "branch"}},
+		Snapshots: []icebergSnapshot{{
+			SequenceNumber: 1,
+			SnapshotID:     snapshotID,
+			TimestampMs:    now,
+			Summary: map[string]string{
+				"operation":              "append",
+				"added-data-files":       total,
+				"added-records":          strconv.FormatInt(rows, 10),
+				"added-files-size":       strconv.FormatInt(size, 10),
+				"total-data-files":       total,
+				"total-records":          strconv.FormatInt(rows, 10),
+				"total-files-size":       strconv.FormatInt(size, 10),
+				"total-delete-files":     "0",
+				"total-position-deletes": "0",
+				"total-equality-deletes": "0",
+			},
+			ManifestList: fileURI(listName),
+		}},
+		SnapshotLog: []icebergSnapshotLog{{TimestampMs: now, SnapshotID: snapshotID}},
+		MetadataLog: []struct{}{},
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomically(filepath.Join(metadataDir, "v1.metadata.json"), data); err != nil {
+		return err
+	}
+	// The version hint makes the table visible to a Hadoop catalog.
+	return writeFileAtomically(filepath.Join(metadataDir, "version-hint.text"), []byte("1"))
+}
+
+// appendIcebergManifestEntry appends the manifest entry of a data file added
+// by the snapshot.
+func appendIcebergManifestEntry(buf []byte, snapshotID int64, path string, s parquetFileStats) []byte {
+	buf = appendAvroLong(buf, 1) // status: added
+	buf = appendAvroLong(buf, 1)
+	buf = appendAvroLong(buf, snapshotID)
+	buf = appendAvroLong(buf, 0) // sequence_number: inherited
+	buf = appendAvroLong(buf, 0) // file_sequence_number: inherited
+	buf = appendAvroLong(buf, 0) // content: data
+	buf = appendAvroString(buf, path)
+	buf = appendAvroString(buf, "PARQUET")
+	buf = appendAvroLong(buf, s.rows)
+	buf = appendAvroLong(buf, s.bytes)
+
+	sizes := make([]int64, len(s.columns))
+	values := make([]int64, len(s.columns))
+	nulls := make([]int64, len(s.columns))
+	lower := make([][]byte, len(s.columns))
+	upper := make([][]byte, len(s.columns))
+	for i, c := range s.columns {
+		sizes[i], values[i], nulls[i], lower[i], upper[i] = c.bytes, c.values, c.nulls, c.min, c.max
+	}
+	buf = appendIcebergCounts(buf, sizes)
+	buf = appendIcebergCounts(buf, values)
+	buf = appendIcebergCounts(buf, nulls)
+	buf = appendIcebergBounds(buf, lower)
+	return appendIcebergBounds(buf, upper)
+}
+
+// appendIcebergCounts appends a map from field ID to count, leaving out the
+// unknown (negative) counts.
+func appendIcebergCounts(buf []byte, counts []int64) []byte {
+	n := 0
+	for _, c := range counts {
+		if c >= 0 {
+			n++
+		}
+	}
+	buf = appendAvroLong(buf, 1)
+	if n > 0 {
+		buf = appendAvroLong(buf, int64(n))
+		for i, c := range counts {
+			if c >= 0 {
+				buf = appendAvroLong(buf, int64(i+1))
+				buf = appendAvroLong(buf, c)
+			}
+		}
+	}
+	return appendAvroLong(buf, 0)
+}
+
+// appendIcebergBounds appends a map from field ID to bound, leaving out the
+// columns without one.
+func appendIcebergBounds(buf []byte, bounds [][]byte) []byte {
+	n := 0
+	for _, b := range bounds {
+		if b != nil {
+			n++
+		}
+	}
+	buf = appendAvroLong(buf, 1)
+	if n > 0 {
+		buf = appendAvroLong(buf, int64(n))
+		for i, b := range bounds {
+			if b != nil {
+				buf = appendAvroLong(buf, int64(i+1))
+				buf = appendAvroBytes(buf, b)
+			}
+		}
+	}
+	return appendAvroLong(buf, 0)
+}
+
+// fileURI returns the file URI of an absolute path.
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// writeFileAtomically writes data to filename under its temporary name and
+// renames it into place.
+func writeFileAtomically(filename string, data []byte) error {
+	f, err := CreateOutputFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	// The file is not published to the checkpoint, so it need not be described.
+	takeOutputFile(filename)
+	if err == nil {
+		err = os.Rename(TempName(filename), filename)
+	}
+	if err != nil {
+		os.Remove(TempName(filename))
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
@@ -1,57 +1,173 @@
 package formats
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
 	"fmt"
-	"os"
+	"math"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-func writeSliceToJSON(data interface{}, targetFilename string) error {
+const jsonBufferSize = 16 * 1024 * 1024 // 16MB buffer, same as the CSV slice writer
+
+// jsonKeys returns the object key of every field of t. The json tag wins, then the
+// csv and parquet tags, so models that only carry csv tags still get snake_case keys.
+func jsonKeys(t reflect.Type) []string {
+	keys := make([]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "" {
+			tag = field.Tag.Get("csv")
+		}
+		if tag == "" {
+			tag = field.Tag.Get("parquet")
+		}
+		tag = strings.SplitN(tag, ",", 2)[0]
+		if tag == "" {
+			tag = field.Name
+		}
+		keys[i] = tag
+	}
+	return keys
+}
+
+// JSONKeyPrefixes renders `{"key":` for the first field and `,"key":` for the rest,
+// so a record is written as prefix, value, prefix, value, ..., '}'.
+func JSONKeyPrefixes(keys []string) [][]byte {
+	prefixes := make([][]byte, len(keys))
+	for i, key := range keys {
+		sep := byte(',')
+		if i == 0 {
+			sep = '{'
+		}
+		buf := append([]byte{sep}, appendJSONString(nil, key)...)
+		prefixes[i] = append(buf, ':')
+	}
+	return prefixes
+}
+
+// appendJSONString appends s as a quoted JSON string.
+func appendJSONString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, "\ufffd"...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
+
+// appendJSONValue appends a struct field as a JSON value. Timestamps are RFC 3339
+// strings, matching the reflective CSV writer; NaN and infinities become null.
+func appendJSONValue(buf []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(buf, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(buf, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return append(buf, "null"...)
+		}
+		return strconv.AppendFloat(buf, f, 'f', -1, 64)
+	case reflect.String:
+		return appendJSONString(buf, v.String())
+	case reflect.Bool:
+		return strconv.AppendBool(buf, v.Bool())
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return appendJSONString(buf, t.Format(time.RFC3339))
+		}
+	}
+	return append(buf, "null"...)
+}
+
+// writeSliceToJSON streams a slice of structs to a JSON Lines file, one object per line.
+func writeSliceToJSON(ctx context.Context, data interface{}, targetFilename string, opts Options) (err error) {
+	startTime := time.Now()
 	sliceVal := reflect.ValueOf(data)
 	if sliceVal.Kind() != reflect.Slice {
 		return fmt.Errorf("writeSliceToJSON expected a slice, got %T", data)
 	}
 	sliceLen := sliceVal.Len()
 	if sliceLen == 0 {
-		fmt.Printf("Skipping JSON write for %s: slice is empty.\n", targetFilename)
-		return nil
+		return nil // Nothing to write
 	}
 
-	file, err := os.Create(targetFilename)
+	file, err := opts.CreateTextFile(targetFilename)
 	if err != nil {
 		return fmt.Errorf("failed to create json file %s: %w", targetFilename, err)
 	}
-	var fileCloseErr error
-	defer func() {
-		if err := file.Close(); err != nil {
-			fileCloseErr = fmt.Errorf("error closing json file %s: %w", targetFilename, err)
-		}
-	}()
-
-	encoder := json.NewEncoder(file)
-
-	elemType := sliceVal.Type().Elem()
-	isPointer := elemType.Kind() == reflect.Ptr
+	writer := bufio.NewWriterSize(file, jsonBufferSize)
+	defer opts.CloseBufferedFile(writer, file, targetFilename, &err)
 
+	var prefixes [][]byte
+	rowBuf := make([]byte, 0, 1024)
+	written := 0
 	for i := 0; i < sliceLen; i++ {
-		elemVal := sliceVal.Index(i)
-		if isPointer && elemVal.IsNil() {
-			fmt.Fprintf(os.Stderr, "Warning: skipping nil JSON element at index %d in %s\n", i, targetFilename)
-			continue
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
+		elem := sliceVal.Index(i)
+		for elem.Kind() == reflect.Interface || elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				break
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return fmt.Errorf("writeSliceToJSON expected structs, got %s at index %d in %s", elem.Kind(), i, targetFilename)
+		}
+		if prefixes == nil {
+			prefixes = JSONKeyPrefixes(jsonKeys(elem.Type()))
 		}
 
-		interfaceVal := elemVal.Interface()
-
-		if err := encoder.Encode(interfaceVal); err != nil {
-			return fmt.Errorf("failed to encode record %d to json file %s: %w", i, targetFilename, err)
+		rowBuf = rowBuf[:0]
+		for f, prefix := range prefixes {
+			rowBuf = append(rowBuf, prefix...)
+			rowBuf = appendJSONValue(rowBuf, elem.Field(f))
 		}
-	}
+		rowBuf = append(rowBuf, '}', '\n')
 
-	if fileCloseErr != nil {
-		return fileCloseErr
+		if _, err := writer.Write(rowBuf); err != nil {
+			return fmt.Errorf("failed to write record %d to json file %s: %w", i, targetFilename, err)
+		}
+		written++
 	}
 
-	fmt.Printf("Successfully wrote %d records to %s (JSON Lines)\n", sliceLen, targetFilename)
+	duration := time.Since(startTime)
+	fmt.Printf("Successfully wrote %d records to %s in %s\n", written, targetFilename, duration.Round(time.Millisecond))
 	return nil
 }
@@ -0,0 +1,46 @@
+package formats
+
+// Options are the settings a run writes its files with. They are passed to
+// every writer of the run instead of being installed in the package, so runs
+// in the same process cannot see each other's settings.
+type Options struct {
+	// Format is the format data files are written in: csv, json, parquet,
+	// arrow, avro or dsdgen. The table formats write parquet data files.
+	Format string
+	// TableFormat is the table format every table is written as, iceberg or
+	// delta, or "" if tables are written as plain files.
+	TableFormat string
+	// CSV is the dialect of CSV files.
+	CSV CSVDialect
+	// Compression is the codec text files, or the buffers of Arrow IPC
+	// files, are compressed with, or "" for none.
+	Compression string
+	// ArrowIPC is the variant Arrow IPC files are written in, file or stream.
+	ArrowIPC string
+	// AvroCodec is the codec the blocks of Avro files are compressed with.
+	AvroCodec string
+	// Parquet are the writer properties of Parquet files.
+	Parquet ParquetOptions
+	// Partitioning splits the files of fact tables into partitions; it has
+	// no keys if they are written unpartitioned.
+	Partitioning Partitioning
+	// Checkpoint is told about every file the run publishes and knows the
+	// files an interrupted attempt of the run completed; nil if there is none.
+	Checkpoint Checkpoint
+}
+
+// DefaultOptions returns the options writing format, which may be a table
+// format, with the defaults of every setting.
+func DefaultOptions(format string) Options {
+	o := Options{
+		Format:    DataFormat(format),
+		CSV:       DefaultCSVDialect,
+		ArrowIPC:  "file",
+		AvroCodec: "deflate",
+		Parquet:   DefaultParquetOptions,
+	}
+	if o.Format != format {
+		o.TableFormat = format
+	}
+	return o
+}
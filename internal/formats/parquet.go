@@ -1,8 +1,8 @@
 package formats
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"reflect"
 	"strings"
 	"time"
@@ -14,23 +14,20 @@ import (
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
-	"github.com/apache/arrow-go/v18/parquet"
-	"github.com/apache/arrow-go/v18/parquet/compress"
-	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 )
 
 const (
 	parquetWriteBatchSize = 1024 * 64
 )
 
-func WriteSliceToParquet(data interface{}, targetFilename string) (err error) {
+func WriteSliceRecords(ctx context.Context, data interface{}, targetFilename string, opts Options) (err error) {
 	sliceVal := reflect.ValueOf(data)
 	if sliceVal.Kind() != reflect.Slice {
-		return fmt.Errorf("WriteSliceToParquet expected a slice, got %T", data)
+		return fmt.Errorf("WriteSliceRecords expected a slice, got %T", data)
 	}
 	sliceLen := sliceVal.Len()
 	if sliceLen == 0 {
-		fmt.Printf("Skipping Parquet write for %s: slice is empty.\n", targetFilename)
+		fmt.Printf("Skipping %s write for %s: slice is empty.\n", opts.Format, targetFilename)
 		return nil
 	}
 
@@ -47,7 +44,7 @@ func WriteSliceToParquet(data interface{}, targetFilename string) (err error) {
 				elemType = elemType.Elem()
 			}
 		} else {
-			return fmt.Errorf("WriteSliceToParquet cannot determine type from empty []interface{}")
+			return fmt.Errorf("WriteSliceRecords cannot determine type from empty []interface{}")
 		}
 	} else {
 		elemType = sliceVal.Type().Elem()
@@ -58,12 +55,13 @@ func WriteSliceToParquet(data interface{}, targetFilename string) (err error) {
 	}
 
 	if elemType.Kind() != reflect.Struct {
-		return fmt.Errorf("WriteSliceToParquet expected slice of structs/pointers, got %s", elemType.Kind())
+		return fmt.Errorf("WriteSliceRecords expected slice of structs/pointers, got %s", elemType.Kind())
 	}
 	schema, err := buildArrowSchema(elemType)
 	if err != nil {
 		return fmt.Errorf("failed to build schema for %s: %w", elemType.Name(), err)
 	}
+	schema = opts.tableSchema(schema)
 	fieldMap := map[string]int{}
 	for i := 0; i < elemType.NumField(); i++ {
 		field := elemType.Field(i)
@@ -73,40 +71,33 @@ func WriteSliceToParquet(data interface{}, targetFilename string) (err error) {
 		}
 	}
 
-	var file *os.File
-	file, err = os.Create(targetFilename)
+	var writer RecordWriter
+	_, writer, err = openRecordWriter(schema, targetFilename, true, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create parquet file %s: %w", targetFilename, err)
+		return err
 	}
-	defer func() { // Defer check/close only for error path before writer setup/close
-		if err != nil && file != nil {
-			_ = file.Close()
-		}
-	}()
-
-	pool := memory.NewGoAllocator()
-	props := parquet.NewWriterProperties(parquet.WithDictionaryDefault(true), parquet.WithCompression(compress.Codecs.Snappy))
-	arrowProps := pqarrow.NewArrowWriterProperties()
-	var writer *pqarrow.FileWriter
-	writer, err = pqarrow.NewFileWriter(schema, file, props, arrowProps)
-	if err != nil {
-		return fmt.Errorf("failed to create parquet writer for %s: %w", targetFilename, err)
-	} // Error triggers file close defer
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() { // Use DEFER for writer close
 		if closeErr := writer.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("error closing parquet writer for %s: %w", targetFilename, closeErr)
+			err = fmt.Errorf("error closing writer for %s: %w", targetFilename, closeErr)
 		}
 	}()
+	pool := memory.NewGoAllocator()
 	builder := array.NewRecordBuilder(pool, schema)
 	defer builder.Release()
 
 	rowsInCurrentBatch := 0
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", sliceLen, targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", sliceLen, targetFilename, opts.Format)
 	progressStep := sliceLen / 20
 	if progressStep == 0 {
 		progressStep = 1
 	}
 	for i := 0; i < sliceLen; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
 		elemVal := sliceVal.Index(i)
 
 		// Handle []interface{} case - need to extract the actual value from the interface
@@ -179,11 +170,11 @@ func WriteSliceToParquet(data interface{}, targetFilename string) (err error) {
 }
 
 // writeParquetBatchCorrected writes a batch, releases the builder, returns a new one.
-func writeParquetBatchCorrected(writer *pqarrow.FileWriter, builder *array.RecordBuilder, pool memory.Allocator, schema *arrow.Schema, errorContextFilename string) (*array.RecordBuilder, error) {
+func writeParquetBatchCorrected(writer RecordWriter, builder *array.RecordBuilder, pool memory.Allocator, schema *arrow.Schema, errorContextFilename string) (*array.RecordBuilder, error) {
 	record := builder.NewRecord()
 	defer record.Release()
 	if err := writer.Write(record); err != nil {
-		return builder, fmt.Errorf("error writing batch to parquet file %s: %w", errorContextFilename, err)
+		return builder, fmt.Errorf("error writing batch to %s: %w", errorContextFilename, err)
 	}
 	return builder, nil
 }
@@ -206,6 +197,12 @@ func getParquetFieldName(field reflect.StructField) string {
 }
 
 // buildArrowSchema creates an Arrow schema from a Go struct type via reflection.
+// StructSchema returns the Arrow schema of the model struct row, as written to
+// Parquet.
+func StructSchema(row interface{}) (*arrow.Schema, error) {
+	return buildArrowSchema(reflect.TypeOf(row))
+}
+
 func buildArrowSchema(structType reflect.Type) (*arrow.Schema, error) {
 	fields := make([]arrow.Field, 0, structType.NumField())
 	for i := 0; i < structType.NumField(); i++ {
@@ -250,64 +247,64 @@ func buildArrowSchema(structType reflect.Type) (*arrow.Schema, error) {
 	return arrow.NewSchema(fields, nil), nil
 }
 
-// Parquet writer functions for e-commerce models
+// Record writer functions for e-commerce models
 
-func WriteCustomersToParquet(customers []ecommercemodels.Customer, targetFilename string) error {
-	return WriteSliceToParquet(customers, targetFilename)
+func WriteCustomerRecords(ctx context.Context, customers []ecommercemodels.Customer, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, customers, targetFilename, opts)
 }
 
-func WriteCustomerAddressesToParquet(addresses []ecommercemodels.CustomerAddress, targetFilename string) error {
-	return WriteSliceToParquet(addresses, targetFilename)
+func WriteCustomerAddressRecords(ctx context.Context, addresses []ecommercemodels.CustomerAddress, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, addresses, targetFilename, opts)
 }
 
-func WriteSuppliersToParquet(suppliers []ecommercemodels.Supplier, targetFilename string) error {
-	return WriteSliceToParquet(suppliers, targetFilename)
+func WriteSupplierRecords(ctx context.Context, suppliers []ecommercemodels.Supplier, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, suppliers, targetFilename, opts)
 }
 
-func WriteProductCategoriesToParquet(categories []ecommercemodels.ProductCategory, targetFilename string) error {
-	return WriteSliceToParquet(categories, targetFilename)
+func WriteProductCategoryRecords(ctx context.Context, categories []ecommercemodels.ProductCategory, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, categories, targetFilename, opts)
 }
 
-func WriteProductsToParquet(products []ecommercemodels.Product, targetFilename string) error {
-	return WriteSliceToParquet(products, targetFilename)
+func WriteProductRecords(ctx context.Context, products []ecommercemodels.Product, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, products, targetFilename, opts)
 }
 
-func WriteOrderHeadersToParquet(headers []ecommercemodels.OrderHeader, targetFilename string) error {
-	return WriteOrderHeadersToParquetTyped(headers, targetFilename)
+func WriteOrderHeaderRecords(headers []ecommercemodels.OrderHeader, targetFilename string, opts Options) error {
+	return WriteOrderHeaderRecordsTyped(headers, targetFilename, opts)
 }
 
-func WriteOrderItemsToParquet(items []ecommercemodels.OrderItem, targetFilename string) error {
-	return WriteOrderItemsToParquetTyped(items, targetFilename)
+func WriteOrderItemRecords(items []ecommercemodels.OrderItem, targetFilename string, opts Options) error {
+	return WriteOrderItemRecordsTyped(items, targetFilename, opts)
 }
 
-// Parquet writer functions for financial models
+// Record writer functions for financial models
 
-func WriteCompaniesToParquet(companies []financialmodels.Company, targetFilename string) error {
-	return WriteSliceToParquet(companies, targetFilename)
+func WriteCompanyRecords(ctx context.Context, companies []financialmodels.Company, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, companies, targetFilename, opts)
 }
 
-func WriteExchangesToParquet(exchanges []financialmodels.Exchange, targetFilename string) error {
-	return WriteSliceToParquet(exchanges, targetFilename)
+func WriteExchangeRecords(ctx context.Context, exchanges []financialmodels.Exchange, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, exchanges, targetFilename, opts)
 }
 
-func WriteDailyStockPricesToParquet(prices []financialmodels.DailyStockPrice, targetFilename string) error {
-	return WriteDailyStockPricesToParquetTyped(prices, targetFilename)
+func WriteDailyStockPriceRecords(prices []financialmodels.DailyStockPrice, targetFilename string, opts Options) error {
+	return WriteDailyStockPriceRecordsTyped(prices, targetFilename, opts)
 }
 
-// Parquet writer functions for medical models
+// Record writer functions for medical models
 
-func WritePatientsToParquet(patients []medicalmodels.Patient, targetFilename string) error {
-	return WriteSliceToParquet(patients, targetFilename)
+func WritePatientRecords(ctx context.Context, patients []medicalmodels.Patient, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, patients, targetFilename, opts)
 }
 
-func WriteDoctorsToParquet(doctors []medicalmodels.Doctor, targetFilename string) error {
-	return WriteSliceToParquet(doctors, targetFilename)
+func WriteDoctorRecords(ctx context.Context, doctors []medicalmodels.Doctor, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, doctors, targetFilename, opts)
 }
 
-func WriteClinicsToParquet(clinics []medicalmodels.Clinic, targetFilename string) error {
-	return WriteSliceToParquet(clinics, targetFilename)
+func WriteClinicRecords(ctx context.Context, clinics []medicalmodels.Clinic, targetFilename string, opts Options) error {
+	return WriteSliceRecords(ctx, clinics, targetFilename, opts)
 }
 
-func WriteAppointmentsToParquet(appointments []medicalmodels.Appointment, targetFilename string) error {
-	return WriteAppointmentsToParquetTyped(appointments, targetFilename)
+func WriteAppointmentRecords(appointments []medicalmodels.Appointment, targetFilename string, opts Options) error {
+	return WriteAppointmentRecordsTyped(appointments, targetFilename, opts)
 }
@@ -0,0 +1,177 @@
+package formats
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// The Parquet writer lists the page encoding stats of a column chunk in the
+// order of a Go map, so two files written from the same rows can differ in
+// their footers once a column chunk has pages of more than one encoding, as
+// when its dictionary outgrows the page size limit. sortParquetEncodingStats
+// puts them in a fixed order after the file is written. Only the order of the
+// list elements changes, so the footer keeps its length and is rewritten in
+// place.
+
+// Field IDs along the path to the encoding stats in the footer's FileMetaData.
+const (
+	fileMetaDataRowGroups       = 4
+	rowGroupColumns             = 1
+	columnChunkMetaData         = 3
+	columnMetaDataEncodingStats = 13
+)
+
+// sortParquetEncodingStats sorts the page encoding stats in the footer of the
+// Parquet file f.
+func sortParquetEncodingStats(f *OutputFile) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	var tail [8]byte
+	if _, err := f.ReadAt(tail[:], info.Size()-8); err != nil {
+		return fmt.Errorf("failed to read the footer of %s: %w", f.Name(), err)
+	}
+	size := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if string(tail[4:]) != "PAR1" || size > info.Size()-12 {
+		return fmt.Errorf("%s has no Parquet footer", f.Name())
+	}
+	footer := make([]byte, size)
+	offset := info.Size() - 8 - size
+	if _, err := f.ReadAt(footer, offset); err != nil {
+		return fmt.Errorf("failed to read the footer of %s: %w", f.Name(), err)
+	}
+	sorted, err := sortFooterEncodingStats(footer)
+	if err != nil {
+		return fmt.Errorf("failed to read the footer of %s: %w", f.Name(), err)
+	}
+	if !sorted {
+		return nil
+	}
+	if _, err := f.WriteAt(footer, offset); err != nil {
+		return fmt.Errorf("failed to write the footer of %s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// footerReader reads the Thrift compact encoding of a Parquet footer and
+// knows the offset it has reached.
+type footerReader struct {
+	ctx   context.Context
+	data  []byte
+	buf   *thrift.TMemoryBuffer
+	proto *thrift.TCompactProtocol
+}
+
+func (r *footerReader) pos() int { return len(r.data) - r.buf.Len() }
+
+// sortFooterEncodingStats sorts the encoding stats lists of the serialized
+// FileMetaData footer in place, reporting whether any list changed.
+func sortFooterEncodingStats(footer []byte) (bool, error) {
+	buf := &thrift.TMemoryBuffer{Buffer: bytes.NewBuffer(footer)}
+	r := &footerReader{ctx: context.Background(), data: footer, buf: buf, proto: thrift.NewTCompactProtocol(buf)}
+	changed := false
+	err := r.structFields(func(id int16, typ thrift.TType) (bool, error) {
+		if id != fileMetaDataRowGroups || typ != thrift.LIST {
+			return false, nil
+		}
+		return true, r.listStructs(func() error {
+			return r.structFields(func(id int16, typ thrift.TType) (bool, error) {
+				if id != rowGroupColumns || typ != thrift.LIST {
+					return false, nil
+				}
+				return true, r.listStructs(func() error {
+					return r.structFields(func(id int16, typ thrift.TType) (bool, error) {
+						if id != columnChunkMetaData || typ != thrift.STRUCT {
+							return false, nil
+						}
+						return true, r.structFields(func(id int16, typ thrift.TType) (bool, error) {
+							if id != columnMetaDataEncodingStats || typ != thrift.LIST {
+								return false, nil
+							}
+							sorted, err := r.sortList()
+							changed = changed || sorted
+							return true, err
+						})
+					})
+				})
+			})
+		})
+	})
+	return changed, err
+}
+
+// structFields reads a struct, calling fn for each field. fn reports whether it
+// read the field's value; the values it leaves are skipped.
+func (r *footerReader) structFields(fn func(id int16, typ thrift.TType) (bool, error)) error {
+	if _, err := r.proto.ReadStructBegin(r.ctx); err != nil {
+		return err
+	}
+	for {
+		_, typ, id, err := r.proto.ReadFieldBegin(r.ctx)
+		if err != nil {
+			return err
+		}
+		if typ == thrift.STOP {
+			break
+		}
+		read, err := fn(id, typ)
+		if err != nil {
+			return err
+		}
+		if !read {
+			if err := r.proto.Skip(r.ctx, typ); err != nil {
+				return err
+			}
+		}
+		if err := r.proto.ReadFieldEnd(r.ctx); err != nil {
+			return err
+		}
+	}
+	return r.proto.ReadStructEnd(r.ctx)
+}
+
+// listStructs reads a list of structs, calling fn to read each of them.
+func (r *footerReader) listStructs(fn func() error) error {
+	typ, n, err := r.proto.ReadListBegin(r.ctx)
+	if err != nil {
+		return err
+	}
+	if typ != thrift.STRUCT {
+		return fmt.Errorf("unexpected list of %s", typ)
+	}
+	for i := 0; i < n; i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return r.proto.ReadListEnd(r.ctx)
+}
+
+// sortList reads a list of structs and sorts its encoded elements in place.
+func (r *footerReader) sortList() (bool, error) {
+	var elems [][]byte
+	start := -1
+	err := r.listStructs(func() error {
+		from := r.pos()
+		if start < 0 {
+			start = from
+		}
+		if err := r.proto.Skip(r.ctx, thrift.STRUCT); err != nil {
+			return err
+		}
+		elems = append(elems, slices.Clone(r.data[from:r.pos()]))
+		return nil
+	})
+	if err != nil || slices.IsSortedFunc(elems, bytes.Compare) {
+		return false, err
+	}
+	slices.SortFunc(elems, bytes.Compare)
+	copy(r.data[start:], bytes.Join(elems, nil))
+	return true, nil
+}
@@ -0,0 +1,102 @@
+package formats
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+)
+
+// ParquetOptions are the writer properties of Parquet files, applied to the
+// files of every table.
+type ParquetOptions struct {
+	Codec string `json:"codec"`
+	// CompressionLevel is the level of gzip (1-9) or zstd (1-22); 0 uses the
+	// codec's default.
+	CompressionLevel int `json:"compression_level,omitempty"`
+	// RowGroupBytes is the size a row group is closed at once it reaches it;
+	// 0 writes every batch of 64K rows as a row group of its own.
+	RowGroupBytes int64 `json:"row_group_bytes,omitempty"`
+	PageBytes     int64 `json:"page_bytes"`
+	// Dictionary turns dictionary encoding on or off for every column; nil
+	// keeps the writer's default, on for dimension tables and off for facts.
+	Dictionary *bool  `json:"dictionary,omitempty"`
+	Statistics bool   `json:"statistics"`
+	Version    string `json:"version"`
+}
+
+// DefaultParquetOptions are Snappy-compressed Parquet 2.6 files with 1 MB pages
+// and column statistics.
+var DefaultParquetOptions = ParquetOptions{
+	Codec:      "snappy",
+	PageBytes:  parquet.DefaultDataPageSize,
+	Statistics: true,
+	Version:    "2.6",
+}
+
+// ParquetCodecs are the compression codecs of Parquet column chunks.
+var ParquetCodecs = []string{"zstd", "gzip", "lz4", "snappy", "none"}
+
+var parquetCodecs = map[string]compress.Compression{
+	"zstd":   compress.Codecs.Zstd,
+	"gzip":   compress.Codecs.Gzip,
+	"lz4":    compress.Codecs.Lz4Raw,
+	"snappy": compress.Codecs.Snappy,
+	"none":   compress.Codecs.Uncompressed,
+}
+
+// ParquetVersions are the Parquet format versions files can be written in.
+var ParquetVersions = []string{"1.0", "2.4", "2.6"}
+
+var parquetVersions = map[string]parquet.Version{
+	"1.0": parquet.V1_0,
+	"2.4": parquet.V2_4,
+	"2.6": parquet.V2_6,
+}
+
+// Check returns an error unless Parquet files can be written with o.
+func (o ParquetOptions) Check() error {
+	if _, ok := parquetCodecs[o.Codec]; !ok {
+		return fmt.Errorf("unsupported Parquet codec: %s (choose %s)", o.Codec, strings.Join(ParquetCodecs, ", "))
+	}
+	switch {
+	case o.CompressionLevel == 0:
+	case o.Codec == "gzip" && (o.CompressionLevel < 1 || o.CompressionLevel > 9):
+		return fmt.Errorf("invalid gzip compression level %d: it must be between 1 and 9", o.CompressionLevel)
+	case o.Codec == "zstd" && (o.CompressionLevel < 1 || o.CompressionLevel > 22):
+		return fmt.Errorf("invalid zstd compression level %d: it must be between 1 and 22", o.CompressionLevel)
+	case o.Codec != "gzip" && o.Codec != "zstd":
+		return fmt.Errorf("the %s Parquet codec has no compression levels", o.Codec)
+	}
+	if o.RowGroupBytes < 0 {
+		return fmt.Errorf("invalid Parquet row group size %d: it cannot be negative", o.RowGroupBytes)
+	}
+	if o.PageBytes <= 0 {
+		return fmt.Errorf("invalid Parquet page size %d: it must be positive", o.PageBytes)
+	}
+	if _, ok := parquetVersions[o.Version]; !ok {
+		return fmt.Errorf("unsupported Parquet version: %s (choose %s)", o.Version, strings.Join(ParquetVersions, ", "))
+	}
+	return nil
+}
+
+// writerProperties returns the writer properties of o. dictionary is the
+// writer's default for dictionary encoding.
+func (o ParquetOptions) writerProperties(dictionary bool) *parquet.WriterProperties {
+	if o.Dictionary != nil {
+		dictionary = *o.Dictionary
+	}
+	level := compress.DefaultCompressionLevel
+	if o.CompressionLevel != 0 {
+		level = o.CompressionLevel
+	}
+	return parquet.NewWriterProperties(
+		parquet.WithCompression(parquetCodecs[o.Codec]),
+		parquet.WithCompressionLevel(level),
+		parquet.WithDictionaryDefault(dictionary),
+		parquet.WithDataPageSize(o.PageBytes),
+		parquet.WithStats(o.Statistics),
+		parquet.WithVersion(parquetVersions[o.Version]),
+	)
+}
@@ -2,7 +2,6 @@ package formats
 
 import (
 	"fmt"
-	"os"
 
 	ecommercemodels "github.com/peekknuf/Gengo/internal/models/ecommerce"
 	ecommerceds "github.com/peekknuf/Gengo/internal/models/ecommerce-ds"
@@ -12,42 +11,33 @@ import (
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
-	"github.com/apache/arrow-go/v18/parquet"
-	"github.com/apache/arrow-go/v18/parquet/compress"
-	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 )
 
 const typedWriteBatchSize = 65536
 
-func CreateTypedParquetWriter(schema *arrow.Schema, targetFilename string) (*os.File, *pqarrow.FileWriter, *array.RecordBuilder, error) {
-	file, err := os.Create(targetFilename)
+// CreateRecordWriter opens a writer of record batches in the format of opts,
+// Parquet, Arrow IPC or Avro, on the temporary file of targetFilename. Closing
+// the writer closes the file; the caller then publishes it with PublishFile.
+func CreateRecordWriter(schema *arrow.Schema, targetFilename string, opts Options) (*OutputFile, RecordWriter, *array.RecordBuilder, error) {
+	schema = opts.tableSchema(schema)
+	file, writer, err := openRecordWriter(schema, targetFilename, false, opts)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create parquet file %s: %w", targetFilename, err)
+		return nil, nil, nil, err
 	}
-
-	pool := memory.NewGoAllocator()
-	props := parquet.NewWriterProperties(parquet.WithDictionaryDefault(false), parquet.WithCompression(compress.Codecs.Snappy))
-	arrowProps := pqarrow.NewArrowWriterProperties()
-	writer, err := pqarrow.NewFileWriter(schema, file, props, arrowProps)
-	if err != nil {
-		file.Close()
-		return nil, nil, nil, fmt.Errorf("failed to create parquet writer for %s: %w", targetFilename, err)
-	}
-
-	builder := array.NewRecordBuilder(pool, schema)
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
 	return file, writer, builder, nil
 }
 
-func WriteTypedBatch(writer *pqarrow.FileWriter, builder *array.RecordBuilder, targetFilename string) error {
+func WriteTypedBatch(writer RecordWriter, builder *array.RecordBuilder, targetFilename string) error {
 	record := builder.NewRecord()
 	defer record.Release()
 	if err := writer.Write(record); err != nil {
-		return fmt.Errorf("error writing batch to parquet file %s: %w", targetFilename, err)
+		return fmt.Errorf("error writing batch to %s: %w", targetFilename, err)
 	}
 	return nil
 }
 
-func WriteOrderHeadersToParquetTyped(headers []ecommercemodels.OrderHeader, targetFilename string) (err error) {
+func WriteOrderHeaderRecordsTyped(headers []ecommercemodels.OrderHeader, targetFilename string, opts Options) (err error) {
 	if len(headers) == 0 {
 		return nil
 	}
@@ -61,10 +51,11 @@ func WriteOrderHeadersToParquetTyped(headers []ecommercemodels.OrderHeader, targ
 		{Name: "order_status", Type: arrow.BinaryTypes.String, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -84,7 +75,7 @@ func WriteOrderHeadersToParquetTyped(headers []ecommercemodels.OrderHeader, targ
 	b4 := builder.Field(4).(*array.TimestampBuilder)
 	b5 := builder.Field(5).(*array.StringBuilder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(headers), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(headers), targetFilename, opts.Format)
 
 	for i, h := range headers {
 		b0.Append(int32(h.OrderID))
@@ -111,7 +102,7 @@ func WriteOrderHeadersToParquetTyped(headers []ecommercemodels.OrderHeader, targ
 	return nil
 }
 
-func WriteOrderItemsToParquetTyped(items []ecommercemodels.OrderItem, targetFilename string) (err error) {
+func WriteOrderItemRecordsTyped(items []ecommercemodels.OrderItem, targetFilename string, opts Options) (err error) {
 	if len(items) == 0 {
 		return nil
 	}
@@ -125,10 +116,11 @@ func WriteOrderItemsToParquetTyped(items []ecommercemodels.OrderItem, targetFile
 		{Name: "discount", Type: arrow.PrimitiveTypes.Float64, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -148,7 +140,7 @@ func WriteOrderItemsToParquetTyped(items []ecommercemodels.OrderItem, targetFile
 	b4 := builder.Field(4).(*array.Float64Builder)
 	b5 := builder.Field(5).(*array.Float64Builder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(items), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(items), targetFilename, opts.Format)
 
 	for i, item := range items {
 		b0.Append(int32(item.OrderItemID))
@@ -175,7 +167,7 @@ func WriteOrderItemsToParquetTyped(items []ecommercemodels.OrderItem, targetFile
 	return nil
 }
 
-func WriteStoreSalesToParquetTyped(rows []ecommerceds.StoreSales, targetFilename string) (err error) {
+func WriteStoreSalesRecordsTyped(rows []ecommerceds.StoreSales, targetFilename string, opts Options) (err error) {
 	if len(rows) == 0 {
 		return nil
 	}
@@ -206,10 +198,11 @@ func WriteStoreSalesToParquetTyped(rows []ecommerceds.StoreSales, targetFilename
 		{Name: "ss_net_profit", Type: arrow.PrimitiveTypes.Float64, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -246,7 +239,7 @@ func WriteStoreSalesToParquetTyped(rows []ecommerceds.StoreSales, targetFilename
 	b21 := builder.Field(21).(*array.Float64Builder)
 	b22 := builder.Field(22).(*array.Float64Builder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(rows), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(rows), targetFilename, opts.Format)
 
 	for i, r := range rows {
 		b0.Append(r.SS_SoldDateSK)
@@ -290,7 +283,7 @@ func WriteStoreSalesToParquetTyped(rows []ecommerceds.StoreSales, targetFilename
 	return nil
 }
 
-func WriteCatalogSalesToParquetTyped(rows []ecommerceds.CatalogSales, targetFilename string) (err error) {
+func WriteCatalogSalesRecordsTyped(rows []ecommerceds.CatalogSales, targetFilename string, opts Options) (err error) {
 	if len(rows) == 0 {
 		return nil
 	}
@@ -332,10 +325,11 @@ func WriteCatalogSalesToParquetTyped(rows []ecommerceds.CatalogSales, targetFile
 		{Name: "cs_net_profit", Type: arrow.PrimitiveTypes.Float64, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -383,7 +377,7 @@ func WriteCatalogSalesToParquetTyped(rows []ecommerceds.CatalogSales, targetFile
 	b32 := builder.Field(32).(*array.Float64Builder)
 	b33 := builder.Field(33).(*array.Float64Builder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(rows), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(rows), targetFilename, opts.Format)
 
 	for i, r := range rows {
 		b0.Append(r.CS_SoldDateSK)
@@ -438,7 +432,7 @@ func WriteCatalogSalesToParquetTyped(rows []ecommerceds.CatalogSales, targetFile
 	return nil
 }
 
-func WriteWebSalesToParquetTyped(rows []ecommerceds.WebSales, targetFilename string) (err error) {
+func WriteWebSalesRecordsTyped(rows []ecommerceds.WebSales, targetFilename string, opts Options) (err error) {
 	if len(rows) == 0 {
 		return nil
 	}
@@ -480,10 +474,11 @@ func WriteWebSalesToParquetTyped(rows []ecommerceds.WebSales, targetFilename str
 		{Name: "ws_net_profit", Type: arrow.PrimitiveTypes.Float64, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -531,7 +526,7 @@ func WriteWebSalesToParquetTyped(rows []ecommerceds.WebSales, targetFilename str
 	b32 := builder.Field(32).(*array.Float64Builder)
 	b33 := builder.Field(33).(*array.Float64Builder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(rows), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(rows), targetFilename, opts.Format)
 
 	for i, r := range rows {
 		b0.Append(r.WS_SoldDateSK)
@@ -586,7 +581,7 @@ func WriteWebSalesToParquetTyped(rows []ecommerceds.WebSales, targetFilename str
 	return nil
 }
 
-func WriteDailyStockPricesToParquetTyped(prices []financialmodels.DailyStockPrice, targetFilename string) (err error) {
+func WriteDailyStockPriceRecordsTyped(prices []financialmodels.DailyStockPrice, targetFilename string, opts Options) (err error) {
 	if len(prices) == 0 {
 		return nil
 	}
@@ -603,10 +598,11 @@ func WriteDailyStockPricesToParquetTyped(prices []financialmodels.DailyStockPric
 		{Name: "volume", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -629,7 +625,7 @@ func WriteDailyStockPricesToParquetTyped(prices []financialmodels.DailyStockPric
 	b7 := builder.Field(7).(*array.Float64Builder)
 	b8 := builder.Field(8).(*array.Int32Builder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(prices), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(prices), targetFilename, opts.Format)
 
 	for i, p := range prices {
 		b0.Append(p.PriceID)
@@ -659,7 +655,7 @@ func WriteDailyStockPricesToParquetTyped(prices []financialmodels.DailyStockPric
 	return nil
 }
 
-func WriteAppointmentsToParquetTyped(appts []medicalmodels.Appointment, targetFilename string) (err error) {
+func WriteAppointmentRecordsTyped(appts []medicalmodels.Appointment, targetFilename string, opts Options) (err error) {
 	if len(appts) == 0 {
 		return nil
 	}
@@ -673,10 +669,11 @@ func WriteAppointmentsToParquetTyped(appts []medicalmodels.Appointment, targetFi
 		{Name: "diagnosis", Type: arrow.BinaryTypes.String, Nullable: false},
 	}, nil)
 
-	file, writer, builder, err := CreateTypedParquetWriter(schema, targetFilename)
+	file, writer, builder, err := CreateRecordWriter(schema, targetFilename, opts)
 	if err != nil {
 		return err
 	}
+	defer opts.PublishFile(targetFilename, &err)
 	defer func() {
 		if err != nil && file != nil {
 			_ = file.Close()
@@ -696,7 +693,7 @@ func WriteAppointmentsToParquetTyped(appts []medicalmodels.Appointment, targetFi
 	b4 := builder.Field(4).(*array.TimestampBuilder)
 	b5 := builder.Field(5).(*array.StringBuilder)
 
-	fmt.Printf("Writing %d records to %s (Parquet)...\n", len(appts), targetFilename)
+	fmt.Printf("Writing %d records to %s (%s)...\n", len(appts), targetFilename, opts.Format)
 
 	for i, a := range appts {
 		b0.Append(a.AppointmentID)
@@ -0,0 +1,124 @@
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Partitioning splits the files of fact tables into Hive-style partitions, e.g.
+// fact_store_sales/year=2001/month=04/part-00003.parquet.
+type Partitioning struct {
+	// Keys are the partition keys in directory order. year, month and day are
+	// taken from the date of a row, the first column ending in date_sk, which
+	// holds a Julian day number. Any other key names a column, with or without
+	// the prefix of the table's columns: store_sk partitions fact_store_sales
+	// by ss_store_sk.
+	Keys []string `json:"keys"`
+	// MaxOpen is the number of partition files a shard keeps open. Rows of the
+	// other partitions are held back, spilling to disk, and written when the
+	// shard is done, so a shard still writes one file per partition.
+	MaxOpen int `json:"max_open"`
+}
+
+// DefaultMaxOpenPartitions is the number of partition files a shard keeps open
+// unless told otherwise.
+const DefaultMaxOpenPartitions = 64
+
+// ParsePartitionKeys parses a comma-separated list of partition keys.
+func ParsePartitionKeys(s string) []string {
+	var keys []string
+	for _, key := range strings.Split(s, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Check returns an error unless p can name partition directories.
+func (p Partitioning) Check() error {
+	seen := make(map[string]bool, len(p.Keys))
+	for _, key := range p.Keys {
+		for _, r := range key {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+				return fmt.Errorf("invalid partition key %q: use lower-case letters, digits and underscores", key)
+			}
+		}
+		if seen[key] {
+			return fmt.Errorf("partition key %s is given twice", key)
+		}
+		seen[key] = true
+	}
+	if p.MaxOpen < 1 {
+		return fmt.Errorf("invalid number of open partition files %d: it must be at least 1", p.MaxOpen)
+	}
+	return nil
+}
+
+// Columns returns the position in columns of the column each partition key is
+// taken from. ok is false if the table has no column for one of the keys, or
+// if fact tables are not partitioned; such tables are written unpartitioned.
+func (p Partitioning) Columns(columns []string) (positions []int, ok bool) {
+	if len(p.Keys) == 0 {
+		return nil, false
+	}
+	positions = make([]int, len(p.Keys))
+	for i, key := range p.Keys {
+		positions[i] = -1
+		for j, column := range columns {
+			var match bool
+			switch key {
+			case "year", "month", "day":
+				match = strings.HasSuffix(column, "date_sk")
+			default:
+				match = column == key || strings.HasSuffix(column, "_"+key)
+			}
+			if match {
+				positions[i] = j
+				break
+			}
+		}
+		if positions[i] < 0 {
+			return nil, false
+		}
+	}
+	return positions, true
+}
+
+// AppendPath appends the partition directories of a row to buf, e.g.
+// year=2001/month=04/, given the values of the columns of the keys.
+func (p Partitioning) AppendPath(buf []byte, values []int64) []byte {
+	for i, key := range p.Keys {
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		switch key {
+		case "year":
+			buf = strconv.AppendInt(buf, int64(JulianDate(values[i]).Year()), 10)
+		case "month":
+			buf = appendTwoDigits(buf, int(JulianDate(values[i]).Month()))
+		case "day":
+			buf = appendTwoDigits(buf, JulianDate(values[i]).Day())
+		default:
+			buf = strconv.AppendInt(buf, values[i], 10)
+		}
+		buf = append(buf, '/')
+	}
+	return buf
+}
+
+// julianUnixEpoch is the Julian day number of 1970-01-01.
+const julianUnixEpoch = 2440588
+
+// JulianDate returns the date of a Julian day number.
+func JulianDate(day int64) time.Time {
+	return time.Unix((day-julianUnixEpoch)*24*60*60, 0).UTC()
+}
+
+func appendTwoDigits(buf []byte, v int) []byte {
+	if v < 10 {
+		buf = append(buf, '0')
+	}
+	return strconv.AppendInt(buf, int64(v), 10)
+}
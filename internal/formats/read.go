@@ -0,0 +1,250 @@
+package formats
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// ReadColumns calls fn with the values of columns for every row of the output
+// file filename and returns the number of rows read. Values are passed as text;
+// NULLs, empty CSV fields and missing JSON keys are passed as "". Parquet files
+// only decode the requested columns. CSV files are read in dialect d, and
+// compressed text files are decompressed as they are read.
+// Files without a header line, dsdgen files and CSV in a dialect without one,
+// take the names of their columns from header. fn must not keep row.
+func ReadColumns(ctx context.Context, filename string, d CSVDialect, header, columns []string, fn func(row []string) error) (int64, error) {
+	switch DataExtension(filename) {
+	case ".parquet":
+		return readParquetColumns(ctx, filename, columns, fn)
+	case ".arrow", ".arrows":
+		return readArrowColumns(ctx, filename, columns, fn)
+	case ".avro":
+		return readAvroColumns(ctx, filename, columns, fn)
+	case ".jsonl":
+		return readJSONColumns(filename, columns, fn)
+	case ".dat":
+		return readDelimitedColumns(filename, dsdgenDialect, true, header, columns, fn)
+	default:
+		if d.Quoting == "none" {
+			return readDelimitedColumns(filename, d, false, header, columns, fn)
+		}
+		return readCSVColumns(filename, d, header, columns, fn)
+	}
+}
+
+func readCSVColumns(filename string, d CSVDialect, header, columns []string, fn func(row []string) error) (int64, error) {
+	f, err := OpenTextFile(filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReaderSize(f, 1024*1024))
+	r.Comma = rune(d.Delimiter[0])
+	r.ReuseRecord = true
+	if d.Header {
+		header, err = r.Read()
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the header of %s: %w", filename, err)
+		}
+	}
+	fields, err := columnPositions(filename, header, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	row := make([]string, len(columns))
+	var rows int64
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		for i, pos := range fields {
+			row[i] = d.value(record[pos])
+		}
+		if err := fn(row); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+}
+
+// readDelimitedColumns reads a file whose fields are never quoted, in dialect
+// d. If trailing is set, every field is followed by the delimiter, as dsdgen
+// writes them.
+func readDelimitedColumns(filename string, d CSVDialect, trailing bool, header, columns []string, fn func(row []string) error) (int64, error) {
+	f, err := OpenTextFile(filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+	if d.Header {
+		if !scanner.Scan() {
+			return 0, scanner.Err()
+		}
+		header = strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), d.Delimiter)
+	}
+	fields, err := columnPositions(filename, header, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	want := len(header)
+	if trailing {
+		want++
+	}
+	record := make([]string, 0, want)
+	row := make([]string, len(columns))
+	var rows int64
+	for scanner.Scan() {
+		record = append(record[:0], strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), d.Delimiter)...)
+		if len(record) != want || trailing && record[want-1] != "" {
+			return rows, fmt.Errorf("row %d of %s has %d fields, expected %d", rows+1, filename, len(record)-want+len(header), len(header))
+		}
+		for i, pos := range fields {
+			row[i] = d.value(record[pos])
+		}
+		if err := fn(row); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	if err := scanner.Err(); err != nil {
+		return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return rows, nil
+}
+
+// columnPositions returns the position of each of columns in header.
+func columnPositions(filename string, header, columns []string) ([]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	fields := make([]int, len(columns))
+	for i, column := range columns {
+		pos, ok := index[column]
+		if !ok {
+			return nil, fmt.Errorf("%s has no column %s", filename, column)
+		}
+		fields[i] = pos
+	}
+	return fields, nil
+}
+
+func readJSONColumns(filename string, columns []string, fn func(row []string) error) (int64, error) {
+	f, err := OpenTextFile(filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+	row := make([]string, len(columns))
+	var rows int64
+	for scanner.Scan() {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &object); err != nil {
+			return rows, fmt.Errorf("failed to parse row %d of %s: %w", rows+1, filename, err)
+		}
+		for i, column := range columns {
+			row[i] = jsonText(object[column])
+		}
+		if err := fn(row); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	if err := scanner.Err(); err != nil {
+		return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return rows, nil
+}
+
+// jsonText returns a JSON value as text: strings unquoted, null as "".
+func jsonText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	if raw[0] == '"' {
+		if s, err := strconv.Unquote(string(raw)); err == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+func readParquetColumns(ctx context.Context, filename string, columns []string, fn func(row []string) error) (int64, error) {
+	rdr, err := file.OpenParquetFile(filename, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer rdr.Close()
+	fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{BatchSize: parquetWriteBatchSize}, memory.DefaultAllocator)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	schema, err := fr.Schema()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the schema of %s: %w", filename, err)
+	}
+	indices := make([]int, len(columns))
+	for i, column := range columns {
+		fields := schema.FieldIndices(column)
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("%s has no column %s", filename, column)
+		}
+		indices[i] = fields[0]
+	}
+
+	rr, err := fr.GetRecordReader(ctx, indices, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	defer rr.Release()
+
+	row := make([]string, len(columns))
+	var rows int64
+	for rr.Next() {
+		rec := rr.Record()
+		for j := 0; j < int(rec.NumRows()); j++ {
+			for i := range columns {
+				col := rec.Column(i)
+				if col.IsNull(j) {
+					row[i] = ""
+				} else {
+					row[i] = col.ValueStr(j)
+				}
+			}
+			if err := fn(row); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+	if err := rr.Err(); err != nil && err != io.EOF {
+		return rows, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return rows, nil
+}
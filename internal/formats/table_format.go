@@ -0,0 +1,95 @@
+package formats
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// TableFormats are the output formats that write every table as a directory
+// of its own: Parquet data files under data/ and the metadata of the table
+// format next to them, e.g. dim_items/data/dim_items.parquet and
+// dim_items/metadata/v1.metadata.json for Iceberg or
+// dim_items/_delta_log/00000000000000000000.json for Delta Lake.
+var TableFormats = []string{"iceberg", "delta"}
+
+// DataFormat returns the format the data files of format are written in:
+// parquet for the table formats, format itself otherwise.
+func DataFormat(format string) string {
+	for _, f := range TableFormats {
+		if f == format {
+			return "parquet"
+		}
+	}
+	return format
+}
+
+// TablePath returns the path of the output file called name in outputDir,
+// without extension: outputDir/name, or, in a table format, the data
+// directory of the table the file belongs to, e.g.
+// outputDir/fact_store_sales/data/fact_store_sales_3 for fact_store_sales_3.
+// dsdgen files are named after their TPC-DS table.
+func (o Options) TablePath(outputDir, name string) string {
+	file := TableFileName(name, o.Format)
+	if o.TableFormat == "" {
+		return filepath.Join(outputDir, file)
+	}
+	return filepath.Join(outputDir, TableOfFile(name), "data", file)
+}
+
+// ShardPath returns the path of the output file of shard, numbered from 0, of
+// the shards files table is written in: outputDir/table_<shard> with the
+// extension of o. dsdgen files are named the way dsdgen names the files of a
+// parallel run, e.g. store_sales_4_8.dat for the fourth of eight, or
+// store_sales.dat for a single file.
+func (o Options) ShardPath(outputDir, table string, shard, shards int) string {
+	path := o.TablePath(outputDir, table)
+	switch {
+	case o.Format != "dsdgen":
+		path = fmt.Sprintf("%s_%d", path, shard)
+	case shards > 1:
+		path = fmt.Sprintf("%s_%d_%d", path, shard+1, shards)
+	}
+	return path + o.FileExtension()
+}
+
+// tableCommitTime is the time the metadata of a table format records for the
+// commit of a table. Like its IDs, which are drawn from the run seed, it does
+// not depend on when the run happens, so runs with the same seed write the
+// same metadata.
+var tableCommitTime = time.Unix(0, 0)
+
+// CommitTable writes the metadata of the table format for the table in dir,
+// whose data files are files, with the IDs of the metadata drawn from seed.
+// Without a table format there is nothing to do.
+func (o Options) CommitTable(dir string, files []string, seed int64) error {
+	switch o.TableFormat {
+	case "iceberg":
+		return commitIcebergTable(dir, files, seed, o.Parquet.Codec)
+	case "delta":
+		return commitDeltaTable(dir, files, seed)
+	}
+	return nil
+}
+
+// parquetFieldIDKey is the Arrow field metadata key pqarrow writes as the
+// field ID of a Parquet column.
+const parquetFieldIDKey = "PARQUET:field_id"
+
+// tableSchema returns the schema Parquet files are written with: schema, with
+// the field IDs Iceberg maps columns by, 1 to n in column order, if the run
+// writes Iceberg tables.
+func (o Options) tableSchema(schema *arrow.Schema) *arrow.Schema {
+	if o.TableFormat != "iceberg" {
+		return schema
+	}
+	fields := schema.Fields()
+	for i := range fields {
+		fields[i].Metadata = arrow.NewMetadata([]string{parquetFieldIDKey}, []string{strconv.Itoa(i + 1)})
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
@@ -0,0 +1,416 @@
+// Package custom reads data models declared in a YAML model file: their tables,
+// columns, column generators, keys and size ratios.
+package custom
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	gf "github.com/brianvoe/gofakeit/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// Model is a data model declared in a model file.
+type Model struct {
+	Name   string   `yaml:"name"`
+	Tables []*Table `yaml:"tables"`
+
+	// Path is the model file the model was read from.
+	Path string `yaml:"-"`
+}
+
+// Table is a table of a custom model. Its size is either a fixed number of
+// Rows or a Ratio: tables with a ratio share the target size so that their row
+// counts keep the ratios between them.
+type Table struct {
+	Name    string    `yaml:"name"`
+	Rows    int       `yaml:"rows"`
+	Ratio   float64   `yaml:"ratio"`
+	Columns []*Column `yaml:"columns"`
+
+	rowType reflect.Type
+}
+
+// Column is a column of a custom table and the generator of its values. Every
+// column has exactly one generator:
+//
+//   - primary_key: sequential ids from 1
+//   - references: ids of the referenced table, sampled with the popularity skew
+//     of the built-in models, or uniformly with distribution "uniform"
+//   - faker: a gofakeit function such as name, email or city, with params
+//   - template: a gofakeit template such as "{firstname}.{lastname}@example.com"
+//   - enum: one of values, optionally weighted
+//   - range: [min, max] of a number or timestamp, drawn with a uniform, normal
+//     or exponential distribution
+//   - probability: the chance of true for a bool
+type Column struct {
+	Name         string            `yaml:"name"`
+	Type         string            `yaml:"type"`
+	PrimaryKey   bool              `yaml:"primary_key"`
+	References   string            `yaml:"references"`
+	Faker        string            `yaml:"faker"`
+	Params       map[string]string `yaml:"params"`
+	Template     string            `yaml:"template"`
+	Enum         []string          `yaml:"enum"`
+	Weights      []float64         `yaml:"weights"`
+	Range        []string          `yaml:"range"`
+	Distribution string            `yaml:"distribution"`
+	Decimals     *int              `yaml:"decimals"`
+	Probability  *float64          `yaml:"probability"`
+
+	// Min and Max are the parsed range; timestamps are Unix seconds.
+	Min, Max float64 `yaml:"-"`
+}
+
+// Column types and the Go types their values are generated as.
+var columnTypes = map[string]reflect.Type{
+	"int32":     reflect.TypeOf(int32(0)),
+	"int64":     reflect.TypeOf(int64(0)),
+	"float64":   reflect.TypeOf(float64(0)),
+	"string":    reflect.TypeOf(""),
+	"bool":      reflect.TypeOf(false),
+	"timestamp": reflect.TypeOf(time.Time{}),
+}
+
+var (
+	identifier = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	modelName  = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+)
+
+// Load reads and checks the model file at path.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading model file: %w", err)
+	}
+	var m Model
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("error parsing model file %s: %w", path, err)
+	}
+	m.Path = path
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("model file %s: %w", path, err)
+	}
+	if err := m.order(); err != nil {
+		return nil, fmt.Errorf("model file %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Table returns the table called name, or nil.
+func (m *Model) Table(name string) *Table {
+	for _, t := range m.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// PrimaryKey returns the primary key column of t, or nil.
+func (t *Table) PrimaryKey() *Column {
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			return c
+		}
+	}
+	return nil
+}
+
+// RowType returns the struct type of a row of t. Its fields are the columns of
+// t in order, tagged with their names for the writers.
+func (t *Table) RowType() reflect.Type {
+	return t.rowType
+}
+
+func (m *Model) check() error {
+	if !modelName.MatchString(m.Name) {
+		return fmt.Errorf("invalid model name %q: use lowercase letters, digits, - and _", m.Name)
+	}
+	if len(m.Tables) == 0 {
+		return fmt.Errorf("the model has no tables")
+	}
+	seen := make(map[string]bool)
+	ratios := false
+	for _, t := range m.Tables {
+		if !identifier.MatchString(t.Name) {
+			return fmt.Errorf("invalid table name %q", t.Name)
+		}
+		// Shard files are named table_<n>, so a trailing number would be
+		// read back as a shard.
+		if i := strings.LastIndexByte(t.Name, '_'); i >= 0 {
+			if _, err := strconv.Atoi(t.Name[i+1:]); err == nil {
+				return fmt.Errorf("table name %s must not end in _<number>", t.Name)
+			}
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("table %s is declared twice", t.Name)
+		}
+		seen[t.Name] = true
+		if (t.Rows > 0) == (t.Ratio > 0) {
+			return fmt.Errorf("table %s needs either rows or ratio", t.Name)
+		}
+		if t.Rows < 0 || t.Ratio < 0 {
+			return fmt.Errorf("table %s: rows and ratio must be positive", t.Name)
+		}
+		ratios = ratios || t.Ratio > 0
+	}
+	if !ratios {
+		return fmt.Errorf("at least one table needs a ratio so the model can be sized")
+	}
+
+	for _, t := range m.Tables {
+		if len(t.Columns) == 0 {
+			return fmt.Errorf("table %s has no columns", t.Name)
+		}
+		columns := make(map[string]bool)
+		keys := 0
+		for _, c := range t.Columns {
+			if !identifier.MatchString(c.Name) {
+				return fmt.Errorf("table %s: invalid column name %q", t.Name, c.Name)
+			}
+			if columns[c.Name] {
+				return fmt.Errorf("table %s: column %s is declared twice", t.Name, c.Name)
+			}
+			columns[c.Name] = true
+			if c.PrimaryKey {
+				keys++
+			}
+		}
+		if keys > 1 {
+			return fmt.Errorf("table %s has more than one primary key column", t.Name)
+		}
+		// References are resolved once every primary key type is known.
+		for _, c := range t.Columns {
+			if c.References == "" {
+				if err := c.check(); err != nil {
+					return fmt.Errorf("table %s: column %s: %w", t.Name, c.Name, err)
+				}
+			}
+		}
+	}
+	for _, t := range m.Tables {
+		for _, c := range t.Columns {
+			if c.References == "" {
+				continue
+			}
+			ref := m.Table(c.References)
+			if ref == nil {
+				return fmt.Errorf("table %s: column %s references unknown table %s", t.Name, c.Name, c.References)
+			}
+			if ref == t {
+				return fmt.Errorf("table %s: column %s references its own table", t.Name, c.Name)
+			}
+			key := ref.PrimaryKey()
+			if key == nil {
+				return fmt.Errorf("table %s: column %s references %s, which has no primary key", t.Name, c.Name, ref.Name)
+			}
+			if c.Type == "" {
+				c.Type = key.Type
+			}
+			if c.Type != key.Type {
+				return fmt.Errorf("table %s: column %s is %s but the primary key of %s is %s", t.Name, c.Name, c.Type, ref.Name, key.Type)
+			}
+			if err := c.check(); err != nil {
+				return fmt.Errorf("table %s: column %s: %w", t.Name, c.Name, err)
+			}
+		}
+	}
+
+	for _, t := range m.Tables {
+		fields := make([]reflect.StructField, len(t.Columns))
+		for i, c := range t.Columns {
+			fields[i] = reflect.StructField{
+				Name: fmt.Sprintf("F%d", i),
+				Type: columnTypes[c.Type],
+				Tag:  reflect.StructTag(fmt.Sprintf(`csv:"%s" json:"%s" parquet:"%s"`, c.Name, c.Name, c.Name)),
+			}
+		}
+		t.rowType = reflect.StructOf(fields)
+	}
+	return nil
+}
+
+// check fills in the default type of c and checks its generator.
+func (c *Column) check() error {
+	generators := 0
+	for _, set := range []bool{c.PrimaryKey, c.References != "", c.Faker != "", c.Template != "", len(c.Enum) > 0, len(c.Range) > 0, c.Probability != nil} {
+		if set {
+			generators++
+		}
+	}
+	if generators != 1 {
+		return fmt.Errorf("needs exactly one of primary_key, references, faker, template, enum, range or probability")
+	}
+
+	if c.Type == "" {
+		switch {
+		case c.PrimaryKey:
+			c.Type = "int64"
+		case c.Probability != nil:
+			c.Type = "bool"
+		case len(c.Range) > 0:
+			return fmt.Errorf("a range needs a type (int32, int64, float64 or timestamp)")
+		default:
+			c.Type = "string"
+		}
+	}
+	if _, ok := columnTypes[c.Type]; !ok {
+		return fmt.Errorf("unsupported type %s", c.Type)
+	}
+	if c.Distribution != "" && len(c.Range) == 0 && c.References == "" {
+		return fmt.Errorf("distribution only applies to range and references")
+	}
+	if c.Decimals != nil && (c.Type != "float64" || *c.Decimals < 0) {
+		return fmt.Errorf("decimals only applies to float64 columns")
+	}
+	if len(c.Weights) > 0 && len(c.Weights) != len(c.Enum) {
+		return fmt.Errorf("%d weights for %d enum values", len(c.Weights), len(c.Enum))
+	}
+	for _, w := range c.Weights {
+		if w < 0 {
+			return fmt.Errorf("weights must not be negative")
+		}
+	}
+	if len(c.Params) > 0 && c.Faker == "" {
+		return fmt.Errorf("params only apply to faker")
+	}
+
+	switch {
+	case c.PrimaryKey, c.References != "":
+		if c.Type != "int32" && c.Type != "int64" {
+			return fmt.Errorf("keys must be int32 or int64")
+		}
+		if c.References != "" {
+			switch c.Distribution {
+			case "", "weighted", "uniform":
+			default:
+				return fmt.Errorf("unsupported distribution %s for references (weighted, uniform)", c.Distribution)
+			}
+		}
+	case c.Faker != "", c.Template != "", len(c.Enum) > 0:
+		if c.Type != "string" {
+			return fmt.Errorf("faker, template and enum columns are strings")
+		}
+		if c.Faker != "" {
+			return c.checkFaker()
+		}
+	case c.Probability != nil:
+		if c.Type != "bool" {
+			return fmt.Errorf("probability only applies to bool columns")
+		}
+		if *c.Probability < 0 || *c.Probability > 1 {
+			return fmt.Errorf("probability must be between 0 and 1")
+		}
+	case len(c.Range) > 0:
+		return c.checkRange()
+	}
+	return nil
+}
+
+func (c *Column) checkFaker() error {
+	info := gf.GetFuncLookup(c.Faker)
+	if info == nil {
+		return fmt.Errorf("unknown faker function %s", c.Faker)
+	}
+	if _, err := info.Generate(rand.New(rand.NewSource(1)), c.FakerParams(), info); err != nil {
+		return fmt.Errorf("faker %s: %w", c.Faker, err)
+	}
+	return nil
+}
+
+// FakerParams returns the params of the faker function of c.
+func (c *Column) FakerParams() *gf.MapParams {
+	params := gf.NewMapParams()
+	for field, value := range c.Params {
+		params.Add(field, value)
+	}
+	return params
+}
+
+func (c *Column) checkRange() error {
+	if len(c.Range) != 2 {
+		return fmt.Errorf("range needs [min, max]")
+	}
+	var bounds [2]float64
+	for i, s := range c.Range {
+		var err error
+		switch c.Type {
+		case "int32", "int64":
+			var n int64
+			n, err = strconv.ParseInt(s, 10, 64)
+			bounds[i] = float64(n)
+		case "float64":
+			bounds[i], err = strconv.ParseFloat(s, 64)
+		case "timestamp":
+			var t time.Time
+			t, err = parseTime(s)
+			bounds[i] = float64(t.Unix())
+		default:
+			return fmt.Errorf("range does not apply to %s columns", c.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid range bound %q for a %s column", s, c.Type)
+		}
+	}
+	c.Min, c.Max = bounds[0], bounds[1]
+	if c.Min > c.Max {
+		return fmt.Errorf("range minimum is above its maximum")
+	}
+	switch c.Distribution {
+	case "", "uniform", "normal", "exponential":
+	default:
+		return fmt.Errorf("unsupported distribution %s (uniform, normal, exponential)", c.Distribution)
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// order sorts the tables so every table comes after the tables it references.
+func (m *Model) order() error {
+	ordered := make([]*Table, 0, len(m.Tables))
+	state := make(map[*Table]int) // 1 while visiting, 2 once placed
+	var visit func(t *Table, path []string) error
+	visit = func(t *Table, path []string) error {
+		switch state[t] {
+		case 1:
+			return fmt.Errorf("tables reference each other in a cycle: %s", strings.Join(append(path, t.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[t] = 1
+		for _, c := range t.Columns {
+			if c.References != "" {
+				if err := visit(m.Table(c.References), append(path, t.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[t] = 2
+		ordered = append(ordered, t)
+		return nil
+	}
+	for _, t := range m.Tables {
+		if err := visit(t, nil); err != nil {
+			return err
+		}
+	}
+	m.Tables = ordered
+	return nil
+}
@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/peekknuf/Gengo/internal/formats"
+)
+
+// Dialects are the SQL dialects DDL can write.
+var Dialects = []string{"postgres", "duckdb", "spark", "clickhouse"}
+
+// CheckDialect returns an error unless dialect is one of Dialects.
+func CheckDialect(dialect string) error {
+	for _, d := range Dialects {
+		if d == dialect {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported DDL dialect: %s (choose %s)", dialect, strings.Join(Dialects, ", "))
+}
+
+// DDL returns the CREATE TABLE statements of model's tables in dialect, with
+// the column types the tables have when written in format. Tables come in
+// dependency order. Postgres and DuckDB declare primary and foreign keys;
+// Spark and ClickHouse do not enforce them, so they are written as comments
+// and ClickHouse orders its MergeTree tables by the primary key. Tables are
+// named like their files, so dsdgen output gets the TPC-DS table names. Spark
+// reads CSV tables in the CSV dialect csv.
+func DDL(model, format, dialect string, csv formats.CSVDialect) (string, error) {
+	if err := CheckDialect(dialect); err != nil {
+		return "", err
+	}
+	tables, err := Tables(model)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema of the gengo %s model (%s output) for %s.\n", model, format, dialect)
+	for _, table := range tables {
+		columns, err := table.Columns(format)
+		if err != nil {
+			return "", fmt.Errorf("error reading the columns of %s: %w", table.Name, err)
+		}
+
+		b.WriteString("\n")
+		if dialect == "spark" || dialect == "clickhouse" {
+			if dialect == "spark" && len(table.PrimaryKey) > 0 {
+				fmt.Fprintf(&b, "-- PRIMARY KEY (%s)\n", strings.Join(table.PrimaryKey, ", "))
+			}
+			for _, ref := range table.ForeignKeys {
+				fmt.Fprintf(&b, "-- %s\n", foreignKey(ref, tables, format))
+			}
+		}
+
+		lines := make([]string, 0, columns.NumFields()+1+len(table.ForeignKeys))
+		for _, field := range columns.Fields() {
+			typ, err := sqlType(dialect, field.Type)
+			if err != nil {
+				return "", fmt.Errorf("column %s.%s: %w", table.Name, field.Name, err)
+			}
+			lines = append(lines, fmt.Sprintf("    %s %s", field.Name, typ))
+		}
+		if dialect == "postgres" || dialect == "duckdb" {
+			if len(table.PrimaryKey) > 0 {
+				lines = append(lines, fmt.Sprintf("    PRIMARY KEY (%s)", strings.Join(table.PrimaryKey, ", ")))
+			}
+			for _, ref := range table.ForeignKeys {
+				lines = append(lines, "    "+foreignKey(ref, tables, format))
+			}
+		}
+		fmt.Fprintf(&b, "CREATE TABLE %s (\n%s\n)", formats.TableFileName(table.Name, format), strings.Join(lines, ",\n"))
+
+		switch dialect {
+		case "spark":
+			b.WriteString(sparkSource(format, csv))
+		case "clickhouse":
+			orderBy := "tuple()"
+			if len(table.PrimaryKey) > 0 {
+				orderBy = "(" + strings.Join(table.PrimaryKey, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "\nENGINE = MergeTree\nORDER BY %s", orderBy)
+		}
+		b.WriteString(";\n")
+	}
+	return b.String(), nil
+}
+
+func foreignKey(ref ForeignKey, tables []Table, format string) string {
+	var columns []string
+	for _, table := range tables {
+		if table.Name == ref.References {
+			columns = table.PrimaryKey
+		}
+	}
+	return fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		strings.Join(ref.Columns, ", "), formats.TableFileName(ref.References, format), strings.Join(columns, ", "))
+}
+
+func sparkSource(format string, d formats.CSVDialect) string {
+	switch format {
+	case "parquet":
+		return " USING PARQUET"
+	case "iceberg":
+		return " USING iceberg"
+	case "delta":
+		return " USING DELTA"
+	case "json":
+		return " USING JSON"
+	case "avro":
+		return " USING AVRO"
+	case "arrow":
+		// Spark has no Arrow IPC source; the table gets its default format.
+		return ""
+	case "dsdgen":
+		return " USING CSV OPTIONS (sep '|')"
+	default:
+		options := []string{fmt.Sprintf("header '%t'", d.Header)}
+		if d.Delimiter != "," {
+			options = append(options, "sep "+sparkString(d.Delimiter))
+		}
+		if d.Null != "" {
+			options = append(options, "nullValue "+sparkString(d.Null))
+		}
+		if d.Quoting == "none" {
+			options = append(options, "quote ''")
+		}
+		return " USING CSV OPTIONS (" + strings.Join(options, ", ") + ")"
+	}
+}
+
+// sparkString quotes s as a Spark SQL string literal.
+func sparkString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\t", `\t`).Replace(s) + "'"
+}
+
+// sqlType maps a column type to its SQL type in dialect. Timestamps are written
+// in UTC.
+func sqlType(dialect string, typ arrow.DataType) (string, error) {
+	var types [6]string
+	switch dialect {
+	case "postgres":
+		types = [...]string{"INTEGER", "BIGINT", "REAL", "DOUBLE PRECISION", "TEXT", "BOOLEAN"}
+	case "duckdb":
+		types = [...]string{"INTEGER", "BIGINT", "FLOAT", "DOUBLE", "VARCHAR", "BOOLEAN"}
+	case "spark":
+		types = [...]string{"INT", "BIGINT", "FLOAT", "DOUBLE", "STRING", "BOOLEAN"}
+	case "clickhouse":
+		types = [...]string{"Int32", "Int64", "Float32", "Float64", "String", "Bool"}
+	}
+	switch typ.ID() {
+	case arrow.INT32:
+		return types[0], nil
+	case arrow.INT64:
+		return types[1], nil
+	case arrow.FLOAT32:
+		return types[2], nil
+	case arrow.FLOAT64:
+		return types[3], nil
+	case arrow.STRING:
+		return types[4], nil
+	case arrow.BOOL:
+		return types[5], nil
+	case arrow.TIMESTAMP:
+		switch dialect {
+		case "spark":
+			return "TIMESTAMP", nil
+		case "clickhouse":
+			return "DateTime64(6, 'UTC')", nil
+		default:
+			return "TIMESTAMPTZ", nil
+		}
+	default:
+		return "", fmt.Errorf("no %s type for %s", dialect, typ)
+	}
+}
@@ -0,0 +1,64 @@
+package schema
+
+import ecommercemodels "github.com/peekknuf/Gengo/internal/models/ecommerce"
+
+// ECommerceTables are the tables of the ecommerce model.
+var ECommerceTables = []Table{
+	{Name: "dim_customers", Row: ecommercemodels.Customer{}, PrimaryKey: key("customer_id"), RowCount: "Customers"},
+	{
+		Name:        "dim_customer_addresses",
+		Row:         ecommercemodels.CustomerAddress{},
+		PrimaryKey:  key("address_id"),
+		ForeignKeys: []ForeignKey{fk("customer_id", "dim_customers")},
+		RowCount:    "CustomerAddresses",
+		Estimated:   true,
+		Derived:     true,
+	},
+	{Name: "dim_suppliers", Row: ecommercemodels.Supplier{}, PrimaryKey: key("supplier_id"), RowCount: "Suppliers"},
+	{Name: "dim_product_categories", Row: ecommercemodels.ProductCategory{}, PrimaryKey: key("category_id"), RowCount: "ProductCategories", Derived: true},
+	{
+		Name:       "dim_products",
+		Row:        ecommercemodels.Product{},
+		PrimaryKey: key("product_id"),
+		ForeignKeys: []ForeignKey{
+			fk("supplier_id", "dim_suppliers"),
+			fk("category_id", "dim_product_categories"),
+		},
+		RowCount: "Products",
+	},
+	{
+		Name:       "fact_orders_header",
+		Row:        ecommercemodels.OrderHeader{},
+		TextRow:    orderHeaderText{},
+		PrimaryKey: key("order_id"),
+		ForeignKeys: []ForeignKey{
+			fk("customer_id", "dim_customers"),
+			fk("shipping_address_id", "dim_customer_addresses"),
+			fk("billing_address_id", "dim_customer_addresses"),
+		},
+		RowCount: "OrderHeaders",
+	},
+	{
+		Name:       "fact_order_items",
+		Row:        ecommercemodels.OrderItem{},
+		PrimaryKey: key("order_item_id"),
+		ForeignKeys: []ForeignKey{
+			{Columns: key("order_id"), References: "fact_orders_header", SameShard: true},
+			fk("product_id", "dim_products"),
+		},
+		RowCount:  "OrderItems",
+		Estimated: true,
+		Derived:   true,
+	},
+}
+
+// orderHeaderText is the layout of fact_orders_header in CSV and JSON Lines,
+// which hold the order time as Unix seconds.
+type orderHeaderText struct {
+	OrderID            int    `json:"order_id"`
+	CustomerID         int    `json:"customer_id"`
+	ShippingAddressID  int    `json:"shipping_address_id"`
+	BillingAddressID   int    `json:"billing_address_id"`
+	OrderTimestampUnix int64  `json:"order_timestamp_unix"`
+	OrderStatus        string `json:"order_status"`
+}
@@ -0,0 +1,215 @@
+package schema
+
+import ecommercedsmodels "github.com/peekknuf/Gengo/internal/models/ecommerce-ds"
+
+// ECommerceDSTables are the tables of the ecommerce-ds model, with the TPC-DS
+// keys. d_date_sk is the Julian day number of the date. The date keys of
+// dim_customers, dim_call_centers, dim_web_sites and dim_web_pages hold Julian
+// day numbers (0 for none) that may fall outside dim_date, so they are not
+// declared.
+var ECommerceDSTables = []Table{
+	{Name: "dim_date", Row: ecommercedsmodels.DateDim{}, PrimaryKey: key("d_date_sk")},
+	{Name: "dim_time", Row: ecommercedsmodels.TimeDim{}, PrimaryKey: key("t_time_sk")},
+	{Name: "dim_income_bands", Row: ecommercedsmodels.IncomeBand{}, PrimaryKey: key("ib_income_band_sk"), RowCount: "IncomeBands"},
+	{Name: "dim_customer_demographics", Row: ecommercedsmodels.CustomerDemographics{}, PrimaryKey: key("cd_demo_sk"), RowCount: "CustomerDemographics"},
+	{
+		Name:        "dim_household_demographics",
+		Row:         ecommercedsmodels.HouseholdDemographics{},
+		PrimaryKey:  key("hd_demo_sk"),
+		ForeignKeys: []ForeignKey{fk("hd_income_band_sk", "dim_income_bands")},
+		RowCount:    "HouseholdDemographics",
+	},
+	{Name: "dim_customer_addresses", Row: ecommercedsmodels.CustomerAddress{}, PrimaryKey: key("ca_address_sk"), RowCount: "CustomerAddresses"},
+	{
+		Name:       "dim_customers",
+		Row:        ecommercedsmodels.Customer{},
+		PrimaryKey: key("c_customer_sk"),
+		ForeignKeys: []ForeignKey{
+			fk("c_current_cdemo_sk", "dim_customer_demographics"),
+			fk("c_current_hdemo_sk", "dim_household_demographics"),
+			fk("c_current_addr_sk", "dim_customer_addresses"),
+		},
+		RowCount: "Customers",
+	},
+	{Name: "dim_items", Row: ecommercedsmodels.Item{}, PrimaryKey: key("i_item_sk"), RowCount: "Items"},
+	{
+		Name:       "dim_promotions",
+		Row:        ecommercedsmodels.Promotion{},
+		PrimaryKey: key("p_promo_sk"),
+		ForeignKeys: []ForeignKey{
+			fk("p_start_date_sk", "dim_date"),
+			fk("p_end_date_sk", "dim_date"),
+			fk("p_item_sk", "dim_items"),
+		},
+		RowCount: "Promotions",
+	},
+	{Name: "dim_stores", Row: ecommercedsmodels.Store{}, PrimaryKey: key("s_store_sk"), RowCount: "Stores"},
+	{Name: "dim_call_centers", Row: ecommercedsmodels.CallCenter{}, PrimaryKey: key("cc_call_center_sk"), RowCount: "CallCenters"},
+	{
+		Name:       "dim_catalog_pages",
+		Row:        ecommercedsmodels.CatalogPage{},
+		PrimaryKey: key("cp_catalog_page_sk"),
+		ForeignKeys: []ForeignKey{
+			fk("cp_start_date_sk", "dim_date"),
+			fk("cp_end_date_sk", "dim_date"),
+		},
+		RowCount: "CatalogPages",
+	},
+	{Name: "dim_web_sites", Row: ecommercedsmodels.WebSite{}, PrimaryKey: key("web_site_sk"), RowCount: "WebSites"},
+	{
+		Name:        "dim_web_pages",
+		Row:         ecommercedsmodels.WebPage{},
+		PrimaryKey:  key("wp_web_page_sk"),
+		ForeignKeys: []ForeignKey{fk("wp_customer_sk", "dim_customers")},
+		RowCount:    "WebPages",
+	},
+	{Name: "dim_warehouses", Row: ecommercedsmodels.Warehouse{}, PrimaryKey: key("w_warehouse_sk"), RowCount: "Warehouses"},
+	{Name: "dim_reasons", Row: ecommercedsmodels.Reason{}, PrimaryKey: key("r_reason_sk"), RowCount: "Reasons"},
+	{Name: "dim_ship_modes", Row: ecommercedsmodels.ShipMode{}, PrimaryKey: key("sm_ship_mode_sk"), RowCount: "ShipModes"},
+	{
+		Name:       "fact_store_sales",
+		Row:        ecommercedsmodels.StoreSales{},
+		PrimaryKey: key("ss_item_sk", "ss_ticket_number"),
+		ForeignKeys: []ForeignKey{
+			fk("ss_sold_date_sk", "dim_date"),
+			fk("ss_sold_time_sk", "dim_time"),
+			fk("ss_item_sk", "dim_items"),
+			fk("ss_customer_sk", "dim_customers"),
+			fk("ss_cdemo_sk", "dim_customer_demographics"),
+			fk("ss_hdemo_sk", "dim_household_demographics"),
+			fk("ss_addr_sk", "dim_customer_addresses"),
+			fk("ss_store_sk", "dim_stores"),
+			fk("ss_promo_sk", "dim_promotions"),
+		},
+		RowCount: "StoreSales",
+	},
+	{
+		Name:       "fact_store_returns",
+		Row:        ecommercedsmodels.StoreReturns{},
+		PrimaryKey: key("sr_item_sk", "sr_ticket_number"),
+		ForeignKeys: []ForeignKey{
+			fk("sr_returned_date_sk", "dim_date"),
+			fk("sr_return_time_sk", "dim_time"),
+			fk("sr_item_sk", "dim_items"),
+			fk("sr_customer_sk", "dim_customers"),
+			fk("sr_cdemo_sk", "dim_customer_demographics"),
+			fk("sr_hdemo_sk", "dim_household_demographics"),
+			fk("sr_addr_sk", "dim_customer_addresses"),
+			fk("sr_store_sk", "dim_stores"),
+			fk("sr_reason_sk", "dim_reasons"),
+			{Columns: key("sr_item_sk", "sr_ticket_number"), References: "fact_store_sales", SameShard: true},
+		},
+		RowCount:   "StoreReturns",
+		ScalesWith: "fact_store_sales",
+	},
+	{
+		Name:       "fact_catalog_sales",
+		Row:        ecommercedsmodels.CatalogSales{},
+		PrimaryKey: key("cs_item_sk", "cs_order_number"),
+		ForeignKeys: []ForeignKey{
+			fk("cs_sold_date_sk", "dim_date"),
+			fk("cs_sold_time_sk", "dim_time"),
+			fk("cs_ship_date_sk", "dim_date"),
+			fk("cs_bill_customer_sk", "dim_customers"),
+			fk("cs_bill_cdemo_sk", "dim_customer_demographics"),
+			fk("cs_bill_hdemo_sk", "dim_household_demographics"),
+			fk("cs_bill_addr_sk", "dim_customer_addresses"),
+			fk("cs_ship_customer_sk", "dim_customers"),
+			fk("cs_ship_cdemo_sk", "dim_customer_demographics"),
+			fk("cs_ship_hdemo_sk", "dim_household_demographics"),
+			fk("cs_ship_addr_sk", "dim_customer_addresses"),
+			fk("cs_call_center_sk", "dim_call_centers"),
+			fk("cs_catalog_page_sk", "dim_catalog_pages"),
+			fk("cs_ship_mode_sk", "dim_ship_modes"),
+			fk("cs_warehouse_sk", "dim_warehouses"),
+			fk("cs_item_sk", "dim_items"),
+			fk("cs_promo_sk", "dim_promotions"),
+		},
+		RowCount: "CatalogSales",
+	},
+	{
+		Name:       "fact_catalog_returns",
+		Row:        ecommercedsmodels.CatalogReturns{},
+		PrimaryKey: key("cr_item_sk", "cr_order_number"),
+		ForeignKeys: []ForeignKey{
+			fk("cr_returned_date_sk", "dim_date"),
+			fk("cr_returned_time_sk", "dim_time"),
+			fk("cr_item_sk", "dim_items"),
+			fk("cr_refund_customer_sk", "dim_customers"),
+			fk("cr_refund_cdemo_sk", "dim_customer_demographics"),
+			fk("cr_refund_hdemo_sk", "dim_household_demographics"),
+			fk("cr_refund_addr_sk", "dim_customer_addresses"),
+			fk("cr_returning_customer_sk", "dim_customers"),
+			fk("cr_returning_cdemo_sk", "dim_customer_demographics"),
+			fk("cr_returning_hdemo_sk", "dim_household_demographics"),
+			fk("cr_returning_addr_sk", "dim_customer_addresses"),
+			fk("cr_call_center_sk", "dim_call_centers"),
+			fk("cr_catalog_page_sk", "dim_catalog_pages"),
+			fk("cr_ship_mode_sk", "dim_ship_modes"),
+			fk("cr_warehouse_sk", "dim_warehouses"),
+			fk("cr_reason_sk", "dim_reasons"),
+			{Columns: key("cr_item_sk", "cr_order_number"), References: "fact_catalog_sales", SameShard: true},
+		},
+		RowCount:   "CatalogReturns",
+		ScalesWith: "fact_catalog_sales",
+	},
+	{
+		Name:       "fact_web_sales",
+		Row:        ecommercedsmodels.WebSales{},
+		PrimaryKey: key("ws_item_sk", "ws_order_number"),
+		ForeignKeys: []ForeignKey{
+			fk("ws_sold_date_sk", "dim_date"),
+			fk("ws_sold_time_sk", "dim_time"),
+			fk("ws_ship_date_sk", "dim_date"),
+			fk("ws_item_sk", "dim_items"),
+			fk("ws_bill_customer_sk", "dim_customers"),
+			fk("ws_bill_cdemo_sk", "dim_customer_demographics"),
+			fk("ws_bill_hdemo_sk", "dim_household_demographics"),
+			fk("ws_bill_addr_sk", "dim_customer_addresses"),
+			fk("ws_ship_customer_sk", "dim_customers"),
+			fk("ws_ship_cdemo_sk", "dim_customer_demographics"),
+			fk("ws_ship_hdemo_sk", "dim_household_demographics"),
+			fk("ws_ship_addr_sk", "dim_customer_addresses"),
+			fk("ws_web_page_sk", "dim_web_pages"),
+			fk("ws_web_site_sk", "dim_web_sites"),
+			fk("ws_ship_mode_sk", "dim_ship_modes"),
+			fk("ws_warehouse_sk", "dim_warehouses"),
+			fk("ws_promo_sk", "dim_promotions"),
+		},
+		RowCount: "WebSales",
+	},
+	{
+		Name:       "fact_web_returns",
+		Row:        ecommercedsmodels.WebReturns{},
+		PrimaryKey: key("wr_item_sk", "wr_order_number"),
+		ForeignKeys: []ForeignKey{
+			fk("wr_returned_date_sk", "dim_date"),
+			fk("wr_returned_time_sk", "dim_time"),
+			fk("wr_item_sk", "dim_items"),
+			fk("wr_refund_customer_sk", "dim_customers"),
+			fk("wr_refund_cdemo_sk", "dim_customer_demographics"),
+			fk("wr_refund_hdemo_sk", "dim_household_demographics"),
+			fk("wr_refund_addr_sk", "dim_customer_addresses"),
+			fk("wr_returning_customer_sk", "dim_customers"),
+			fk("wr_returning_cdemo_sk", "dim_customer_demographics"),
+			fk("wr_returning_hdemo_sk", "dim_household_demographics"),
+			fk("wr_returning_addr_sk", "dim_customer_addresses"),
+			fk("wr_web_page_sk", "dim_web_pages"),
+			fk("wr_reason_sk", "dim_reasons"),
+			{Columns: key("wr_item_sk", "wr_order_number"), References: "fact_web_sales", SameShard: true},
+		},
+		RowCount:   "WebReturns",
+		ScalesWith: "fact_web_sales",
+	},
+	{
+		Name:       "fact_inventory",
+		Row:        ecommercedsmodels.Inventory{},
+		PrimaryKey: key("inv_date_sk", "inv_item_sk", "inv_warehouse_sk"),
+		ForeignKeys: []ForeignKey{
+			fk("inv_date_sk", "dim_date"),
+			fk("inv_item_sk", "dim_items"),
+			fk("inv_warehouse_sk", "dim_warehouses"),
+		},
+		RowCount: "Inventory",
+	},
+}
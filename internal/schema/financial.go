@@ -0,0 +1,20 @@
+package schema
+
+import financialmodels "github.com/peekknuf/Gengo/internal/models/financial"
+
+// FinancialTables are the tables of the financial model.
+var FinancialTables = []Table{
+	{Name: "dim_companies", Row: financialmodels.Company{}, PrimaryKey: key("company_id"), RowCount: "Companies"},
+	{Name: "dim_exchanges", Row: financialmodels.Exchange{}, PrimaryKey: key("exchange_id"), RowCount: "Exchanges"},
+	{
+		Name:       "fact_daily_stock_prices",
+		Row:        financialmodels.DailyStockPrice{},
+		PrimaryKey: key("price_id"),
+		ForeignKeys: []ForeignKey{
+			fk("company_id", "dim_companies"),
+			fk("exchange_id", "dim_exchanges"),
+		},
+		RowCount:  "DailyStockPrices",
+		Estimated: true,
+	},
+}
@@ -0,0 +1,21 @@
+package schema
+
+import medicalmodels "github.com/peekknuf/Gengo/internal/models/medical"
+
+// MedicalTables are the tables of the medical model.
+var MedicalTables = []Table{
+	{Name: "dim_patients", Row: medicalmodels.Patient{}, PrimaryKey: key("patient_id"), RowCount: "Patients"},
+	{Name: "dim_doctors", Row: medicalmodels.Doctor{}, PrimaryKey: key("doctor_id"), RowCount: "Doctors"},
+	{Name: "dim_clinics", Row: medicalmodels.Clinic{}, PrimaryKey: key("clinic_id"), RowCount: "Clinics"},
+	{
+		Name:       "fact_appointments",
+		Row:        medicalmodels.Appointment{},
+		PrimaryKey: key("appointment_id"),
+		ForeignKeys: []ForeignKey{
+			fk("patient_id", "dim_patients"),
+			fk("doctor_id", "dim_doctors"),
+			fk("clinic_id", "dim_clinics"),
+		},
+		RowCount: "Appointments",
+	},
+}
@@ -0,0 +1,88 @@
+// Package schema declares the tables each model generates and the keys that
+// relate them. Models register their tables when they are registered with
+// the generator.
+package schema
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/peekknuf/Gengo/internal/formats"
+)
+
+// Table describes a generated table and its keys.
+type Table struct {
+	Name string
+	// Row is the model struct of a row; its fields are the table's columns.
+	// TextRow replaces it for CSV and JSON Lines when they lay the table out
+	// differently.
+	Row         interface{}
+	TextRow     interface{}
+	PrimaryKey  []string
+	ForeignKeys []ForeignKey
+	// RowCount is the field of the model's row counts that sizes the table, if
+	// any. Estimated is set when the generated rows only approximate it, and
+	// Derived when they follow from other tables so it cannot be set.
+	RowCount  string
+	Estimated bool
+	Derived   bool
+	// ScalesWith names the table whose rows this table's rows are drawn from,
+	// like returns from sales. When only that table's row count is set, this
+	// table's row count keeps its ratio to it.
+	ScalesWith string
+}
+
+// ForeignKey references the primary key of another table. SameShard is set
+// when the rows referencing a row are written by the shard that wrote it, like
+// returns next to their sales, so the key can be checked shard by shard.
+type ForeignKey struct {
+	Columns    []string
+	References string
+	SameShard  bool
+}
+
+// Tables returns the tables of a registered model, ordered so every table comes
+// after the tables it references.
+func Tables(model string) ([]Table, error) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	if tables, ok := registered[model]; ok {
+		return tables, nil
+	}
+	return nil, fmt.Errorf("unsupported model type: %s", model)
+}
+
+var (
+	registeredMu sync.Mutex
+	registered   = make(map[string][]Table)
+)
+
+// Register declares the tables of model. They must be in dependency order.
+func Register(model string, tables []Table) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered[model] = tables
+}
+
+// Columns returns the columns of t as they are written in format. dsdgen
+// files hold the columns of the TPC-DS table.
+func (t Table) Columns(format string) (*arrow.Schema, error) {
+	row := t.Row
+	if t.TextRow != nil && !formats.Columnar(format) {
+		row = t.TextRow
+	}
+	columns, err := formats.StructSchema(row)
+	if err != nil || format != "dsdgen" {
+		return columns, err
+	}
+	return formats.DsdgenSchema(t.Name, columns)
+}
+
+func key(columns ...string) []string {
+	return columns
+}
+
+func fk(column, references string) ForeignKey {
+	return ForeignKey{Columns: []string{column}, References: references}
+}
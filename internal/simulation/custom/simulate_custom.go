@@ -0,0 +1,210 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	gf "github.com/brianvoe/gofakeit/v6"
+	"github.com/peekknuf/Gengo/internal/common"
+	"github.com/peekknuf/Gengo/internal/formats"
+	"github.com/peekknuf/Gengo/internal/models/custom"
+	"golang.org/x/sync/errgroup"
+)
+
+const ctxCheckInterval = 4096 // Rows between checks for a cancelled run
+
+// columnGenerator sets the value of a column in the row with primary key id.
+type columnGenerator func(f *gf.Faker, id int64, field reflect.Value)
+
+// GenerateTable generates table t of a custom model and writes it to outputDir.
+// counts holds the row count of every table of the model. Tables with more
+// rows than a shard holds are split into shard files generated in parallel;
+// each shard draws from its own seeded stream. Primary keys run from 1 to the row count,
+// so foreign keys are drawn from the row counts of the referenced tables and
+// every table can be generated independently.
+func GenerateTable(ctx context.Context, t *custom.Table, counts map[string]int, opts formats.Options, outputDir string, seed int64) error {
+	rows := counts[t.Name]
+	if rows <= 0 {
+		return nil
+	}
+	generators := make([]columnGenerator, len(t.Columns))
+	for i, c := range t.Columns {
+		gen, err := newColumnGenerator(c, counts)
+		if err != nil {
+			return fmt.Errorf("table %s: column %s: %w", t.Name, c.Name, err)
+		}
+		generators[i] = gen
+	}
+
+	shards := common.ShardCount(rows)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
+	for shard := 0; shard < shards; shard++ {
+		name := t.Name
+		if shards > 1 {
+			name = fmt.Sprintf("%s_%d", t.Name, shard)
+		}
+		if opts.Completed(opts.TablePath(outputDir, name) + opts.FileExtension()) {
+			continue
+		}
+		start := int64(rows) * int64(shard) / int64(shards)
+		end := int64(rows) * int64(shard+1) / int64(shards)
+		g.Go(func() error {
+			faker := common.NewFaker(seed, t.Name, shard)
+			data, err := generateRows(ctx, t.RowType(), generators, start, end, faker)
+			if err != nil {
+				return err
+			}
+			return formats.WriteSliceData(ctx, data, name, opts, outputDir)
+		})
+	}
+	return g.Wait()
+}
+
+// generateRows returns a slice of the rows with primary keys start+1 to end.
+func generateRows(ctx context.Context, rowType reflect.Type, generators []columnGenerator, start, end int64, faker *gf.Faker) (interface{}, error) {
+	n := int(end - start)
+	rows := reflect.MakeSlice(reflect.SliceOf(rowType), n, n)
+	for i := 0; i < n; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		row := rows.Index(i)
+		id := start + int64(i) + 1
+		for j, gen := range generators {
+			gen(faker, id, row.Field(j))
+		}
+	}
+	return rows.Interface(), nil
+}
+
+func newColumnGenerator(c *custom.Column, counts map[string]int) (columnGenerator, error) {
+	switch {
+	case c.PrimaryKey:
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.SetInt(id)
+		}, nil
+
+	case c.References != "":
+		n := int64(counts[c.References])
+		if n <= 0 {
+			return nil, fmt.Errorf("referenced table %s has no rows", c.References)
+		}
+		if c.Distribution == "uniform" {
+			return func(f *gf.Faker, id int64, field reflect.Value) {
+				field.SetInt(1 + f.Rand.Int63n(n))
+			}, nil
+		}
+		ids := make([]int64, n)
+		for i := range ids {
+			ids[i] = int64(i) + 1
+		}
+		sampler, err := common.NewUnifiedWeightedSampler64(ids)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.SetInt(sampler.Sample(f.Rand))
+		}, nil
+
+	case c.Faker != "":
+		info := gf.GetFuncLookup(c.Faker)
+		params := c.FakerParams()
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			value, _ := info.Generate(f.Rand, params, info)
+			if s, ok := value.(string); ok {
+				field.SetString(s)
+			} else {
+				field.SetString(fmt.Sprint(value))
+			}
+		}, nil
+
+	case c.Template != "":
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.SetString(f.Generate(c.Template))
+		}, nil
+
+	case len(c.Enum) > 0:
+		if len(c.Weights) == 0 {
+			return func(f *gf.Faker, id int64, field reflect.Value) {
+				field.SetString(c.Enum[f.Rand.Intn(len(c.Enum))])
+			}, nil
+		}
+		cumulative := make([]float64, len(c.Weights))
+		var total float64
+		for i, w := range c.Weights {
+			total += w
+			cumulative[i] = total
+		}
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			i := sort.SearchFloat64s(cumulative, f.Rand.Float64()*total)
+			if i == len(cumulative) {
+				i--
+			}
+			field.SetString(c.Enum[i])
+		}, nil
+
+	case c.Probability != nil:
+		p := *c.Probability
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.SetBool(f.Rand.Float64() < p)
+		}, nil
+
+	case len(c.Range) > 0:
+		return rangeGenerator(c), nil
+	}
+	return nil, fmt.Errorf("no generator")
+}
+
+// rangeGenerator draws values between c.Min and c.Max. Normal values centre on
+// the middle of the range with a sixth of it as standard deviation; exponential
+// values fall off from the minimum with a fifth of the range as mean. Both are
+// clamped to the range.
+func rangeGenerator(c *custom.Column) columnGenerator {
+	lo, hi := c.Min, c.Max
+	draw := func(f *gf.Faker) float64 {
+		var x float64
+		switch c.Distribution {
+		case "normal":
+			x = (lo+hi)/2 + f.Rand.NormFloat64()*(hi-lo)/6
+		case "exponential":
+			x = lo + f.Rand.ExpFloat64()*(hi-lo)/5
+		default:
+			x = lo + f.Rand.Float64()*(hi-lo)
+		}
+		return math.Max(lo, math.Min(hi, x))
+	}
+
+	switch c.Type {
+	case "int32", "int64":
+		if c.Distribution == "" || c.Distribution == "uniform" {
+			span := int64(hi-lo) + 1
+			return func(f *gf.Faker, id int64, field reflect.Value) {
+				field.SetInt(int64(lo) + f.Rand.Int63n(span))
+			}
+		}
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.SetInt(int64(math.Round(draw(f))))
+		}
+	case "timestamp":
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.Set(reflect.ValueOf(time.Unix(int64(draw(f)), 0).UTC()))
+		}
+	default:
+		if c.Decimals == nil {
+			return func(f *gf.Faker, id int64, field reflect.Value) {
+				field.SetFloat(draw(f))
+			}
+		}
+		scale := math.Pow(10, float64(*c.Decimals))
+		return func(f *gf.Faker, id int64, field reflect.Value) {
+			field.SetFloat(math.Round(draw(f)*scale) / scale)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package ecommerceds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/peekknuf/Gengo/internal/formats"
+)
+
+// columnKind describes how an int64 fact value is rendered on disk.
+type columnKind int
+
+const (
+	colKey      columnKind = iota // surrogate keys and numbers, Int64
+	colQuantity                   // small counts, Int32
+	colPrice                      // amounts held in cents, written with 2 decimals / Float64
+)
+
+type factColumn struct {
+	name string
+	kind columnKind
+}
+
+// factTable is the on-disk layout of a fact table whose values are all integers.
+type factTable struct {
+	name    string
+	columns []factColumn
+}
+
+func (t *factTable) arrowSchema() *arrow.Schema {
+	fields := make([]arrow.Field, len(t.columns))
+	for i, c := range t.columns {
+		switch c.kind {
+		case colQuantity:
+			fields[i] = arrow.Field{Name: c.name, Type: arrow.PrimitiveTypes.Int32}
+		case colPrice:
+			fields[i] = arrow.Field{Name: c.name, Type: arrow.PrimitiveTypes.Float64}
+		default:
+			fields[i] = arrow.Field{Name: c.name, Type: arrow.PrimitiveTypes.Int64}
+		}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+func (t *factTable) columnNames() []string {
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// csvRowEncoder returns a CSV encoder for the table in dialect d.
+func (t *factTable) csvRowEncoder(d formats.CSVDialect) func(buf []byte, row []int64) []byte {
+	prefix, end := d.Framing(len(t.columns))
+	return func(buf []byte, row []int64) []byte {
+		for i, c := range t.columns {
+			buf = append(buf, prefix[i]...)
+			if c.kind == colPrice {
+				buf = appendPrice(buf, row[i])
+			} else {
+				buf = strconv.AppendInt(buf, row[i], 10)
+			}
+		}
+		return append(buf, end...)
+	}
+}
+
+// appendDatRow writes the row like dsdgen: a '|' after every field.
+func (t *factTable) appendDatRow(buf []byte, row []int64) []byte {
+	for i, c := range t.columns {
+		if c.kind == colPrice {
+			buf = appendPrice(buf, row[i])
+		} else {
+			buf = strconv.AppendInt(buf, row[i], 10)
+		}
+		buf = append(buf, '|')
+	}
+	return append(buf, '\n')
+}
+
+// jsonRowEncoder returns a JSON Lines encoder for the table. Prices are written as
+// decimal numbers with two places, like in CSV.
+func (t *factTable) jsonRowEncoder() func(buf []byte, row []int64) []byte {
+	prefixes := formats.JSONKeyPrefixes(t.columnNames())
+	return func(buf []byte, row []int64) []byte {
+		for i, c := range t.columns {
+			buf = append(buf, prefixes[i]...)
+			if c.kind == colPrice {
+				buf = appendPrice(buf, row[i])
+			} else {
+				buf = strconv.AppendInt(buf, row[i], 10)
+			}
+		}
+		return append(buf, '}', '\n')
+	}
+}
+
+// factShardWriter writes the rows of one shard of a fact table. The shard is
+// written under a temporary name: close publishes it, discard removes it.
+type factShardWriter interface {
+	writeRow(row []int64) error
+	close() error
+	discard()
+}
+
+// newFactShardWriter opens the writer of a shard of table. If fact tables are
+// partitioned and table has the partition keys, the shard is written to a file
+// per partition instead of to filename.
+func newFactShardWriter(table *factTable, filename string, opts formats.Options, bufSize int) (factShardWriter, error) {
+	if keys, ok := opts.Partitioning.Columns(table.columnNames()); ok {
+		return newPartitionedShardWriter(table, filename, opts, keys), nil
+	}
+	return newFileShardWriter(table, filename, opts, bufSize, recordBatchRows)
+}
+
+// fileShardWriter is a factShardWriter writing a single file. finish flushes and
+// closes the file without publishing it.
+type fileShardWriter interface {
+	factShardWriter
+	finish() error
+}
+
+// recordBatchRows is the number of rows a Parquet, Arrow IPC or Avro shard
+// writer buffers before writing them out as a batch.
+const recordBatchRows = 65536
+
+func newFileShardWriter(table *factTable, filename string, opts formats.Options, bufSize, batchRows int) (fileShardWriter, error) {
+	switch opts.Format {
+	case "parquet", "arrow", "avro":
+		return newRecordShardWriter(table, filename, opts, batchRows)
+	case "json":
+		return newTextShardWriter(filename, opts, bufSize, nil, table.jsonRowEncoder())
+	case "dsdgen":
+		return newTextShardWriter(filename, opts, bufSize, nil, table.appendDatRow)
+	default:
+		return newTextShardWriter(filename, opts, bufSize, opts.CSV.HeaderLine(table.columnNames()), table.csvRowEncoder(opts.CSV))
+	}
+}
+
+// textShardWriter writes line-oriented formats (CSV, JSON Lines, dsdgen) through a buffered file.
+type textShardWriter struct {
+	filename  string
+	opts      formats.Options
+	file      io.WriteCloser
+	writer    *bufio.Writer
+	appendRow func(buf []byte, row []int64) []byte
+	rowBuf    []byte
+	rows      int
+}
+
+func newTextShardWriter(filename string, opts formats.Options, bufSize int, header []byte, appendRow func(buf []byte, row []int64) []byte) (*textShardWriter, error) {
+	file, err := opts.CreateTextFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", filename, err)
+	}
+	w := &textShardWriter{
+		filename:  filename,
+		opts:      opts,
+		file:      file,
+		writer:    bufio.NewWriterSize(file, bufSize),
+		appendRow: appendRow,
+		rowBuf:    make([]byte, 0, 1024),
+	}
+	if len(header) > 0 {
+		if _, err := w.writer.Write(header); err != nil {
+			file.Close()
+			formats.DiscardFile(filename)
+			return nil, fmt.Errorf("failed to write header to %s: %w", filename, err)
+		}
+	}
+	return w, nil
+}
+
+func (w *textShardWriter) writeRow(row []int64) error {
+	w.rowBuf = w.appendRow(w.rowBuf[:0], row)
+	if _, err := w.writer.Write(w.rowBuf); err != nil {
+		return fmt.Errorf("failed to write row to %s: %w", w.filename, err)
+	}
+	w.rows++
+	if w.rows%flushBatchSize == 0 {
+		if err := w.writer.Flush(); err != nil {
+			return fmt.Errorf("failed to flush %s: %w", w.filename, err)
+		}
+	}
+	return nil
+}
+
+func (w *textShardWriter) close() (err error) {
+	w.opts.CloseBufferedFile(w.writer, w.file, w.filename, &err)
+	return err
+}
+
+func (w *textShardWriter) finish() (err error) {
+	formats.FinishBufferedFile(w.writer, w.file, w.filename, &err)
+	return err
+}
+
+func (w *textShardWriter) discard() {
+	w.file.Close()
+	formats.DiscardFile(w.filename)
+}
+
+// recordShardWriter writes a shard in a columnar format, Parquet, Arrow IPC or
+// Avro, as record batches.
+type recordShardWriter struct {
+	table    *factTable
+	filename string
+	opts     formats.Options
+	writer   formats.RecordWriter
+	builder  *array.RecordBuilder
+	fields   []array.Builder
+	rows     int
+	batch    int
+}
+
+func newRecordShardWriter(table *factTable, filename string, opts formats.Options, batchRows int) (*recordShardWriter, error) {
+	_, writer, builder, err := formats.CreateRecordWriter(table.arrowSchema(), filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &recordShardWriter{
+		table:    table,
+		filename: filename,
+		opts:     opts,
+		writer:   writer,
+		builder:  builder,
+		fields:   builder.Fields(),
+		batch:    batchRows,
+	}, nil
+}
+
+func (w *recordShardWriter) writeRow(row []int64) error {
+	for i, c := range w.table.columns {
+		switch c.kind {
+		case colQuantity:
+			w.fields[i].(*array.Int32Builder).Append(int32(row[i]))
+		case colPrice:
+			w.fields[i].(*array.Float64Builder).Append(float64(row[i]) / 100.0)
+		default:
+			w.fields[i].(*array.Int64Builder).Append(row[i])
+		}
+	}
+	w.rows++
+	if w.rows%w.batch == 0 {
+		return formats.WriteTypedBatch(w.writer, w.builder, w.filename)
+	}
+	return nil
+}
+
+func (w *recordShardWriter) close() error {
+	err := w.finish()
+	w.opts.PublishFile(w.filename, &err)
+	return err
+}
+
+func (w *recordShardWriter) finish() (err error) {
+	defer w.builder.Release()
+	if w.rows%w.batch != 0 {
+		err = formats.WriteTypedBatch(w.writer, w.builder, w.filename)
+	}
+	if closeErr := w.writer.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("error closing record writer for %s: %w", w.filename, closeErr)
+	}
+	if err != nil {
+		formats.DiscardFile(w.filename)
+	}
+	return err
+}
+
+func (w *recordShardWriter) discard() {
+	w.builder.Release()
+	w.writer.Close()
+	formats.DiscardFile(w.filename)
+}
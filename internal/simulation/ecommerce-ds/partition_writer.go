@@ -0,0 +1,342 @@
+package ecommerceds
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/peekknuf/Gengo/internal/formats"
+)
+
+const (
+	// partitionBufferSize is the write buffer of a partition file, smaller than
+	// that of a shard file as a shard keeps many partition files open.
+	partitionBufferSize = 256 << 10
+	// partitionBatchRows is the number of rows a Parquet, Arrow IPC or Avro
+	// partition file buffers before writing them out as a batch.
+	partitionBatchRows = 8192
+	// partitionPendingRows is the number of rows a shard holds back for
+	// partitions without an open file before spilling them to disk.
+	partitionPendingRows = 65536
+)
+
+// partitionedShardWriter writes a shard of a partitioned fact table to a file
+// per partition: shard 3 of store_sales_3.csv goes to
+// store_sales/year=2001/month=04/part-00003.csv and so on. The first MaxOpen
+// partitions the shard meets get a file kept open until the shard is closed.
+// Rows of the other partitions are held back, and spilled to a hidden run file
+// of the shard, ordered by partition, whenever partitionPendingRows are
+// pending. Closing the shard merges the runs, writing each of these partitions
+// in a single pass, so a shard writes one file per partition. The files are
+// published together when the shard is closed.
+type partitionedShardWriter struct {
+	table    *factTable
+	opts     formats.Options
+	dir      string // directory of the table
+	part     string // file name of the shard in a partition, without extension
+	ext      string
+	keys     []int // columns of the partition keys
+	open     map[string]*partitionFile
+	finished []string           // closed files, published with the shard
+	pending  map[string][]int64 // rows held back per partition, one after another
+	held     int                // number of rows held back
+	spills   []*os.File         // runs of rows held back, ordered by partition
+	values   []int64
+	path     []byte
+}
+
+type partitionFile struct {
+	writer   fileShardWriter
+	filename string
+}
+
+func newPartitionedShardWriter(table *factTable, filename string, opts formats.Options, keys []int) *partitionedShardWriter {
+	dir, part, ext := partitionLayout(filename, opts)
+	return &partitionedShardWriter{
+		table:   table,
+		opts:    opts,
+		dir:     dir,
+		part:    part,
+		ext:     ext,
+		keys:    keys,
+		open:    make(map[string]*partitionFile),
+		pending: make(map[string][]int64),
+		values:  make([]int64, len(keys)),
+	}
+}
+
+// partitionLayout returns the directory of the table, the name of the shard's
+// partition files and their extension for the shard file filename, e.g.
+// out/store_sales, part-00003 and .csv for out/store_sales_3.csv, or
+// out/store_sales, part-00003 and .dat for the dsdgen file
+// out/store_sales_4_8.dat.
+func partitionLayout(filename string, opts formats.Options) (dir, part, ext string) {
+	ext = opts.FileExtension()
+	stem := strings.TrimSuffix(filepath.Base(filename), ext)
+	shard := 0
+	if i := strings.LastIndexByte(stem, '_'); i >= 0 && opts.Format != "dsdgen" {
+		shard, _ = strconv.Atoi(stem[i+1:])
+		stem = stem[:i]
+	} else if i >= 0 {
+		// dsdgen numbers the files of a table <child>_<parallel> from 1, and
+		// leaves a table written as a single file unnumbered.
+		if j := strings.LastIndexByte(stem[:i], '_'); j >= 0 {
+			if child, err := strconv.Atoi(stem[j+1 : i]); err == nil {
+				if _, err := strconv.Atoi(stem[i+1:]); err == nil {
+					shard, stem = child-1, stem[:j]
+				}
+			}
+		}
+	}
+	return filepath.Join(filepath.Dir(filename), stem), fmt.Sprintf("part-%05d", shard), ext
+}
+
+// shardPublished reports whether a resumed run already published the shard of
+// table written to filename, or to its partition files if table is
+// partitioned.
+func shardPublished(table *factTable, filename string, opts formats.Options) bool {
+	keys, ok := opts.Partitioning.Columns(table.columnNames())
+	if !ok {
+		return opts.Completed(filename)
+	}
+	dir, part, _ := partitionLayout(filename, opts)
+	return opts.CompletedMatching(filepath.Base(dir) + "/" + strings.Repeat("*/", len(keys)) + part + ".*")
+}
+
+func (w *partitionedShardWriter) writeRow(row []int64) error {
+	for i, k := range w.keys {
+		w.values[i] = row[k]
+	}
+	w.path = w.opts.Partitioning.AppendPath(w.path[:0], w.values)
+	f := w.open[string(w.path)]
+	if f == nil && len(w.open) < w.opts.Partitioning.MaxOpen {
+		var err error
+		if f, err = w.openPartition(string(w.path)); err != nil {
+			return err
+		}
+		w.open[string(w.path)] = f
+	}
+	if f == nil {
+		w.pending[string(w.path)] = append(w.pending[string(w.path)], row...)
+		if w.held++; w.held >= partitionPendingRows {
+			return w.spill()
+		}
+		return nil
+	}
+	return f.writer.writeRow(row)
+}
+
+// pendingPaths returns the partitions with rows held back, in order.
+func (w *partitionedShardWriter) pendingPaths() []string {
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// spill writes the rows held back to a new run: for each partition in order,
+// its path and the number of values of its rows, then the values.
+func (w *partitionedShardWriter) spill() error {
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create partition directory %s: %w", w.dir, err)
+	}
+	file, err := os.CreateTemp(w.dir, "."+w.part+"-*.spill")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file in %s: %w", w.dir, err)
+	}
+	w.spills = append(w.spills, file)
+	buf := bufio.NewWriterSize(file, partitionBufferSize)
+	var b []byte
+	for _, path := range w.pendingPaths() {
+		rows := w.pending[path]
+		b = binary.AppendUvarint(b[:0], uint64(len(path)))
+		b = append(b, path...)
+		b = binary.AppendUvarint(b, uint64(len(rows)))
+		for _, v := range rows {
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		}
+		if _, err := buf.Write(b); err != nil {
+			return fmt.Errorf("failed to write spill file %s: %w", file.Name(), err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file %s: %w", file.Name(), err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind spill file %s: %w", file.Name(), err)
+	}
+	clear(w.pending)
+	w.held = 0
+	return nil
+}
+
+// spillRun reads back a run, a partition at a time.
+type spillRun struct {
+	r      *bufio.Reader
+	name   string
+	path   string // partition of the next rows, "" once the run is read
+	values uint64 // number of values of the next rows
+}
+
+func (r *spillRun) next() error {
+	n, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		r.path = ""
+		return nil
+	}
+	path := make([]byte, n)
+	if err == nil {
+		_, err = io.ReadFull(r.r, path)
+	}
+	if err == nil {
+		r.values, err = binary.ReadUvarint(r.r)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read spill file %s: %w", r.name, err)
+	}
+	r.path = string(path)
+	return nil
+}
+
+// copyRows writes the next rows of the run to f.
+func (r *spillRun) copyRows(f *partitionFile, row []int64) error {
+	var b [8]byte
+	for left := r.values; left > 0; left -= uint64(len(row)) {
+		for i := range row {
+			if _, err := io.ReadFull(r.r, b[:]); err != nil {
+				return fmt.Errorf("failed to read spill file %s: %w", r.name, err)
+			}
+			row[i] = int64(binary.LittleEndian.Uint64(b[:]))
+		}
+		if err := f.writer.writeRow(row); err != nil {
+			return err
+		}
+	}
+	return r.next()
+}
+
+// writeHeldBack writes the partitions without an open file, each to a file of
+// its own, merging the rows of the runs and those still held back in the
+// order they were generated.
+func (w *partitionedShardWriter) writeHeldBack() error {
+	runs := make([]*spillRun, len(w.spills))
+	for i, file := range w.spills {
+		runs[i] = &spillRun{r: bufio.NewReaderSize(file, partitionBufferSize), name: file.Name()}
+		if err := runs[i].next(); err != nil {
+			return err
+		}
+	}
+	pending := w.pendingPaths()
+	row := make([]int64, len(w.table.columns))
+	for {
+		var path string
+		for _, r := range runs {
+			if r.path != "" && (path == "" || r.path < path) {
+				path = r.path
+			}
+		}
+		if len(pending) > 0 && (path == "" || pending[0] < path) {
+			path = pending[0]
+		}
+		if path == "" {
+			return nil
+		}
+
+		f, err := w.openPartition(path)
+		if err != nil {
+			return err
+		}
+		w.open[path] = f
+		for _, r := range runs {
+			if r.path == path {
+				if err := r.copyRows(f, row); err != nil {
+					return err
+				}
+			}
+		}
+		if len(pending) > 0 && pending[0] == path {
+			n := len(row)
+			for rows := w.pending[path]; len(rows) > 0; rows = rows[n:] {
+				if err := f.writer.writeRow(rows[:n]); err != nil {
+					return err
+				}
+			}
+			delete(w.pending, path)
+			pending = pending[1:]
+		}
+		if err := w.finishPartition(path); err != nil {
+			return err
+		}
+	}
+}
+
+// openPartition opens the file of a partition.
+func (w *partitionedShardWriter) openPartition(path string) (*partitionFile, error) {
+	filename := filepath.Join(w.dir, filepath.FromSlash(path), w.part+w.ext)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create partition directory for %s: %w", filename, err)
+	}
+	writer, err := newFileShardWriter(w.table, filename, w.opts, partitionBufferSize, partitionBatchRows)
+	if err != nil {
+		return nil, err
+	}
+	return &partitionFile{writer: writer, filename: filename}, nil
+}
+
+func (w *partitionedShardWriter) finishPartition(path string) error {
+	f := w.open[path]
+	delete(w.open, path)
+	if err := f.writer.finish(); err != nil {
+		return err
+	}
+	w.finished = append(w.finished, f.filename)
+	return nil
+}
+
+func (w *partitionedShardWriter) close() (err error) {
+	defer w.removeSpills()
+	paths := make([]string, 0, len(w.open))
+	for path := range w.open {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err = w.finishPartition(path); err != nil {
+			w.discard()
+			return err
+		}
+	}
+	if err = w.writeHeldBack(); err != nil {
+		w.discard()
+		return err
+	}
+	w.opts.PublishFiles(w.finished, &err)
+	return err
+}
+
+func (w *partitionedShardWriter) removeSpills() {
+	for _, file := range w.spills {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	w.spills = nil
+}
+
+func (w *partitionedShardWriter) discard() {
+	for path, f := range w.open {
+		f.writer.discard()
+		delete(w.open, path)
+	}
+	for _, filename := range w.finished {
+		formats.DiscardFile(filename)
+	}
+	w.removeSpills()
+}
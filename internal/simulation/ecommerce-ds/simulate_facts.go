@@ -1,27 +1,25 @@
 package ecommerceds
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
-	"os"
-	"runtime"
 	"strconv"
-	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/apache/arrow-go/v18/arrow"
-	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/formats"
 	ecommerceds "github.com/peekknuf/Gengo/internal/models/ecommerce-ds"
+	"golang.org/x/sync/errgroup"
 )
 
 // Performance optimization constants
 const (
-	bufferSize     = 64 << 20 // 64MB buffer for NVMe optimization (increased from 32MB)
-	flushBatchSize = 50000    // Batch size for periodic flushes (increased from 10K)
+	bufferSize       = 64 << 20 // 64MB buffer for NVMe optimization (increased from 32MB)
+	flushBatchSize   = 50000    // Batch size for periodic flushes (increased from 10K)
+	ctxCheckInterval = 4096     // Rows between checks for a cancelled run
 )
 
 // AliasSampler implements O(1) weighted sampling using Vose's alias method
@@ -176,77 +174,156 @@ func appendPrice(buf []byte, cents int64) []byte {
 	return buf
 }
 
+// dimensionSKs are the SKs of a dimension table a fact table draws keys from.
+type dimensionSKs struct {
+	table string
+	sks   []int64
+}
+
+// requireDimensionSKs returns an error naming the first of dims without rows,
+// as facts cannot reference an empty dimension.
+func requireDimensionSKs(facts string, dims ...dimensionSKs) error {
+	for _, d := range dims {
+		if len(d.sks) == 0 {
+			return fmt.Errorf("%s need rows in %s", facts, d.table)
+		}
+	}
+	return nil
+}
+
+// openReturns opens the returns shard written alongside a sales shard. When the
+// shard has no returns, the writer is nil and the selector never fires.
+func openReturns(table *factTable, shard returnsShard, salesCount int, opts formats.Options) (factShardWriter, *returnSelector, error) {
+	if shard.count <= 0 || len(shard.reasonSKs) == 0 {
+		return nil, newReturnSelector(salesCount, 0), nil
+	}
+	w, err := newFactShardWriter(table, shard.filename, opts, returnsBufferSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, newReturnSelector(salesCount, shard.count), nil
+}
+
+// shardDone reports whether a resumed run already published a sales shard and
+// the returns shard written alongside it.
+func shardDone(sales *factTable, filename string, returnsTable *factTable, returns returnsShard, opts formats.Options) bool {
+	if !shardPublished(sales, filename, opts) {
+		return false
+	}
+	return returns.count <= 0 || len(returns.reasonSKs) == 0 || shardPublished(returnsTable, returns.filename, opts)
+}
+
+// closeShard closes a shard writer, keeping the first error. If the worker has
+// already failed the shard is discarded instead of published. A nil writer, as
+// returned by openReturns for a shard without returns, is ignored.
+func closeShard(w factShardWriter, err *error) {
+	if w == nil {
+		return
+	}
+	if *err != nil {
+		w.discard()
+		return
+	}
+	if closeErr := w.close(); closeErr != nil && *err == nil {
+		*err = closeErr
+	}
+}
+
+// shardRecords splits count records over numWorkers, giving the remainder to the first workers.
+func shardRecords(count, numWorkers int) []int {
+	out := make([]int, numWorkers)
+	for i := range out {
+		out[i] = count / numWorkers
+		if i < count%numWorkers {
+			out[i]++
+		}
+	}
+	return out
+}
+
+var storeSalesTable = factTable{
+	name: "fact_store_sales",
+	columns: []factColumn{
+		{"ss_sold_date_sk", colKey},
+		{"ss_sold_time_sk", colKey},
+		{"ss_item_sk", colKey},
+		{"ss_customer_sk", colKey},
+		{"ss_cdemo_sk", colKey},
+		{"ss_hdemo_sk", colKey},
+		{"ss_addr_sk", colKey},
+		{"ss_store_sk", colKey},
+		{"ss_promo_sk", colKey},
+		{"ss_ticket_number", colKey},
+		{"ss_quantity", colQuantity},
+		{"ss_wholesale_cost", colPrice},
+		{"ss_list_price", colPrice},
+		{"ss_sales_price", colPrice},
+		{"ss_ext_discount_amt", colPrice},
+		{"ss_ext_sales_price", colPrice},
+		{"ss_ext_wholesale_cost", colPrice},
+		{"ss_ext_list_price", colPrice},
+		{"ss_ext_tax", colPrice},
+		{"ss_coupon_amt", colPrice},
+		{"ss_net_paid", colPrice},
+		{"ss_net_paid_inc_tax", colPrice},
+		{"ss_net_profit", colPrice},
+	},
+}
+
 // High-performance worker function for generating store sales with direct file writing
-func generateStoreSalesWorker(count int, startTicket int64, itemSampler, customerSampler, storeSampler, promoSampler *AliasSampler, filename string, rng *rand.Rand, format string) error {
+func generateStoreSalesWorker(ctx context.Context, count int, startTicket int64, dateSKs, timeSKs, cdemoSKs, hdemoSKs, addrSKs []int64, itemSampler, customerSampler, storeSampler, promoSampler *AliasSampler, returns returnsShard, filename string, rng *rand.Rand, opts formats.Options) (err error) {
 	if count <= 0 {
 		return nil
 	}
 
-	if format == "parquet" {
-		return generateStoreSalesWorkerParquet(count, startTicket, itemSampler, customerSampler, storeSampler, promoSampler, filename, rng)
-	}
-
 	startTime := time.Now()
-	file, err := os.Create(filename)
+	w, err := newFactShardWriter(&storeSalesTable, filename, opts, bufferSize)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriterSize(file, bufferSize)
-	defer writer.Flush()
+	defer closeShard(w, &err)
 
-	// Write header
-	header := "ss_sold_date_sk,ss_sold_time_sk,ss_item_sk,ss_customer_sk,ss_cdemo_sk,ss_hdemo_sk,ss_addr_sk,ss_store_sk,ss_promo_sk,ss_ticket_number,ss_quantity,ss_wholesale_cost,ss_list_price,ss_sales_price,ss_ext_discount_amt,ss_ext_sales_price,ss_ext_wholesale_cost,ss_ext_list_price,ss_ext_tax,ss_coupon_amt,ss_net_paid,ss_net_paid_inc_tax,ss_net_profit\n"
-	writer.WriteString(header)
-
-	// Pre-calculate common ranges for better performance
-	dateSKRange := 2000  // Date SK range (1-2000)
-	timeSKRange := 86400 // Time SK range (0-86399)
-	cdemoSKRange := 1000 // Customer demo SK range (1-1000)
-	hdemoSKRange := 1000 // Household demo SK range (1-1000)
-	addrSKRange := 1500  // Address SK range (1-1500)
+	returnsWriter, selector, err := openReturns(&storeReturnsTable, returns, count, opts)
+	if err != nil {
+		return err
+	}
+	defer closeShard(returnsWriter, &err)
 
-	// Pre-allocate buffer for row construction (increased to 4KB to reduce allocations)
-	rowBuf := make([]byte, 0, 4096)
+	row := make([]int64, 0, len(storeSalesTable.columns))
+	returnRow := make([]int64, 0, len(storeReturnsTable.columns))
+	var sale saleLine
 
 	for i := 0; i < count; i++ {
-		rowBuf = rowBuf[:0] // Reset buffer
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
 
 		quantity := rng.Intn(10) + 1
 		listPriceCents := int64(1000 + rng.Intn(100000))                 // 10.00 to 1010.00
 		salesPriceCents := listPriceCents * int64(80+rng.Intn(21)) / 100 // 80-100% of list price
 		wholesaleCents := salesPriceCents * int64(60+rng.Intn(21)) / 100 // 60-80% of sales price
 
-		// Build CSV row with byte-level formatting using weighted sampling and pre-calculated ranges
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(dateSKRange)+1), 10) // date_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(timeSKRange)), 10) // time_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, itemSampler.Sample(rng), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, customerSampler.Sample(rng), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(cdemoSKRange)+1), 10) // cdemo_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(hdemoSKRange)+1), 10) // hdemo_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(addrSKRange)+1), 10) // addr_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, storeSampler.Sample(rng), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, promoSampler.Sample(rng), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, startTicket+int64(i), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(quantity), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, wholesaleCents)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, listPriceCents)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, salesPriceCents)
-		rowBuf = append(rowBuf, ',')
+		returned := selector.next(rng)
+		dateIdx := soldDateIdx(rng, dateSKs, returned)
+		sale = saleLine{
+			soldDateIdx:    dateIdx,
+			soldDateSK:     dateSKs[dateIdx],
+			itemSK:         itemSampler.Sample(rng),
+			billCustomerSK: customerSampler.Sample(rng),
+			billCDemoSK:    cdemoSKs[rng.Intn(len(cdemoSKs))],
+			billHDemoSK:    hdemoSKs[rng.Intn(len(hdemoSKs))],
+			billAddrSK:     addrSKs[rng.Intn(len(addrSKs))],
+			storeSK:        storeSampler.Sample(rng),
+			number:         startTicket + int64(i),
+			quantity:       int64(quantity),
+			salesPrice:     salesPriceCents,
+			wholesaleCost:  wholesaleCents,
+		}
+		soldTimeSK := timeSKs[rng.Intn(len(timeSKs))]
+		promoSK := promoSampler.Sample(rng)
+
 		// Extended calculations
 		extDiscountAmt := (listPriceCents - salesPriceCents) * int64(quantity)
 		extSalesPrice := salesPriceCents * int64(quantity)
@@ -258,175 +335,44 @@ func generateStoreSalesWorker(count int, startTicket int64, itemSampler, custome
 		netPaidIncTax := netPaid + extTax
 		netProfit := netPaid - extWholesaleCost
 
-		rowBuf = appendPrice(rowBuf, extDiscountAmt)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extSalesPrice)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extWholesaleCost)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extListPrice)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, couponAmt)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaid)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netProfit)
-		rowBuf = append(rowBuf, '\n')
-
-		writer.Write(rowBuf)
-
-		// Periodic flush for better performance
-		if (i+1)%flushBatchSize == 0 {
-			writer.Flush()
-		}
-	}
-
-	duration := time.Since(startTime)
-	fmt.Printf("Worker completed: %d store sales records to %s in %s\n", count, filename, duration.Round(time.Millisecond))
-	return nil
-}
-
-func generateStoreSalesWorkerParquet(count int, startTicket int64, itemSampler, customerSampler, storeSampler, promoSampler *AliasSampler, filename string, rng *rand.Rand) (err error) {
-	startTime := time.Now()
-
-	dateSKRange := 2000
-	timeSKRange := 86400
-	cdemoSKRange := 1000
-	hdemoSKRange := 1000
-	addrSKRange := 1500
-
-	schema := arrow.NewSchema([]arrow.Field{
-		{Name: "ss_sold_date_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_sold_time_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_item_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_customer_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_cdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_hdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_addr_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_store_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_promo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_ticket_number", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ss_quantity", Type: arrow.PrimitiveTypes.Int32},
-		{Name: "ss_wholesale_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_list_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_sales_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_ext_discount_amt", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_ext_sales_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_ext_wholesale_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_ext_list_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_ext_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_coupon_amt", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_net_paid", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_net_paid_inc_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ss_net_profit", Type: arrow.PrimitiveTypes.Float64},
-	}, nil)
-
-	file, writer, builder, err := formats.CreateTypedParquetWriter(schema, filename)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err != nil && file != nil {
-			_ = file.Close()
-		}
-	}()
-	defer func() {
-		if closeErr := writer.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("error closing parquet writer for %s: %w", filename, closeErr)
+		row = append(row[:0],
+			sale.soldDateSK, soldTimeSK, sale.itemSK, sale.billCustomerSK,
+			sale.billCDemoSK, sale.billHDemoSK, sale.billAddrSK, sale.storeSK,
+			promoSK, sale.number, sale.quantity,
+			wholesaleCents, listPriceCents, salesPriceCents,
+			extDiscountAmt, extSalesPrice, extWholesaleCost, extListPrice, extTax,
+			couponAmt, netPaid, netPaidIncTax, netProfit,
+		)
+		if err = w.writeRow(row); err != nil {
+			return err
 		}
-	}()
-	defer builder.Release()
-
-	b0 := builder.Field(0).(*array.Int64Builder)
-	b1 := builder.Field(1).(*array.Int64Builder)
-	b2 := builder.Field(2).(*array.Int64Builder)
-	b3 := builder.Field(3).(*array.Int64Builder)
-	b4 := builder.Field(4).(*array.Int64Builder)
-	b5 := builder.Field(5).(*array.Int64Builder)
-	b6 := builder.Field(6).(*array.Int64Builder)
-	b7 := builder.Field(7).(*array.Int64Builder)
-	b8 := builder.Field(8).(*array.Int64Builder)
-	b9 := builder.Field(9).(*array.Int64Builder)
-	b10 := builder.Field(10).(*array.Int32Builder)
-	b11 := builder.Field(11).(*array.Float64Builder)
-	b12 := builder.Field(12).(*array.Float64Builder)
-	b13 := builder.Field(13).(*array.Float64Builder)
-	b14 := builder.Field(14).(*array.Float64Builder)
-	b15 := builder.Field(15).(*array.Float64Builder)
-	b16 := builder.Field(16).(*array.Float64Builder)
-	b17 := builder.Field(17).(*array.Float64Builder)
-	b18 := builder.Field(18).(*array.Float64Builder)
-	b19 := builder.Field(19).(*array.Float64Builder)
-	b20 := builder.Field(20).(*array.Float64Builder)
-	b21 := builder.Field(21).(*array.Float64Builder)
-	b22 := builder.Field(22).(*array.Float64Builder)
-
-	for i := 0; i < count; i++ {
-		quantity := rng.Intn(10) + 1
-		listPriceCents := int64(1000 + rng.Intn(100000))
-		salesPriceCents := listPriceCents * int64(80+rng.Intn(21)) / 100
-		wholesaleCents := salesPriceCents * int64(60+rng.Intn(21)) / 100
-
-		extDiscountAmt := (listPriceCents - salesPriceCents) * int64(quantity)
-		extSalesPrice := salesPriceCents * int64(quantity)
-		extWholesaleCost := wholesaleCents * int64(quantity)
-		extListPrice := listPriceCents * int64(quantity)
-		extTax := extSalesPrice * 8 / 100
-		netPaid := extSalesPrice - extDiscountAmt
-		netPaidIncTax := netPaid + extTax
-		netProfit := netPaid - extWholesaleCost
 
-		b0.Append(int64(rng.Intn(dateSKRange) + 1))
-		b1.Append(int64(rng.Intn(timeSKRange)))
-		b2.Append(itemSampler.Sample(rng))
-		b3.Append(customerSampler.Sample(rng))
-		b4.Append(int64(rng.Intn(cdemoSKRange) + 1))
-		b5.Append(int64(rng.Intn(hdemoSKRange) + 1))
-		b6.Append(int64(rng.Intn(addrSKRange) + 1))
-		b7.Append(storeSampler.Sample(rng))
-		b8.Append(promoSampler.Sample(rng))
-		b9.Append(startTicket + int64(i))
-		b10.Append(int32(quantity))
-		b11.Append(float64(wholesaleCents) / 100.0)
-		b12.Append(float64(listPriceCents) / 100.0)
-		b13.Append(float64(salesPriceCents) / 100.0)
-		b14.Append(float64(extDiscountAmt) / 100.0)
-		b15.Append(float64(extSalesPrice) / 100.0)
-		b16.Append(float64(extWholesaleCost) / 100.0)
-		b17.Append(float64(extListPrice) / 100.0)
-		b18.Append(float64(extTax) / 100.0)
-		b19.Append(0.0)
-		b20.Append(float64(netPaid) / 100.0)
-		b21.Append(float64(netPaidIncTax) / 100.0)
-		b22.Append(float64(netProfit) / 100.0)
-
-		if (i+1)%65536 == 0 {
-			if err = formats.WriteTypedBatch(writer, builder, filename); err != nil {
+		if returned {
+			returnRow = storeReturnRow(returnRow, rng, &returns, &sale)
+			if err = returnsWriter.writeRow(returnRow); err != nil {
 				return err
 			}
 		}
 	}
 
-	if count%65536 != 0 {
-		if err = formats.WriteTypedBatch(writer, builder, filename); err != nil {
-			return err
-		}
-	}
-
 	duration := time.Since(startTime)
-	fmt.Printf("Worker completed: %d store sales records to %s in %s\n", count, filename, duration.Round(time.Millisecond))
+	fmt.Printf("Worker completed: %d store sales records (%d returns) to %s in %s\n", count, returns.count, filename, duration.Round(time.Millisecond))
 	return nil
 }
 
-// GenerateStoreSalesOptimized generates store sales using worker-based file sharding
-func GenerateStoreSalesOptimized(count int, itemSKs, customerSKs, storeSKs, promoSKs []int64, outputDir string, format string) error {
+// GenerateStoreSalesOptimized generates store sales using worker-based file sharding.
+// Each worker also derives its share of returnCount store returns from the lines it emits.
+// The first worker to fail cancels the remaining shards and its error is returned.
+func GenerateStoreSalesOptimized(ctx context.Context, count, returnCount int, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, storeSKs, promoSKs, reasonSKs []int64, outputDir string, opts formats.Options, seed int64) error {
 	if count <= 0 {
 		return nil
 	}
+	if err := requireDimensionSKs("store sales",
+		dimensionSKs{"dim_date", dateSKs}, dimensionSKs{"dim_time", timeSKs},
+		dimensionSKs{"dim_customer_demographics", cdemoSKs}, dimensionSKs{"dim_household_demographics", hdemoSKs},
+		dimensionSKs{"dim_customer_addresses", addrSKs}); err != nil {
+		return err
+	}
 
 	// Create weighted samplers for key dimensions
 	itemSampler, err := NewAliasSampler64(itemSKs)
@@ -446,123 +392,141 @@ func GenerateStoreSalesOptimized(count int, itemSKs, customerSKs, storeSKs, prom
 		return fmt.Errorf("failed to create promo sampler: %w", err)
 	}
 
-	numWorkers := runtime.NumCPU()
-	recordsPerWorker := count / numWorkers
-	extraRecords := count % numWorkers
+	numShards := common.ShardCount(count)
+	workerRecords := shardRecords(count, numShards)
+	workerReturns := splitReturns(returnCount, count, workerRecords)
 
-	var wg sync.WaitGroup
-	baseSeed := time.Now().UnixNano()
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
 	startTicket := int64(1)
 
-	ext := ".csv"
-	if format == "parquet" {
-		ext = ".parquet"
-	}
-
-	for i := 0; i < numWorkers; i++ {
-		workerRecords := recordsPerWorker
-		if i < extraRecords {
-			workerRecords++
-		}
+	for i := 0; i < numShards; i++ {
+		if workerRecords[i] > 0 {
+			records, ticket := workerRecords[i], startTicket
+			rng := common.NewRand(seed, "fact_store_sales", i)
+			filename := opts.ShardPath(outputDir, "fact_store_sales", i, numShards)
+			returns := returnsShard{
+				count:     workerReturns[i],
+				reasonSKs: reasonSKs,
+				dateSKs:   dateSKs,
+				timeSKs:   timeSKs,
+				filename:  opts.ShardPath(outputDir, "fact_store_returns", i, numShards),
+			}
 
-		if workerRecords > 0 {
-			wg.Add(1)
-			workerSeed := baseSeed + int64(i)*int64(0x9e3779b9)
-			rng := rand.New(rand.NewSource(workerSeed))
-			filename := fmt.Sprintf("%s/fact_store_sales_%d%s", outputDir, i, ext)
-			workerStartTicket := startTicket + int64(i*recordsPerWorker)
-
-			go func(records int, ticket int64, fname string, workerRNG *rand.Rand) {
-				defer wg.Done()
-				if err := generateStoreSalesWorker(records, ticket, itemSampler, customerSampler, storeSampler, promoSampler, fname, workerRNG, format); err != nil {
-					fmt.Printf("Error in store sales worker: %v\n", err)
+			g.Go(func() error {
+				if shardDone(&storeSalesTable, filename, &storeReturnsTable, returns, opts) {
+					return nil
 				}
-			}(workerRecords, workerStartTicket, filename, rng)
+				return generateStoreSalesWorker(ctx, records, ticket, dateSKs, timeSKs, cdemoSKs, hdemoSKs, addrSKs, itemSampler, customerSampler, storeSampler, promoSampler, returns, filename, rng, opts)
+			})
 		}
+		startTicket += int64(workerRecords[i])
 	}
 
-	wg.Wait()
-	return nil
+	return g.Wait()
+}
+
+var catalogSalesTable = factTable{
+	name: "fact_catalog_sales",
+	columns: []factColumn{
+		{"cs_sold_date_sk", colKey},
+		{"cs_sold_time_sk", colKey},
+		{"cs_ship_date_sk", colKey},
+		{"cs_bill_customer_sk", colKey},
+		{"cs_bill_cdemo_sk", colKey},
+		{"cs_bill_hdemo_sk", colKey},
+		{"cs_bill_addr_sk", colKey},
+		{"cs_ship_customer_sk", colKey},
+		{"cs_ship_cdemo_sk", colKey},
+		{"cs_ship_hdemo_sk", colKey},
+		{"cs_ship_addr_sk", colKey},
+		{"cs_call_center_sk", colKey},
+		{"cs_catalog_page_sk", colKey},
+		{"cs_ship_mode_sk", colKey},
+		{"cs_warehouse_sk", colKey},
+		{"cs_item_sk", colKey},
+		{"cs_promo_sk", colKey},
+		{"cs_order_number", colKey},
+		{"cs_quantity", colQuantity},
+		{"cs_wholesale_cost", colPrice},
+		{"cs_list_price", colPrice},
+		{"cs_sales_price", colPrice},
+		{"cs_ext_discount_amt", colPrice},
+		{"cs_ext_sales_price", colPrice},
+		{"cs_ext_wholesale_cost", colPrice},
+		{"cs_ext_list_price", colPrice},
+		{"cs_ext_tax", colPrice},
+		{"cs_coupon_amt", colPrice},
+		{"cs_ext_ship_cost", colPrice},
+		{"cs_net_paid", colPrice},
+		{"cs_net_paid_inc_tax", colPrice},
+		{"cs_net_paid_inc_ship", colPrice},
+		{"cs_net_paid_inc_ship_tax", colPrice},
+		{"cs_net_profit", colPrice},
+	},
 }
 
 // High-performance worker function for generating catalog sales with direct file writing
-func generateCatalogSalesWorker(count int, startOrder int64, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs []int64, filename string, rng *rand.Rand, format string) error {
+func generateCatalogSalesWorker(ctx context.Context, count int, startOrder int64, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs []int64, returns returnsShard, filename string, rng *rand.Rand, opts formats.Options) (err error) {
 	if count <= 0 {
 		return nil
 	}
 
-	if format == "parquet" {
-		return generateCatalogSalesWorkerParquet(count, startOrder, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs, filename, rng)
-	}
-
 	startTime := time.Now()
-	file, err := os.Create(filename)
+	w, err := newFactShardWriter(&catalogSalesTable, filename, opts, bufferSize)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
+	defer closeShard(w, &err)
 
-	writer := bufio.NewWriterSize(file, bufferSize)
-	defer writer.Flush()
-
-	// Write header
-	header := "cs_sold_date_sk,cs_sold_time_sk,cs_ship_date_sk,cs_bill_customer_sk,cs_bill_cdemo_sk,cs_bill_hdemo_sk,cs_bill_addr_sk,cs_ship_customer_sk,cs_ship_cdemo_sk,cs_ship_hdemo_sk,cs_ship_addr_sk,cs_call_center_sk,cs_catalog_page_sk,cs_ship_mode_sk,cs_warehouse_sk,cs_item_sk,cs_promo_sk,cs_order_number,cs_quantity,cs_wholesale_cost,cs_list_price,cs_sales_price,cs_ext_discount_amt,cs_ext_sales_price,cs_ext_wholesale_cost,cs_ext_list_price,cs_ext_tax,cs_coupon_amt,cs_ext_ship_cost,cs_net_paid,cs_net_paid_inc_tax,cs_net_paid_inc_ship,cs_net_paid_inc_ship_tax,cs_net_profit\n"
-	writer.WriteString(header)
+	returnsWriter, selector, err := openReturns(&catalogReturnsTable, returns, count, opts)
+	if err != nil {
+		return err
+	}
+	defer closeShard(returnsWriter, &err)
 
-	rowBuf := make([]byte, 0, 4096) // Increased to 4KB to reduce allocations
+	row := make([]int64, 0, len(catalogSalesTable.columns))
+	returnRow := make([]int64, 0, len(catalogReturnsTable.columns))
+	var sale saleLine
 
 	for i := 0; i < count; i++ {
-		rowBuf = rowBuf[:0]
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
 
 		quantity := rng.Intn(10) + 1
 		listPriceCents := int64(1000 + rng.Intn(100000))
 		salesPriceCents := listPriceCents * int64(80+rng.Intn(21)) / 100
 		wholesaleCents := salesPriceCents * int64(60+rng.Intn(21)) / 100
 
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(2000)+1), 10) // sold_date_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(86400)), 10) // sold_time_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(2000)+1), 10) // ship_date_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, customerSKs[rng.Intn(len(customerSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, cdemoSKs[rng.Intn(len(cdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, hdemoSKs[rng.Intn(len(hdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, addrSKs[rng.Intn(len(addrSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, customerSKs[rng.Intn(len(customerSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, cdemoSKs[rng.Intn(len(cdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, hdemoSKs[rng.Intn(len(hdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, addrSKs[rng.Intn(len(addrSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, callCenterSKs[rng.Intn(len(callCenterSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, catalogPageSKs[rng.Intn(len(catalogPageSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, shipModeSKs[rng.Intn(len(shipModeSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, warehouseSKs[rng.Intn(len(warehouseSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, itemSKs[rng.Intn(len(itemSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, promoSKs[rng.Intn(len(promoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, startOrder+int64(i), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(quantity), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, wholesaleCents)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, listPriceCents)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, salesPriceCents)
-		rowBuf = append(rowBuf, ',')
+		returned := selector.next(rng)
+		dateIdx := soldDateIdx(rng, dateSKs, returned)
+		sale = saleLine{
+			soldDateIdx:    dateIdx,
+			soldDateSK:     dateSKs[dateIdx],
+			billCustomerSK: customerSKs[rng.Intn(len(customerSKs))],
+			billCDemoSK:    cdemoSKs[rng.Intn(len(cdemoSKs))],
+			billHDemoSK:    hdemoSKs[rng.Intn(len(hdemoSKs))],
+			billAddrSK:     addrSKs[rng.Intn(len(addrSKs))],
+			shipCustomerSK: customerSKs[rng.Intn(len(customerSKs))],
+			shipCDemoSK:    cdemoSKs[rng.Intn(len(cdemoSKs))],
+			shipHDemoSK:    hdemoSKs[rng.Intn(len(hdemoSKs))],
+			shipAddrSK:     addrSKs[rng.Intn(len(addrSKs))],
+			callCenterSK:   callCenterSKs[rng.Intn(len(callCenterSKs))],
+			catalogPageSK:  catalogPageSKs[rng.Intn(len(catalogPageSKs))],
+			shipModeSK:     shipModeSKs[rng.Intn(len(shipModeSKs))],
+			warehouseSK:    warehouseSKs[rng.Intn(len(warehouseSKs))],
+			itemSK:         itemSKs[rng.Intn(len(itemSKs))],
+			number:         startOrder + int64(i),
+			quantity:       int64(quantity),
+			salesPrice:     salesPriceCents,
+			wholesaleCost:  wholesaleCents,
+		}
+		soldTimeSK := timeSKs[rng.Intn(len(timeSKs))]
+		shipDate := shipDateSK(rng, dateSKs, sale.soldDateIdx)
+		promoSK := promoSKs[rng.Intn(len(promoSKs))]
 
 		extDiscountAmt := (listPriceCents - salesPriceCents) * int64(quantity)
 		extSalesPrice := salesPriceCents * int64(quantity)
@@ -577,328 +541,184 @@ func generateCatalogSalesWorker(count int, startOrder int64, itemSKs, customerSK
 		netPaidIncShipTax := netPaidIncShip + extTax
 		netProfit := netPaid - extWholesaleCost
 
-		rowBuf = appendPrice(rowBuf, extDiscountAmt)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extSalesPrice)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extWholesaleCost)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extListPrice)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, couponAmt)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extShipCost)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaid)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncShip)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncShipTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netProfit)
-		rowBuf = append(rowBuf, '\n')
-
-		writer.Write(rowBuf)
-
-		if (i+1)%flushBatchSize == 0 {
-			writer.Flush()
-		}
-	}
-
-	duration := time.Since(startTime)
-	fmt.Printf("Worker completed: %d catalog sales records to %s in %s\n", count, filename, duration.Round(time.Millisecond))
-	return nil
-}
-
-func generateCatalogSalesWorkerParquet(count int, startOrder int64, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs []int64, filename string, rng *rand.Rand) (err error) {
-	startTime := time.Now()
-
-	schema := arrow.NewSchema([]arrow.Field{
-		{Name: "cs_sold_date_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_sold_time_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_ship_date_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_bill_customer_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_bill_cdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_bill_hdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_bill_addr_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_ship_customer_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_ship_cdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_ship_hdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_ship_addr_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_call_center_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_catalog_page_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_ship_mode_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_warehouse_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_item_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_promo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_order_number", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "cs_quantity", Type: arrow.PrimitiveTypes.Int32},
-		{Name: "cs_wholesale_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_list_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_sales_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_ext_discount_amt", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_ext_sales_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_ext_wholesale_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_ext_list_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_ext_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_coupon_amt", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_ext_ship_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_net_paid", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_net_paid_inc_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_net_paid_inc_ship", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_net_paid_inc_ship_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "cs_net_profit", Type: arrow.PrimitiveTypes.Float64},
-	}, nil)
-
-	file, writer, builder, err := formats.CreateTypedParquetWriter(schema, filename)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err != nil && file != nil {
-			_ = file.Close()
-		}
-	}()
-	defer func() {
-		if closeErr := writer.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("error closing parquet writer for %s: %w", filename, closeErr)
+		row = append(row[:0],
+			sale.soldDateSK, soldTimeSK, shipDate,
+			sale.billCustomerSK, sale.billCDemoSK, sale.billHDemoSK, sale.billAddrSK,
+			sale.shipCustomerSK, sale.shipCDemoSK, sale.shipHDemoSK, sale.shipAddrSK,
+			sale.callCenterSK, sale.catalogPageSK, sale.shipModeSK, sale.warehouseSK,
+			sale.itemSK, promoSK, sale.number, sale.quantity,
+			wholesaleCents, listPriceCents, salesPriceCents,
+			extDiscountAmt, extSalesPrice, extWholesaleCost, extListPrice, extTax,
+			couponAmt, extShipCost, netPaid, netPaidIncTax, netPaidIncShip, netPaidIncShipTax, netProfit,
+		)
+		if err = w.writeRow(row); err != nil {
+			return err
 		}
-	}()
-	defer builder.Release()
-
-	b0 := builder.Field(0).(*array.Int64Builder)
-	b1 := builder.Field(1).(*array.Int64Builder)
-	b2 := builder.Field(2).(*array.Int64Builder)
-	b3 := builder.Field(3).(*array.Int64Builder)
-	b4 := builder.Field(4).(*array.Int64Builder)
-	b5 := builder.Field(5).(*array.Int64Builder)
-	b6 := builder.Field(6).(*array.Int64Builder)
-	b7 := builder.Field(7).(*array.Int64Builder)
-	b8 := builder.Field(8).(*array.Int64Builder)
-	b9 := builder.Field(9).(*array.Int64Builder)
-	b10 := builder.Field(10).(*array.Int64Builder)
-	b11 := builder.Field(11).(*array.Int64Builder)
-	b12 := builder.Field(12).(*array.Int64Builder)
-	b13 := builder.Field(13).(*array.Int64Builder)
-	b14 := builder.Field(14).(*array.Int64Builder)
-	b15 := builder.Field(15).(*array.Int64Builder)
-	b16 := builder.Field(16).(*array.Int64Builder)
-	b17 := builder.Field(17).(*array.Int64Builder)
-	b18 := builder.Field(18).(*array.Int32Builder)
-	b19 := builder.Field(19).(*array.Float64Builder)
-	b20 := builder.Field(20).(*array.Float64Builder)
-	b21 := builder.Field(21).(*array.Float64Builder)
-	b22 := builder.Field(22).(*array.Float64Builder)
-	b23 := builder.Field(23).(*array.Float64Builder)
-	b24 := builder.Field(24).(*array.Float64Builder)
-	b25 := builder.Field(25).(*array.Float64Builder)
-	b26 := builder.Field(26).(*array.Float64Builder)
-	b27 := builder.Field(27).(*array.Float64Builder)
-	b28 := builder.Field(28).(*array.Float64Builder)
-	b29 := builder.Field(29).(*array.Float64Builder)
-	b30 := builder.Field(30).(*array.Float64Builder)
-	b31 := builder.Field(31).(*array.Float64Builder)
-	b32 := builder.Field(32).(*array.Float64Builder)
-	b33 := builder.Field(33).(*array.Float64Builder)
-
-	for i := 0; i < count; i++ {
-		quantity := rng.Intn(10) + 1
-		listPriceCents := int64(1000 + rng.Intn(100000))
-		salesPriceCents := listPriceCents * int64(80+rng.Intn(21)) / 100
-		wholesaleCents := salesPriceCents * int64(60+rng.Intn(21)) / 100
-
-		extDiscountAmt := (listPriceCents - salesPriceCents) * int64(quantity)
-		extSalesPrice := salesPriceCents * int64(quantity)
-		extWholesaleCost := wholesaleCents * int64(quantity)
-		extListPrice := listPriceCents * int64(quantity)
-		extTax := extSalesPrice * 8 / 100
-		extShipCost := int64(500 + rng.Intn(2000))
-		netPaid := extSalesPrice - extDiscountAmt
-		netPaidIncTax := netPaid + extTax
-		netPaidIncShip := netPaid + extShipCost
-		netPaidIncShipTax := netPaidIncShip + extTax
-		netProfit := netPaid - extWholesaleCost
 
-		b0.Append(int64(rng.Intn(2000) + 1))
-		b1.Append(int64(rng.Intn(86400)))
-		b2.Append(int64(rng.Intn(2000) + 1))
-		b3.Append(customerSKs[rng.Intn(len(customerSKs))])
-		b4.Append(cdemoSKs[rng.Intn(len(cdemoSKs))])
-		b5.Append(hdemoSKs[rng.Intn(len(hdemoSKs))])
-		b6.Append(addrSKs[rng.Intn(len(addrSKs))])
-		b7.Append(customerSKs[rng.Intn(len(customerSKs))])
-		b8.Append(cdemoSKs[rng.Intn(len(cdemoSKs))])
-		b9.Append(hdemoSKs[rng.Intn(len(hdemoSKs))])
-		b10.Append(addrSKs[rng.Intn(len(addrSKs))])
-		b11.Append(callCenterSKs[rng.Intn(len(callCenterSKs))])
-		b12.Append(catalogPageSKs[rng.Intn(len(catalogPageSKs))])
-		b13.Append(shipModeSKs[rng.Intn(len(shipModeSKs))])
-		b14.Append(warehouseSKs[rng.Intn(len(warehouseSKs))])
-		b15.Append(itemSKs[rng.Intn(len(itemSKs))])
-		b16.Append(promoSKs[rng.Intn(len(promoSKs))])
-		b17.Append(startOrder + int64(i))
-		b18.Append(int32(quantity))
-		b19.Append(float64(wholesaleCents) / 100.0)
-		b20.Append(float64(listPriceCents) / 100.0)
-		b21.Append(float64(salesPriceCents) / 100.0)
-		b22.Append(float64(extDiscountAmt) / 100.0)
-		b23.Append(float64(extSalesPrice) / 100.0)
-		b24.Append(float64(extWholesaleCost) / 100.0)
-		b25.Append(float64(extListPrice) / 100.0)
-		b26.Append(float64(extTax) / 100.0)
-		b27.Append(0.0)
-		b28.Append(float64(extShipCost) / 100.0)
-		b29.Append(float64(netPaid) / 100.0)
-		b30.Append(float64(netPaidIncTax) / 100.0)
-		b31.Append(float64(netPaidIncShip) / 100.0)
-		b32.Append(float64(netPaidIncShipTax) / 100.0)
-		b33.Append(float64(netProfit) / 100.0)
-
-		if (i+1)%65536 == 0 {
-			if err = formats.WriteTypedBatch(writer, builder, filename); err != nil {
+		if returned {
+			returnRow = catalogReturnRow(returnRow, rng, &returns, &sale)
+			if err = returnsWriter.writeRow(returnRow); err != nil {
 				return err
 			}
 		}
 	}
 
-	if count%65536 != 0 {
-		if err = formats.WriteTypedBatch(writer, builder, filename); err != nil {
-			return err
-		}
-	}
-
 	duration := time.Since(startTime)
-	fmt.Printf("Worker completed: %d catalog sales records to %s in %s\n", count, filename, duration.Round(time.Millisecond))
+	fmt.Printf("Worker completed: %d catalog sales records (%d returns) to %s in %s\n", count, returns.count, filename, duration.Round(time.Millisecond))
 	return nil
 }
 
-// GenerateCatalogSalesOptimized generates catalog sales using worker-based file sharding
-func GenerateCatalogSalesOptimized(count int, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs []int64, outputDir string, format string) error {
+// GenerateCatalogSalesOptimized generates catalog sales using worker-based file sharding.
+// Each worker also derives its share of returnCount catalog returns from the lines it emits.
+func GenerateCatalogSalesOptimized(ctx context.Context, count, returnCount int, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs, reasonSKs []int64, outputDir string, opts formats.Options, seed int64) error {
 	if count <= 0 {
 		return nil
 	}
-
-	numWorkers := runtime.NumCPU()
-	recordsPerWorker := count / numWorkers
-	extraRecords := count % numWorkers
-
-	ext := ".csv"
-	if format == "parquet" {
-		ext = ".parquet"
+	if err := requireDimensionSKs("catalog sales",
+		dimensionSKs{"dim_date", dateSKs}, dimensionSKs{"dim_time", timeSKs},
+		dimensionSKs{"dim_items", itemSKs}, dimensionSKs{"dim_customers", customerSKs},
+		dimensionSKs{"dim_customer_demographics", cdemoSKs}, dimensionSKs{"dim_household_demographics", hdemoSKs},
+		dimensionSKs{"dim_customer_addresses", addrSKs}, dimensionSKs{"dim_call_centers", callCenterSKs},
+		dimensionSKs{"dim_catalog_pages", catalogPageSKs}, dimensionSKs{"dim_ship_modes", shipModeSKs},
+		dimensionSKs{"dim_warehouses", warehouseSKs}, dimensionSKs{"dim_promotions", promoSKs}); err != nil {
+		return err
 	}
 
-	var wg sync.WaitGroup
-	baseSeed := time.Now().UnixNano()
+	numShards := common.ShardCount(count)
+	workerRecords := shardRecords(count, numShards)
+	workerReturns := splitReturns(returnCount, count, workerRecords)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
 	startOrder := int64(1)
 
-	for i := 0; i < numWorkers; i++ {
-		workerRecords := recordsPerWorker
-		if i < extraRecords {
-			workerRecords++
-		}
+	for i := 0; i < numShards; i++ {
+		if workerRecords[i] > 0 {
+			records, order := workerRecords[i], startOrder
+			rng := common.NewRand(seed, "fact_catalog_sales", i)
+			filename := opts.ShardPath(outputDir, "fact_catalog_sales", i, numShards)
+			returns := returnsShard{
+				count:     workerReturns[i],
+				reasonSKs: reasonSKs,
+				dateSKs:   dateSKs,
+				timeSKs:   timeSKs,
+				filename:  opts.ShardPath(outputDir, "fact_catalog_returns", i, numShards),
+			}
 
-		if workerRecords > 0 {
-			wg.Add(1)
-			workerSeed := baseSeed + int64(i)*int64(0x9e3779b9)
-			rng := rand.New(rand.NewSource(workerSeed))
-			filename := fmt.Sprintf("%s/fact_catalog_sales_%d%s", outputDir, i, ext)
-			workerStartOrder := startOrder + int64(i*recordsPerWorker)
-
-			go func(records int, order int64, fname string, workerRNG *rand.Rand) {
-				defer wg.Done()
-				if err := generateCatalogSalesWorker(records, order, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs, fname, workerRNG, format); err != nil {
-					fmt.Printf("Error in catalog sales worker: %v\n", err)
+			g.Go(func() error {
+				if shardDone(&catalogSalesTable, filename, &catalogReturnsTable, returns, opts) {
+					return nil
 				}
-			}(workerRecords, workerStartOrder, filename, rng)
+				return generateCatalogSalesWorker(ctx, records, order, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, callCenterSKs, catalogPageSKs, shipModeSKs, warehouseSKs, promoSKs, returns, filename, rng, opts)
+			})
 		}
+		startOrder += int64(workerRecords[i])
 	}
 
-	wg.Wait()
-	return nil
+	return g.Wait()
+}
+
+var webSalesTable = factTable{
+	name: "fact_web_sales",
+	columns: []factColumn{
+		{"ws_sold_date_sk", colKey},
+		{"ws_sold_time_sk", colKey},
+		{"ws_ship_date_sk", colKey},
+		{"ws_item_sk", colKey},
+		{"ws_bill_customer_sk", colKey},
+		{"ws_bill_cdemo_sk", colKey},
+		{"ws_bill_hdemo_sk", colKey},
+		{"ws_bill_addr_sk", colKey},
+		{"ws_ship_customer_sk", colKey},
+		{"ws_ship_cdemo_sk", colKey},
+		{"ws_ship_hdemo_sk", colKey},
+		{"ws_ship_addr_sk", colKey},
+		{"ws_web_page_sk", colKey},
+		{"ws_web_site_sk", colKey},
+		{"ws_ship_mode_sk", colKey},
+		{"ws_warehouse_sk", colKey},
+		{"ws_promo_sk", colKey},
+		{"ws_order_number", colKey},
+		{"ws_quantity", colQuantity},
+		{"ws_wholesale_cost", colPrice},
+		{"ws_list_price", colPrice},
+		{"ws_sales_price", colPrice},
+		{"ws_ext_discount_amt", colPrice},
+		{"ws_ext_sales_price", colPrice},
+		{"ws_ext_wholesale_cost", colPrice},
+		{"ws_ext_list_price", colPrice},
+		{"ws_ext_tax", colPrice},
+		{"ws_coupon_amt", colPrice},
+		{"ws_ext_ship_cost", colPrice},
+		{"ws_net_paid", colPrice},
+		{"ws_net_paid_inc_tax", colPrice},
+		{"ws_net_paid_inc_ship", colPrice},
+		{"ws_net_paid_inc_ship_tax", colPrice},
+		{"ws_net_profit", colPrice},
+	},
 }
 
 // High-performance worker function for generating web sales with direct file writing
-func generateWebSalesWorker(count int, startOrder int64, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs []int64, filename string, rng *rand.Rand, format string) error {
+func generateWebSalesWorker(ctx context.Context, count int, startOrder int64, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs []int64, returns returnsShard, filename string, rng *rand.Rand, opts formats.Options) (err error) {
 	if count <= 0 {
 		return nil
 	}
 
-	if format == "parquet" {
-		return generateWebSalesWorkerParquet(count, startOrder, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs, filename, rng)
-	}
-
 	startTime := time.Now()
-	file, err := os.Create(filename)
+	w, err := newFactShardWriter(&webSalesTable, filename, opts, bufferSize)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriterSize(file, bufferSize)
-	defer writer.Flush()
+	defer closeShard(w, &err)
 
-	// Write header
-	header := "ws_sold_date_sk,ws_sold_time_sk,ws_ship_date_sk,ws_item_sk,ws_bill_customer_sk,ws_bill_cdemo_sk,ws_bill_hdemo_sk,ws_bill_addr_sk,ws_ship_customer_sk,ws_ship_cdemo_sk,ws_ship_hdemo_sk,ws_ship_addr_sk,ws_web_page_sk,ws_web_site_sk,ws_ship_mode_sk,ws_warehouse_sk,ws_promo_sk,ws_order_number,ws_quantity,ws_wholesale_cost,ws_list_price,ws_sales_price,ws_ext_discount_amt,ws_ext_sales_price,ws_ext_wholesale_cost,ws_ext_list_price,ws_ext_tax,ws_coupon_amt,ws_ext_ship_cost,ws_net_paid,ws_net_paid_inc_tax,ws_net_paid_inc_ship,ws_net_paid_inc_ship_tax,ws_net_profit\n"
-	writer.WriteString(header)
+	returnsWriter, selector, err := openReturns(&webReturnsTable, returns, count, opts)
+	if err != nil {
+		return err
+	}
+	defer closeShard(returnsWriter, &err)
 
-	rowBuf := make([]byte, 0, 4096) // Increased to 4KB to reduce allocations
+	row := make([]int64, 0, len(webSalesTable.columns))
+	returnRow := make([]int64, 0, len(webReturnsTable.columns))
+	var sale saleLine
 
 	for i := 0; i < count; i++ {
-		rowBuf = rowBuf[:0]
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+		}
 
 		quantity := rng.Intn(10) + 1
 		listPriceCents := int64(1000 + rng.Intn(100000))
 		salesPriceCents := listPriceCents * int64(80+rng.Intn(21)) / 100
 		wholesaleCents := salesPriceCents * int64(60+rng.Intn(21)) / 100
 
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(2000)+1), 10) // sold_date_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(86400)), 10) // sold_time_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(rng.Intn(2000)+1), 10) // ship_date_sk
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, itemSKs[rng.Intn(len(itemSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, customerSKs[rng.Intn(len(customerSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, cdemoSKs[rng.Intn(len(cdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, hdemoSKs[rng.Intn(len(hdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, addrSKs[rng.Intn(len(addrSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, customerSKs[rng.Intn(len(customerSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, cdemoSKs[rng.Intn(len(cdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, hdemoSKs[rng.Intn(len(hdemoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, addrSKs[rng.Intn(len(addrSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, webPageSKs[rng.Intn(len(webPageSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, webSiteSKs[rng.Intn(len(webSiteSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, shipModeSKs[rng.Intn(len(shipModeSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, warehouseSKs[rng.Intn(len(warehouseSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, promoSKs[rng.Intn(len(promoSKs))], 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, startOrder+int64(i), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = strconv.AppendInt(rowBuf, int64(quantity), 10)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, wholesaleCents)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, listPriceCents)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, salesPriceCents)
-		rowBuf = append(rowBuf, ',')
+		returned := selector.next(rng)
+		dateIdx := soldDateIdx(rng, dateSKs, returned)
+		sale = saleLine{
+			soldDateIdx:    dateIdx,
+			soldDateSK:     dateSKs[dateIdx],
+			itemSK:         itemSKs[rng.Intn(len(itemSKs))],
+			billCustomerSK: customerSKs[rng.Intn(len(customerSKs))],
+			billCDemoSK:    cdemoSKs[rng.Intn(len(cdemoSKs))],
+			billHDemoSK:    hdemoSKs[rng.Intn(len(hdemoSKs))],
+			billAddrSK:     addrSKs[rng.Intn(len(addrSKs))],
+			shipCustomerSK: customerSKs[rng.Intn(len(customerSKs))],
+			shipCDemoSK:    cdemoSKs[rng.Intn(len(cdemoSKs))],
+			shipHDemoSK:    hdemoSKs[rng.Intn(len(hdemoSKs))],
+			shipAddrSK:     addrSKs[rng.Intn(len(addrSKs))],
+			webPageSK:      webPageSKs[rng.Intn(len(webPageSKs))],
+			number:         startOrder + int64(i),
+			quantity:       int64(quantity),
+			salesPrice:     salesPriceCents,
+			wholesaleCost:  wholesaleCents,
+		}
+		soldTimeSK := timeSKs[rng.Intn(len(timeSKs))]
+		shipDate := shipDateSK(rng, dateSKs, sale.soldDateIdx)
+		webSiteSK := webSiteSKs[rng.Intn(len(webSiteSKs))]
+		shipModeSK := shipModeSKs[rng.Intn(len(shipModeSKs))]
+		warehouseSK := warehouseSKs[rng.Intn(len(warehouseSKs))]
+		promoSK := promoSKs[rng.Intn(len(promoSKs))]
 
 		extDiscountAmt := (listPriceCents - salesPriceCents) * int64(quantity)
 		extSalesPrice := salesPriceCents * int64(quantity)
@@ -913,248 +733,81 @@ func generateWebSalesWorker(count int, startOrder int64, itemSKs, customerSKs, c
 		netPaidIncShipTax := netPaidIncShip + extTax
 		netProfit := netPaid - extWholesaleCost
 
-		rowBuf = appendPrice(rowBuf, extDiscountAmt)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extSalesPrice)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extWholesaleCost)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extListPrice)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, couponAmt)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, extShipCost)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaid)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncShip)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netPaidIncShipTax)
-		rowBuf = append(rowBuf, ',')
-		rowBuf = appendPrice(rowBuf, netProfit)
-		rowBuf = append(rowBuf, '\n')
-
-		writer.Write(rowBuf)
-
-		if (i+1)%flushBatchSize == 0 {
-			writer.Flush()
-		}
-	}
-
-	duration := time.Since(startTime)
-	fmt.Printf("Worker completed: %d web sales records to %s in %s\n", count, filename, duration.Round(time.Millisecond))
-	return nil
-}
-
-func generateWebSalesWorkerParquet(count int, startOrder int64, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs []int64, filename string, rng *rand.Rand) (err error) {
-	startTime := time.Now()
-
-	schema := arrow.NewSchema([]arrow.Field{
-		{Name: "ws_sold_date_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_sold_time_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_ship_date_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_item_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_bill_customer_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_bill_cdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_bill_hdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_bill_addr_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_ship_customer_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_ship_cdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_ship_hdemo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_ship_addr_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_web_page_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_web_site_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_ship_mode_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_warehouse_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_promo_sk", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_order_number", Type: arrow.PrimitiveTypes.Int64},
-		{Name: "ws_quantity", Type: arrow.PrimitiveTypes.Int32},
-		{Name: "ws_wholesale_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_list_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_sales_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_ext_discount_amt", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_ext_sales_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_ext_wholesale_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_ext_list_price", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_ext_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_coupon_amt", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_ext_ship_cost", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_net_paid", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_net_paid_inc_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_net_paid_inc_ship", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_net_paid_inc_ship_tax", Type: arrow.PrimitiveTypes.Float64},
-		{Name: "ws_net_profit", Type: arrow.PrimitiveTypes.Float64},
-	}, nil)
-
-	file, writer, builder, err := formats.CreateTypedParquetWriter(schema, filename)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err != nil && file != nil {
-			_ = file.Close()
-		}
-	}()
-	defer func() {
-		if closeErr := writer.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("error closing parquet writer for %s: %w", filename, closeErr)
+		row = append(row[:0],
+			sale.soldDateSK, soldTimeSK, shipDate, sale.itemSK,
+			sale.billCustomerSK, sale.billCDemoSK, sale.billHDemoSK, sale.billAddrSK,
+			sale.shipCustomerSK, sale.shipCDemoSK, sale.shipHDemoSK, sale.shipAddrSK,
+			sale.webPageSK, webSiteSK, shipModeSK, warehouseSK, promoSK,
+			sale.number, sale.quantity,
+			wholesaleCents, listPriceCents, salesPriceCents,
+			extDiscountAmt, extSalesPrice, extWholesaleCost, extListPrice, extTax,
+			couponAmt, extShipCost, netPaid, netPaidIncTax, netPaidIncShip, netPaidIncShipTax, netProfit,
+		)
+		if err = w.writeRow(row); err != nil {
+			return err
 		}
-	}()
-	defer builder.Release()
-
-	b0 := builder.Field(0).(*array.Int64Builder)
-	b1 := builder.Field(1).(*array.Int64Builder)
-	b2 := builder.Field(2).(*array.Int64Builder)
-	b3 := builder.Field(3).(*array.Int64Builder)
-	b4 := builder.Field(4).(*array.Int64Builder)
-	b5 := builder.Field(5).(*array.Int64Builder)
-	b6 := builder.Field(6).(*array.Int64Builder)
-	b7 := builder.Field(7).(*array.Int64Builder)
-	b8 := builder.Field(8).(*array.Int64Builder)
-	b9 := builder.Field(9).(*array.Int64Builder)
-	b10 := builder.Field(10).(*array.Int64Builder)
-	b11 := builder.Field(11).(*array.Int64Builder)
-	b12 := builder.Field(12).(*array.Int64Builder)
-	b13 := builder.Field(13).(*array.Int64Builder)
-	b14 := builder.Field(14).(*array.Int64Builder)
-	b15 := builder.Field(15).(*array.Int64Builder)
-	b16 := builder.Field(16).(*array.Int64Builder)
-	b17 := builder.Field(17).(*array.Int64Builder)
-	b18 := builder.Field(18).(*array.Int32Builder)
-	b19 := builder.Field(19).(*array.Float64Builder)
-	b20 := builder.Field(20).(*array.Float64Builder)
-	b21 := builder.Field(21).(*array.Float64Builder)
-	b22 := builder.Field(22).(*array.Float64Builder)
-	b23 := builder.Field(23).(*array.Float64Builder)
-	b24 := builder.Field(24).(*array.Float64Builder)
-	b25 := builder.Field(25).(*array.Float64Builder)
-	b26 := builder.Field(26).(*array.Float64Builder)
-	b27 := builder.Field(27).(*array.Float64Builder)
-	b28 := builder.Field(28).(*array.Float64Builder)
-	b29 := builder.Field(29).(*array.Float64Builder)
-	b30 := builder.Field(30).(*array.Float64Builder)
-	b31 := builder.Field(31).(*array.Float64Builder)
-	b32 := builder.Field(32).(*array.Float64Builder)
-	b33 := builder.Field(33).(*array.Float64Builder)
-
-	for i := 0; i < count; i++ {
-		quantity := rng.Intn(10) + 1
-		listPriceCents := int64(1000 + rng.Intn(100000))
-		salesPriceCents := listPriceCents * int64(80+rng.Intn(21)) / 100
-		wholesaleCents := salesPriceCents * int64(60+rng.Intn(21)) / 100
 
-		extDiscountAmt := (listPriceCents - salesPriceCents) * int64(quantity)
-		extSalesPrice := salesPriceCents * int64(quantity)
-		extWholesaleCost := wholesaleCents * int64(quantity)
-		extListPrice := listPriceCents * int64(quantity)
-		extTax := extSalesPrice * 8 / 100
-		extShipCost := int64(500 + rng.Intn(2000))
-		netPaid := extSalesPrice - extDiscountAmt
-		netPaidIncTax := netPaid + extTax
-		netPaidIncShip := netPaid + extShipCost
-		netPaidIncShipTax := netPaidIncShip + extTax
-		netProfit := netPaid - extWholesaleCost
-
-		b0.Append(int64(rng.Intn(2000) + 1))
-		b1.Append(int64(rng.Intn(86400)))
-		b2.Append(int64(rng.Intn(2000) + 1))
-		b3.Append(itemSKs[rng.Intn(len(itemSKs))])
-		b4.Append(customerSKs[rng.Intn(len(customerSKs))])
-		b5.Append(cdemoSKs[rng.Intn(len(cdemoSKs))])
-		b6.Append(hdemoSKs[rng.Intn(len(hdemoSKs))])
-		b7.Append(addrSKs[rng.Intn(len(addrSKs))])
-		b8.Append(customerSKs[rng.Intn(len(customerSKs))])
-		b9.Append(cdemoSKs[rng.Intn(len(cdemoSKs))])
-		b10.Append(hdemoSKs[rng.Intn(len(hdemoSKs))])
-		b11.Append(addrSKs[rng.Intn(len(addrSKs))])
-		b12.Append(webPageSKs[rng.Intn(len(webPageSKs))])
-		b13.Append(webSiteSKs[rng.Intn(len(webSiteSKs))])
-		b14.Append(shipModeSKs[rng.Intn(len(shipModeSKs))])
-		b15.Append(warehouseSKs[rng.Intn(len(warehouseSKs))])
-		b16.Append(promoSKs[rng.Intn(len(promoSKs))])
-		b17.Append(startOrder + int64(i))
-		b18.Append(int32(quantity))
-		b19.Append(float64(wholesaleCents) / 100.0)
-		b20.Append(float64(listPriceCents) / 100.0)
-		b21.Append(float64(salesPriceCents) / 100.0)
-		b22.Append(float64(extDiscountAmt) / 100.0)
-		b23.Append(float64(extSalesPrice) / 100.0)
-		b24.Append(float64(extWholesaleCost) / 100.0)
-		b25.Append(float64(extListPrice) / 100.0)
-		b26.Append(float64(extTax) / 100.0)
-		b27.Append(0.0)
-		b28.Append(float64(extShipCost) / 100.0)
-		b29.Append(float64(netPaid) / 100.0)
-		b30.Append(float64(netPaidIncTax) / 100.0)
-		b31.Append(float64(netPaidIncShip) / 100.0)
-		b32.Append(float64(netPaidIncShipTax) / 100.0)
-		b33.Append(float64(netProfit) / 100.0)
-
-		if (i+1)%65536 == 0 {
-			if err = formats.WriteTypedBatch(writer, builder, filename); err != nil {
+		if returned {
+			returnRow = webReturnRow(returnRow, rng, &returns, &sale)
+			if err = returnsWriter.writeRow(returnRow); err != nil {
 				return err
 			}
 		}
 	}
 
-	if count%65536 != 0 {
-		if err = formats.WriteTypedBatch(writer, builder, filename); err != nil {
-			return err
-		}
-	}
-
 	duration := time.Since(startTime)
-	fmt.Printf("Worker completed: %d web sales records to %s in %s\n", count, filename, duration.Round(time.Millisecond))
+	fmt.Printf("Worker completed: %d web sales records (%d returns) to %s in %s\n", count, returns.count, filename, duration.Round(time.Millisecond))
 	return nil
 }
 
-// GenerateWebSalesOptimized generates web sales using worker-based file sharding
-func GenerateWebSalesOptimized(count int, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs []int64, outputDir string, format string) error {
+// GenerateWebSalesOptimized generates web sales using worker-based file sharding.
+// Each worker also derives its share of returnCount web returns from the lines it emits.
+func GenerateWebSalesOptimized(ctx context.Context, count, returnCount int, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs, reasonSKs []int64, outputDir string, opts formats.Options, seed int64) error {
 	if count <= 0 {
 		return nil
 	}
-
-	numWorkers := runtime.NumCPU()
-	recordsPerWorker := count / numWorkers
-	extraRecords := count % numWorkers
-
-	ext := ".csv"
-	if format == "parquet" {
-		ext = ".parquet"
+	if err := requireDimensionSKs("web sales",
+		dimensionSKs{"dim_date", dateSKs}, dimensionSKs{"dim_time", timeSKs},
+		dimensionSKs{"dim_items", itemSKs}, dimensionSKs{"dim_customers", customerSKs},
+		dimensionSKs{"dim_customer_demographics", cdemoSKs}, dimensionSKs{"dim_household_demographics", hdemoSKs},
+		dimensionSKs{"dim_customer_addresses", addrSKs}, dimensionSKs{"dim_web_pages", webPageSKs},
+		dimensionSKs{"dim_web_sites", webSiteSKs}, dimensionSKs{"dim_ship_modes", shipModeSKs},
+		dimensionSKs{"dim_warehouses", warehouseSKs}, dimensionSKs{"dim_promotions", promoSKs}); err != nil {
+		return err
 	}
 
-	var wg sync.WaitGroup
-	baseSeed := time.Now().UnixNano()
+	numShards := common.ShardCount(count)
+	workerRecords := shardRecords(count, numShards)
+	workerReturns := splitReturns(returnCount, count, workerRecords)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
 	startOrder := int64(1)
 
-	for i := 0; i < numWorkers; i++ {
-		workerRecords := recordsPerWorker
-		if i < extraRecords {
-			workerRecords++
-		}
+	for i := 0; i < numShards; i++ {
+		if workerRecords[i] > 0 {
+			records, order := workerRecords[i], startOrder
+			rng := common.NewRand(seed, "fact_web_sales", i)
+			filename := opts.ShardPath(outputDir, "fact_web_sales", i, numShards)
+			returns := returnsShard{
+				count:     workerReturns[i],
+				reasonSKs: reasonSKs,
+				dateSKs:   dateSKs,
+				timeSKs:   timeSKs,
+				filename:  opts.ShardPath(outputDir, "fact_web_returns", i, numShards),
+			}
 
-		if workerRecords > 0 {
-			wg.Add(1)
-			workerSeed := baseSeed + int64(i)*int64(0x9e3779b9)
-			rng := rand.New(rand.NewSource(workerSeed))
-			filename := fmt.Sprintf("%s/fact_web_sales_%d%s", outputDir, i, ext)
-			workerStartOrder := startOrder + int64(i*recordsPerWorker)
-
-			go func(records int, order int64, fname string, workerRNG *rand.Rand) {
-				defer wg.Done()
-				if err := generateWebSalesWorker(records, order, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs, fname, workerRNG, format); err != nil {
-					fmt.Printf("Error in web sales worker: %v\n", err)
+			g.Go(func() error {
+				if shardDone(&webSalesTable, filename, &webReturnsTable, returns, opts) {
+					return nil
 				}
-			}(workerRecords, workerStartOrder, filename, rng)
+				return generateWebSalesWorker(ctx, records, order, dateSKs, timeSKs, itemSKs, customerSKs, cdemoSKs, hdemoSKs, addrSKs, webPageSKs, webSiteSKs, shipModeSKs, warehouseSKs, promoSKs, returns, filename, rng, opts)
+			})
 		}
+		startOrder += int64(workerRecords[i])
 	}
 
-	wg.Wait()
-	return nil
+	return g.Wait()
 }
 
 // Legacy channel-based functions - kept for backward compatibility but not used in optimized flow
@@ -0,0 +1,172 @@
+package ecommerceds
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/peekknuf/Gengo/internal/common"
+	"github.com/peekknuf/Gengo/internal/formats"
+	ecommerceds "github.com/peekknuf/Gengo/internal/models/ecommerce-ds"
+	"golang.org/x/sync/errgroup"
+)
+
+// Inventory simulation parameters
+const (
+	inventorySnapshotDow = 0    // snapshots are taken every Sunday
+	inventoryMaxStart    = 1000 // upper bound of the initial quantity on hand
+	inventoryReorderAt   = 100  // restock when stock falls below this level
+	inventoryRestockMin  = 400
+	inventoryRestockMax  = 1200
+)
+
+var inventoryTable = factTable{
+	name: "fact_inventory",
+	columns: []factColumn{
+		{"inv_date_sk", colKey},
+		{"inv_item_sk", colKey},
+		{"inv_warehouse_sk", colKey},
+		{"inv_quantity_on_hand", colQuantity},
+	},
+}
+
+// InventorySnapshotDateSKs returns the dim_date SKs on which weekly inventory
+// snapshots are taken, in calendar order.
+func InventorySnapshotDateSKs(dateDim []interface{}) []int64 {
+	var sks []int64
+	for _, d := range dateDim {
+		date := d.(ecommerceds.DateDim)
+		if date.D_Dow == inventorySnapshotDow {
+			sks = append(sks, date.D_DateSK)
+		}
+	}
+	return sks
+}
+
+// inventoryWeeks picks how many weekly snapshots are needed to cover count rows
+// and how many item x warehouse rows the last snapshot keeps.
+func inventoryWeeks(count, rowsPerWeek, availableWeeks int) (weeks, lastWeekRows int) {
+	weeks = (count + rowsPerWeek - 1) / rowsPerWeek
+	if weeks > availableWeeks {
+		weeks = availableWeeks
+		return weeks, rowsPerWeek
+	}
+	lastWeekRows = count - (weeks-1)*rowsPerWeek
+	return weeks, lastWeekRows
+}
+
+// nextQuantityOnHand moves stock by one week: demand draws it down and the
+// warehouse restocks once it falls below the reorder point.
+func nextQuantityOnHand(rng *rand.Rand, qty int32) int32 {
+	qty -= int32(rng.Intn(int(qty)/3 + 20))
+	if qty < inventoryReorderAt {
+		qty += int32(inventoryRestockMin + rng.Intn(inventoryRestockMax-inventoryRestockMin+1))
+	}
+	if qty < 0 {
+		qty = 0
+	}
+	return qty
+}
+
+// generateInventoryWorker writes the snapshots of the items in [itemLo, itemHi) for all warehouses.
+func generateInventoryWorker(ctx context.Context, itemLo, itemHi int, dateSKs, itemSKs, warehouseSKs []int64, lastWeekRows int, filename string, rng *rand.Rand, opts formats.Options) (err error) {
+	startTime := time.Now()
+
+	w, err := newFactShardWriter(&inventoryTable, filename, opts, bufferSize)
+	if err != nil {
+		return err
+	}
+	defer closeShard(w, &err)
+
+	numWarehouses := len(warehouseSKs)
+	qty := make([]int32, (itemHi-itemLo)*numWarehouses)
+	for i := range qty {
+		qty[i] = int32(rng.Intn(inventoryMaxStart + 1))
+	}
+
+	row := make([]int64, len(inventoryTable.columns))
+	rows := 0
+	for week, dateSK := range dateSKs {
+		lastWeek := week == len(dateSKs)-1
+		for item := itemLo; item < itemHi; item++ {
+			for wh := 0; wh < numWarehouses; wh++ {
+				if lastWeek && item*numWarehouses+wh >= lastWeekRows {
+					break
+				}
+				if rows%ctxCheckInterval == 0 {
+					if err = ctx.Err(); err != nil {
+						return err
+					}
+				}
+				slot := (item-itemLo)*numWarehouses + wh
+				if week > 0 {
+					qty[slot] = nextQuantityOnHand(rng, qty[slot])
+				}
+
+				row[0] = dateSK
+				row[1] = itemSKs[item]
+				row[2] = warehouseSKs[wh]
+				row[3] = int64(qty[slot])
+				if err = w.writeRow(row); err != nil {
+					return err
+				}
+				rows++
+			}
+		}
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("Worker completed: %d inventory records to %s in %s\n", rows, filename, duration.Round(time.Millisecond))
+	return nil
+}
+
+// GenerateInventoryOptimized writes weekly item x warehouse inventory snapshots using
+// worker-based file sharding. Each worker owns a contiguous range of items so the
+// quantity on hand of an item/warehouse pair evolves within a single worker.
+func GenerateInventoryOptimized(ctx context.Context, count int, snapshotDateSKs, itemSKs, warehouseSKs []int64, outputDir string, opts formats.Options, seed int64) error {
+	if count <= 0 {
+		return nil
+	}
+	if len(snapshotDateSKs) == 0 || len(itemSKs) == 0 || len(warehouseSKs) == 0 {
+		return fmt.Errorf("inventory needs snapshot dates, items and warehouses (got %d, %d, %d)", len(snapshotDateSKs), len(itemSKs), len(warehouseSKs))
+	}
+
+	rowsPerWeek := len(itemSKs) * len(warehouseSKs)
+	weeks, lastWeekRows := inventoryWeeks(count, rowsPerWeek, len(snapshotDateSKs))
+	if total := (weeks-1)*rowsPerWeek + lastWeekRows; total < count {
+		return fmt.Errorf("inventory of %d rows needs more than the %d weekly snapshots of the date dimension", count, len(snapshotDateSKs))
+	}
+	// Keep the most recent snapshots.
+	dateSKs := snapshotDateSKs[len(snapshotDateSKs)-weeks:]
+
+	numShards := common.ShardCount(count)
+	if numShards > len(itemSKs) {
+		numShards = len(itemSKs)
+	}
+	workerItems := shardRecords(len(itemSKs), numShards)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
+	itemLo := 0
+
+	for i := 0; i < numShards; i++ {
+		lo, hi := itemLo, itemLo+workerItems[i]
+		// With a single snapshot the trailing workers may have nothing to write.
+		hasRows := weeks > 1 || lo*len(warehouseSKs) < lastWeekRows
+		if workerItems[i] > 0 && hasRows {
+			rng := common.NewRand(seed, "fact_inventory", i)
+			filename := opts.ShardPath(outputDir, "fact_inventory", i, numShards)
+
+			g.Go(func() error {
+				if shardPublished(&inventoryTable, filename, opts) {
+					return nil
+				}
+				return generateInventoryWorker(ctx, lo, hi, dateSKs, itemSKs, warehouseSKs, lastWeekRows, filename, rng, opts)
+			})
+		}
+		itemLo = hi
+	}
+
+	return g.Wait()
+}
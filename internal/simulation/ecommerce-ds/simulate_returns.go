@@ -0,0 +1,334 @@
+package ecommerceds
+
+import (
+	"math/rand"
+)
+
+// Lags, in days, between a sale and its shipment or return.
+const (
+	minShipLagDays   = 2
+	maxShipLagDays   = 90
+	maxReturnLagDays = 90
+)
+
+// returnsBufferSize is smaller than bufferSize because returns are a fraction of sales.
+const returnsBufferSize = 8 << 20
+
+var storeReturnsTable = factTable{
+	name: "fact_store_returns",
+	columns: []factColumn{
+		{"sr_returned_date_sk", colKey},
+		{"sr_return_time_sk", colKey},
+		{"sr_item_sk", colKey},
+		{"sr_customer_sk", colKey},
+		{"sr_cdemo_sk", colKey},
+		{"sr_hdemo_sk", colKey},
+		{"sr_addr_sk", colKey},
+		{"sr_store_sk", colKey},
+		{"sr_reason_sk", colKey},
+		{"sr_ticket_number", colKey},
+		{"sr_return_quantity", colQuantity},
+		{"sr_return_amt", colPrice},
+		{"sr_return_tax", colPrice},
+		{"sr_return_amt_inc_tax", colPrice},
+		{"sr_fee", colPrice},
+		{"sr_return_ship_cost", colPrice},
+		{"sr_refunded_cash", colPrice},
+		{"sr_reversed_charge", colPrice},
+		{"sr_store_credit", colPrice},
+		{"sr_net_loss", colPrice},
+	},
+}
+
+var catalogReturnsTable = factTable{
+	name: "fact_catalog_returns",
+	columns: []factColumn{
+		{"cr_returned_date_sk", colKey},
+		{"cr_returned_time_sk", colKey},
+		{"cr_item_sk", colKey},
+		{"cr_refund_customer_sk", colKey},
+		{"cr_refund_cdemo_sk", colKey},
+		{"cr_refund_hdemo_sk", colKey},
+		{"cr_refund_addr_sk", colKey},
+		{"cr_returning_customer_sk", colKey},
+		{"cr_returning_cdemo_sk", colKey},
+		{"cr_returning_hdemo_sk", colKey},
+		{"cr_returning_addr_sk", colKey},
+		{"cr_call_center_sk", colKey},
+		{"cr_catalog_page_sk", colKey},
+		{"cr_ship_mode_sk", colKey},
+		{"cr_warehouse_sk", colKey},
+		{"cr_reason_sk", colKey},
+		{"cr_order_number", colKey},
+		{"cr_return_quantity", colQuantity},
+		{"cr_return_amount", colPrice},
+		{"cr_return_tax", colPrice},
+		{"cr_return_amt_inc_tax", colPrice},
+		{"cr_fee", colPrice},
+		{"cr_return_ship_cost", colPrice},
+		{"cr_refunded_cash", colPrice},
+		{"cr_reversed_charge", colPrice},
+		{"cr_store_credit", colPrice},
+		{"cr_net_loss", colPrice},
+	},
+}
+
+var webReturnsTable = factTable{
+	name: "fact_web_returns",
+	columns: []factColumn{
+		{"wr_returned_date_sk", colKey},
+		{"wr_returned_time_sk", colKey},
+		{"wr_item_sk", colKey},
+		{"wr_refund_customer_sk", colKey},
+		{"wr_refund_cdemo_sk", colKey},
+		{"wr_refund_hdemo_sk", colKey},
+		{"wr_refund_addr_sk", colKey},
+		{"wr_returning_customer_sk", colKey},
+		{"wr_returning_cdemo_sk", colKey},
+		{"wr_returning_hdemo_sk", colKey},
+		{"wr_returning_addr_sk", colKey},
+		{"wr_web_page_sk", colKey},
+		{"wr_reason_sk", colKey},
+		{"wr_order_number", colKey},
+		{"wr_return_quantity", colQuantity},
+		{"wr_return_amt", colPrice},
+		{"wr_return_tax", colPrice},
+		{"wr_return_amt_inc_tax", colPrice},
+		{"wr_fee", colPrice},
+		{"wr_return_ship_cost", colPrice},
+		{"wr_refunded_cash", colPrice},
+		{"wr_reversed_charge", colPrice},
+		{"wr_account_credit", colPrice},
+		{"wr_net_loss", colPrice},
+	},
+}
+
+// saleLine holds the fields of an emitted sales line that a return is derived from.
+// Store sales only fill the bill* customer fields.
+type saleLine struct {
+	soldDateIdx    int // index of soldDateSK in the dim_date SK slice
+	soldDateSK     int64
+	itemSK         int64
+	billCustomerSK int64
+	billCDemoSK    int64
+	billHDemoSK    int64
+	billAddrSK     int64
+	shipCustomerSK int64
+	shipCDemoSK    int64
+	shipHDemoSK    int64
+	shipAddrSK     int64
+	storeSK        int64
+	callCenterSK   int64
+	catalogPageSK  int64
+	webPageSK      int64
+	shipModeSK     int64
+	warehouseSK    int64
+	number         int64 // ticket or order number
+	quantity       int64
+	salesPrice     int64 // cents
+	wholesaleCost  int64 // cents
+}
+
+// returnsShard is the part of a returns table derived from one sales worker's lines.
+type returnsShard struct {
+	count     int
+	reasonSKs []int64
+	dateSKs   []int64
+	timeSKs   []int64
+	filename  string
+}
+
+// returnSelector picks exactly `returns` of `sales` lines, each line equally likely
+// (selection sampling), so shards hit the sized return counts without buffering.
+type returnSelector struct {
+	remainingSales   int64
+	remainingReturns int64
+}
+
+func newReturnSelector(sales, returns int) *returnSelector {
+	if returns > sales {
+		returns = sales
+	}
+	return &returnSelector{remainingSales: int64(sales), remainingReturns: int64(returns)}
+}
+
+func (s *returnSelector) next(rng *rand.Rand) bool {
+	if s.remainingSales <= 0 {
+		return false
+	}
+	take := s.remainingReturns > 0 && rng.Int63n(s.remainingSales) < s.remainingReturns
+	s.remainingSales--
+	if take {
+		s.remainingReturns--
+	}
+	return take
+}
+
+// splitReturns distributes the returns of a table over the sales workers in
+// proportion to the number of sales lines each worker emits.
+func splitReturns(returns, sales int, workerSales []int) []int {
+	out := make([]int, len(workerSales))
+	if sales <= 0 {
+		return out
+	}
+	var cumSales, assigned int64
+	for i, n := range workerSales {
+		cumSales += int64(n)
+		target := int64(returns) * cumSales / int64(sales)
+		out[i] = int(target - assigned)
+		assigned = target
+	}
+	return out
+}
+
+// returnAmounts are the monetary fields shared by all three return tables.
+type returnAmounts struct {
+	quantity       int64
+	amount         int64
+	tax            int64
+	amountIncTax   int64
+	fee            int64
+	shipCost       int64
+	refundedCash   int64
+	reversedCharge int64
+	credit         int64
+	netLoss        int64
+}
+
+func newReturnAmounts(rng *rand.Rand, sale *saleLine) returnAmounts {
+	var a returnAmounts
+	a.quantity = 1 + rng.Int63n(sale.quantity)
+	a.amount = sale.salesPrice * a.quantity
+	a.tax = a.amount * 8 / 100
+	a.amountIncTax = a.amount + a.tax
+	a.fee = 50 + rng.Int63n(9951)                     // 0.50 to 100.00
+	a.shipCost = sale.wholesaleCost * a.quantity / 10 // carrier charge on the way back
+
+	// The refund is split between cash, a reversed card charge and credit.
+	a.refundedCash = a.amountIncTax * rng.Int63n(101) / 100
+	rest := a.amountIncTax - a.refundedCash
+	a.reversedCharge = rest * rng.Int63n(101) / 100
+	a.credit = rest - a.reversedCharge
+
+	a.netLoss = a.amount - sale.wholesaleCost*a.quantity + a.shipCost
+	return a
+}
+
+// soldDateIdx picks the index of a sale's date in dateSKs. A line that is
+// returned is not sold on the last date, so its return can fall on a later one.
+func soldDateIdx(rng *rand.Rand, dateSKs []int64, returned bool) int {
+	if returned && len(dateSKs) > 1 {
+		return rng.Intn(len(dateSKs) - 1)
+	}
+	return rng.Intn(len(dateSKs))
+}
+
+// laterDateSK picks a dim_date SK between minLag and maxLag days after the date
+// at index idx. dateSKs holds consecutive days, so offsets in the slice are days;
+// the result is clamped to the last date of the dimension.
+func laterDateSK(rng *rand.Rand, dateSKs []int64, idx, minLag, maxLag int) int64 {
+	j := idx + minLag + rng.Intn(maxLag-minLag+1)
+	if j >= len(dateSKs) {
+		j = len(dateSKs) - 1
+	}
+	return dateSKs[j]
+}
+
+// shipDateSK picks the ship date of a catalog or web sale.
+func shipDateSK(rng *rand.Rand, dateSKs []int64, soldDateIdx int) int64 {
+	return laterDateSK(rng, dateSKs, soldDateIdx, minShipLagDays, maxShipLagDays)
+}
+
+// returnedDateSK picks a date after the sale.
+func returnedDateSK(rng *rand.Rand, r *returnsShard, sale *saleLine) int64 {
+	return laterDateSK(rng, r.dateSKs, sale.soldDateIdx, 1, maxReturnLagDays)
+}
+
+func storeReturnRow(row []int64, rng *rand.Rand, r *returnsShard, sale *saleLine) []int64 {
+	a := newReturnAmounts(rng, sale)
+	return append(row[:0],
+		returnedDateSK(rng, r, sale),
+		r.timeSKs[rng.Intn(len(r.timeSKs))],
+		sale.itemSK,
+		sale.billCustomerSK,
+		sale.billCDemoSK,
+		sale.billHDemoSK,
+		sale.billAddrSK,
+		sale.storeSK,
+		r.reasonSKs[rng.Intn(len(r.reasonSKs))],
+		sale.number,
+		a.quantity,
+		a.amount,
+		a.tax,
+		a.amountIncTax,
+		a.fee,
+		a.shipCost,
+		a.refundedCash,
+		a.reversedCharge,
+		a.credit,
+		a.netLoss,
+	)
+}
+
+func catalogReturnRow(row []int64, rng *rand.Rand, r *returnsShard, sale *saleLine) []int64 {
+	a := newReturnAmounts(rng, sale)
+	return append(row[:0],
+		returnedDateSK(rng, r, sale),
+		r.timeSKs[rng.Intn(len(r.timeSKs))],
+		sale.itemSK,
+		sale.billCustomerSK,
+		sale.billCDemoSK,
+		sale.billHDemoSK,
+		sale.billAddrSK,
+		sale.shipCustomerSK,
+		sale.shipCDemoSK,
+		sale.shipHDemoSK,
+		sale.shipAddrSK,
+		sale.callCenterSK,
+		sale.catalogPageSK,
+		sale.shipModeSK,
+		sale.warehouseSK,
+		r.reasonSKs[rng.Intn(len(r.reasonSKs))],
+		sale.number,
+		a.quantity,
+		a.amount,
+		a.tax,
+		a.amountIncTax,
+		a.fee,
+		a.shipCost,
+		a.refundedCash,
+		a.reversedCharge,
+		a.credit,
+		a.netLoss,
+	)
+}
+
+func webReturnRow(row []int64, rng *rand.Rand, r *returnsShard, sale *saleLine) []int64 {
+	a := newReturnAmounts(rng, sale)
+	return append(row[:0],
+		returnedDateSK(rng, r, sale),
+		r.timeSKs[rng.Intn(len(r.timeSKs))],
+		sale.itemSK,
+		sale.billCustomerSK,
+		sale.billCDemoSK,
+		sale.billHDemoSK,
+		sale.billAddrSK,
+		sale.shipCustomerSK,
+		sale.shipCDemoSK,
+		sale.shipHDemoSK,
+		sale.shipAddrSK,
+		sale.webPageSK,
+		r.reasonSKs[rng.Intn(len(r.reasonSKs))],
+		sale.number,
+		a.quantity,
+		a.amount,
+		a.tax,
+		a.amountIncTax,
+		a.fee,
+		a.shipCost,
+		a.refundedCash,
+		a.reversedCharge,
+		a.credit,
+		a.netLoss,
+	)
+}
@@ -2,10 +2,9 @@ package ecommerce
 
 import (
 	"fmt"
-	"math/rand"
 	"strings"
 
-	gf "github.com/brianvoe/gofakeit/v6"
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/models/ecommerce"
 	"gonum.org/v1/gonum/stat/distuv"
 )
@@ -18,43 +17,45 @@ var (
 )
 
 // generateCustomers creates a slice of Customer structs.
-func GenerateCustomers(count int) []ecommerce.Customer {
+func GenerateCustomers(count int, seed int64) []ecommerce.Customer {
 	if count <= 0 {
 		return []ecommerce.Customer{}
 	}
+	faker := common.NewFaker(seed, "dim_customers", 0)
 	customers := make([]ecommerce.Customer, count)
 	for i := 0; i < count; i++ {
-		firstName := gf.FirstName()
-		lastName := gf.LastName()
-		emailProvider := emailProviders[rand.Intn(len(emailProviders))]
+		firstName := faker.FirstName()
+		lastName := faker.LastName()
+		emailProvider := emailProviders[faker.Rand.Intn(len(emailProviders))]
 		email := fmt.Sprintf("%s.%s%d@%s",
 			strings.ToLower(firstName),
 			strings.ToLower(lastName),
-			gf.Number(1, 999),
+			faker.Number(1, 999),
 			emailProvider)
 
 		customers[i] = ecommerce.Customer{
 			CustomerID: i + 1,
 			FirstName:  firstName,
 			LastName:   lastName,
-			Email:      gf.Numerify(email),
+			Email:      faker.Numerify(email),
 		}
 	}
 	return customers
 }
 
-func GenerateCustomerAddresses(customers []ecommerce.Customer) []ecommerce.CustomerAddress {
+func GenerateCustomerAddresses(customers []ecommerce.Customer, seed int64) []ecommerce.CustomerAddress {
 	if len(customers) == 0 {
 		return []ecommerce.CustomerAddress{}
 	}
+	faker := common.NewFaker(seed, "dim_customer_addresses", 0)
 	addresses := make([]ecommerce.CustomerAddress, 0, len(customers)*2)
 	addressIDCounter := 1
 
 	for _, customer := range customers {
-		numAddresses := rand.Intn(3) + 1 // 1 to 3 addresses per customer
+		numAddresses := faker.Rand.Intn(3) + 1 // 1 to 3 addresses per customer
 		for j := 0; j < numAddresses; j++ {
-			addrInfo := gf.Address()
-			addressType := addressTypes[rand.Intn(len(addressTypes))]
+			addrInfo := faker.Address()
+			addressType := addressTypes[faker.Rand.Intn(len(addressTypes))]
 
 			addresses = append(addresses, ecommerce.CustomerAddress{
 				AddressID:   addressIDCounter,
@@ -72,16 +73,17 @@ func GenerateCustomerAddresses(customers []ecommerce.Customer) []ecommerce.Custo
 	return addresses
 }
 
-func GenerateSuppliers(count int) []ecommerce.Supplier {
+func GenerateSuppliers(count int, seed int64) []ecommerce.Supplier {
 	if count <= 0 {
 		return []ecommerce.Supplier{}
 	}
+	faker := common.NewFaker(seed, "dim_suppliers", 0)
 	suppliers := make([]ecommerce.Supplier, count)
 	for i := 0; i < count; i++ {
 		suppliers[i] = ecommerce.Supplier{
 			SupplierID:   i + 1,
-			SupplierName: gf.Company(),
-			Country:      gf.Country(),
+			SupplierName: faker.Company(),
+			Country:      faker.Country(),
 		}
 	}
 	return suppliers
@@ -98,26 +100,27 @@ func GenerateProductCategories() []ecommerce.ProductCategory {
 	return categories
 }
 
-func GenerateProducts(count int, supplierIDs []int, categoryIDs []int) []ecommerce.Product {
+func GenerateProducts(count int, supplierIDs []int, categoryIDs []int, seed int64) []ecommerce.Product {
 	if count <= 0 || len(supplierIDs) == 0 {
 		return []ecommerce.Product{}
 	}
+	faker := common.NewFaker(seed, "dim_products", 0)
 	products := make([]ecommerce.Product, count)
 
-	priceDist := distuv.Normal{Mu: 75, Sigma: 45}
+	priceDist := distuv.Normal{Mu: 75, Sigma: 45, Src: common.NewPCG(seed, "dim_products", 1)}
 
 	for i := 0; i < count; i++ {
-		productName := gf.ProductName()
+		productName := faker.ProductName()
 		price := priceDist.Rand()
 		if price < 5.0 {
-			price = gf.Float64Range(5.0, 25.0)
+			price = faker.Float64Range(5.0, 25.0)
 		}
 
 		products[i] = ecommerce.Product{
 			ProductID:   i + 1,
-			SupplierID:  supplierIDs[rand.Intn(len(supplierIDs))],
+			SupplierID:  supplierIDs[faker.Rand.Intn(len(supplierIDs))],
 			ProductName: productName,
-			CategoryID:  categoryIDs[rand.Intn(len(categoryIDs))],
+			CategoryID:  categoryIDs[faker.Rand.Intn(len(categoryIDs))],
 			BasePrice:   price,
 		}
 	}
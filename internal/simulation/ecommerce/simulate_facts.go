@@ -2,18 +2,21 @@ package ecommerce
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"math"
 	"math/rand/v2"
-	"os"
-	"runtime"
-	"sync"
 	"time"
 
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/formats"
 	ecommercemodels "github.com/peekknuf/Gengo/internal/models/ecommerce"
+	"golang.org/x/sync/errgroup"
 )
 
+// ctxCheckInterval is the number of orders between checks for a cancelled run.
+const ctxCheckInterval = 4096
+
 var (
 	orderStatuses = []string{"Pending", "Processing", "Shipped", "Delivered", "Cancelled", "Returned"}
 	digitsLUT     = [100]string{
@@ -187,7 +190,7 @@ func (s *AliasSampler) Sample(rng *rand.Rand) int {
 	return s.ids[s.alias[i]]
 }
 
-func GenerateECommerceModelData(numOrders int, customerIDs []int, customerAddresses []ecommercemodels.CustomerAddress, productDetails []ecommercemodels.ProductDetails, productIDsForSampling []int, outputDir string, format string) error {
+func GenerateECommerceModelData(ctx context.Context, numOrders int, customerIDs []int, customerAddresses []ecommercemodels.CustomerAddress, productDetails []ecommercemodels.ProductDetails, productIDsForSampling []int, outputDir string, opts formats.Options, seed int64) error {
 	if numOrders <= 0 {
 		return nil
 	}
@@ -206,13 +209,48 @@ func GenerateECommerceModelData(numOrders int, customerIDs []int, customerAddres
 		customerAddressSlice[addr.CustomerID] = append(customerAddressSlice[addr.CustomerID], addr.AddressID)
 	}
 
-	if format == "csv" {
-		return generateECommerceModelDataCSV(numOrders, customerIDs, customerAddressSlice, productDetails, productIDsForSampling, outputDir)
+	if formats.Columnar(opts.Format) {
+		return generateECommerceModelDataRecords(ctx, numOrders, customerIDs, customerAddressSlice, productDetails, productIDsForSampling, outputDir, opts, seed)
+	}
+	return generateECommerceModelDataText(ctx, numOrders, customerIDs, customerAddressSlice, productDetails, productIDsForSampling, outputDir, opts, seed)
+}
+
+var (
+	orderHeaderColumns = []string{"order_id", "customer_id", "shipping_address_id", "billing_address_id", "order_timestamp_unix", "order_status"}
+	orderItemColumns   = []string{"order_item_id", "order_id", "product_id", "quantity", "unit_price", "discount"}
+)
+
+// lineLayout describes how the streaming fact writer frames the fields of a row
+// in a line-oriented format: CSV in the run's dialect, or JSON Lines objects.
+type lineLayout struct {
+	header      []byte   // written once at the top of the file
+	prefix      [][]byte // written before each field
+	end         []byte   // terminates a row
+	quoteString bool     // string fields are written as quoted JSON strings
+}
+
+func newLineLayout(opts formats.Options, columns []string) lineLayout {
+	if opts.Format == "json" {
+		return lineLayout{prefix: formats.JSONKeyPrefixes(columns), end: []byte("}\n"), quoteString: true}
+	}
+	prefix, end := opts.CSV.Framing(len(columns))
+	return lineLayout{header: opts.CSV.HeaderLine(columns), prefix: prefix, end: end}
+}
+
+// appendString appends a string field. Only used for order statuses, which are
+// plain words and need no escaping.
+func (l *lineLayout) appendString(buf []byte, s string) []byte {
+	if l.quoteString {
+		buf = append(buf, '"')
+		buf = append(buf, s...)
+		return append(buf, '"')
 	}
-	return generateECommerceModelDataParquet(numOrders, customerIDs, customerAddressSlice, productDetails, productIDsForSampling, outputDir)
+	return append(buf, s...)
 }
 
-func generateECommerceModelDataCSV(numOrders int, customerIDs []int, customerAddressSlice [][]int, productDetails []ecommercemodels.ProductDetails, productIDsForSampling []int, outputDir string) error {
+// generateECommerceModelDataText streams order header and item shards in a
+// line-oriented format (csv or json).
+func generateECommerceModelDataText(ctx context.Context, numOrders int, customerIDs []int, customerAddressSlice [][]int, productDetails []ecommercemodels.ProductDetails, productIDsForSampling []int, outputDir string, opts formats.Options, seed int64) error {
 	productSampler, err := NewAliasSampler(productIDsForSampling)
 	if err != nil {
 		return fmt.Errorf("failed to set up product sampler: %w", err)
@@ -223,25 +261,18 @@ func generateECommerceModelDataCSV(numOrders int, customerIDs []int, customerAdd
 		return fmt.Errorf("failed to set up customer sampler: %w", err)
 	}
 
-	numWorkers := runtime.NumCPU()
+	numWorkers := common.ShardCount(numOrders)
 	ordersPerWorker := (numOrders + numWorkers - 1) / numWorkers
 
-	headerFile, err := os.Create(fmt.Sprintf("%s/fact_orders_header.csv", outputDir))
-	if err != nil {
-		return fmt.Errorf("failed to create header file: %w", err)
-	}
-	defer headerFile.Close()
-
-	sharedHeaderWriter := bufio.NewWriterSize(headerFile, 64<<20)
-	defer sharedHeaderWriter.Flush()
-
-	sharedHeaderWriter.WriteString("order_id,customer_id,shipping_address_id,billing_address_id,order_timestamp_unix,order_status\n")
-
-	var headerMutex sync.Mutex
+	ext := opts.FileExtension()
+	headerLayout := newLineLayout(opts, orderHeaderColumns)
+	itemLayout := newLineLayout(opts, orderItemColumns)
 
+	headerShardFilenames := make([]string, numWorkers)
 	itemShardFilenames := make([]string, numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		itemShardFilenames[i] = fmt.Sprintf("%s/fact_order_items_%d.csv", outputDir, i)
+		headerShardFilenames[i] = fmt.Sprintf("%s_%d%s", opts.TablePath(outputDir, "fact_orders_header"), i, ext)
+		itemShardFilenames[i] = fmt.Sprintf("%s_%d%s", opts.TablePath(outputDir, "fact_order_items"), i, ext)
 	}
 
 	const avgItemsPerOrder = 11.0
@@ -249,8 +280,8 @@ func generateECommerceModelDataCSV(numOrders int, customerIDs []int, customerAdd
 	itemsPerWorker := totalItems / int64(numWorkers)
 	extraItems := totalItems % int64(numWorkers)
 
-	var wg sync.WaitGroup
-	baseSeed := time.Now().UnixNano()
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
 	startItemID := int64(1)
 
 	for i := 0; i < numWorkers; i++ {
@@ -266,23 +297,37 @@ func generateECommerceModelDataCSV(numOrders int, customerIDs []int, customerAdd
 				endItemID++
 			}
 
-			wg.Add(1)
-			go func(workerID, startOrderID, numToGen int, startItemID, endItemID int64) {
-				defer wg.Done()
+			workerID, firstItemID := i, startItemID
+			g.Go(func() (err error) {
+				headerFilename := headerShardFilenames[workerID]
+				itemFilename := itemShardFilenames[workerID]
+				if opts.Completed(headerFilename) && opts.Completed(itemFilename) {
+					return nil
+				}
 
-				rng := rand.New(rand.NewPCG(uint64(baseSeed), uint64(workerID)))
-				idGen := &idBlock{next: startItemID, end: endItemID}
+				rng := common.NewRandV2(seed, "fact_orders", workerID)
+				idGen := &idBlock{next: firstItemID, end: endItemID}
 
-				itemFile, err := os.Create(itemShardFilenames[workerID])
+				headerFile, err := opts.CreateTextFile(headerFilename)
 				if err != nil {
-					return
+					return fmt.Errorf("failed to create file %s: %w", headerFilename, err)
 				}
-				defer itemFile.Close()
+				headerWriter := bufio.NewWriterSize(headerFile, 8<<20)
+				defer opts.CloseBufferedFile(headerWriter, headerFile, headerFilename, &err)
 
+				itemFile, err := opts.CreateTextFile(itemFilename)
+				if err != nil {
+					return fmt.Errorf("failed to create file %s: %w", itemFilename, err)
+				}
 				itemWriter := bufio.NewWriterSize(itemFile, 64<<20)
-				defer itemWriter.Flush()
+				defer opts.CloseBufferedFile(itemWriter, itemFile, itemFilename, &err)
 
-				itemWriter.WriteString("order_item_id,order_id,product_id,quantity,unit_price,discount\n")
+				if _, err = headerWriter.Write(headerLayout.header); err != nil {
+					return fmt.Errorf("failed to write header to %s: %w", headerFilename, err)
+				}
+				if _, err = itemWriter.Write(itemLayout.header); err != nil {
+					return fmt.Errorf("failed to write header to %s: %w", itemFilename, err)
+				}
 
 				headerBatchSize := 1000
 				headerBatch := make([]byte, 0, 256*1024)
@@ -302,26 +347,30 @@ func generateECommerceModelDataCSV(numOrders int, customerIDs []int, customerAdd
 					orderIDBuf = orderIDBuf[:0]
 					orderIDBuf = fastItoa(orderIDBuf, int64(orderID))
 
+					headerBatch = append(headerBatch, headerLayout.prefix[0]...)
 					headerBatch = append(headerBatch, orderIDBuf...)
-					headerBatch = append(headerBatch, ',')
+					headerBatch = append(headerBatch, headerLayout.prefix[1]...)
 					headerBatch = fastItoa(headerBatch, int64(customerID))
-					headerBatch = append(headerBatch, ',')
+					headerBatch = append(headerBatch, headerLayout.prefix[2]...)
 					headerBatch = fastItoa(headerBatch, int64(shippingAddressID))
-					headerBatch = append(headerBatch, ',')
+					headerBatch = append(headerBatch, headerLayout.prefix[3]...)
 					headerBatch = fastItoa(headerBatch, int64(billingAddressID))
-					headerBatch = append(headerBatch, ',')
+					headerBatch = append(headerBatch, headerLayout.prefix[4]...)
 					headerBatch = fastItoa(headerBatch, orderTimestamp)
-					headerBatch = append(headerBatch, ',')
-					headerBatch = append(headerBatch, orderStatus...)
-					headerBatch = append(headerBatch, '\n')
+					headerBatch = append(headerBatch, headerLayout.prefix[5]...)
+					headerBatch = headerLayout.appendString(headerBatch, orderStatus)
+					headerBatch = append(headerBatch, headerLayout.end...)
 					headerBatchCount++
 
 					if headerBatchCount >= headerBatchSize {
-						headerMutex.Lock()
-						sharedHeaderWriter.Write(headerBatch)
-						headerMutex.Unlock()
+						if _, err = headerWriter.Write(headerBatch); err != nil {
+							return fmt.Errorf("failed to write rows to %s: %w", headerFilename, err)
+						}
 						headerBatch = headerBatch[:0]
 						headerBatchCount = 0
+						if err = ctx.Err(); err != nil {
+							return err
+						}
 					}
 
 					numItems := int(rng.Uint64()%10) + 1
@@ -339,40 +388,42 @@ func generateECommerceModelDataCSV(numOrders int, customerIDs []int, customerAdd
 
 						id := idGen.nextID()
 
-						itemBuf = itemBuf[:0]
+						itemBuf = append(itemBuf[:0], itemLayout.prefix[0]...)
 						itemBuf = fastItoa(itemBuf, id)
-						itemBuf = append(itemBuf, ',')
+						itemBuf = append(itemBuf, itemLayout.prefix[1]...)
 						itemBuf = append(itemBuf, orderIDBuf...)
-						itemBuf = append(itemBuf, ',')
+						itemBuf = append(itemBuf, itemLayout.prefix[2]...)
 						itemBuf = fastItoa(itemBuf, int64(productID))
-						itemBuf = append(itemBuf, ',')
+						itemBuf = append(itemBuf, itemLayout.prefix[3]...)
 						itemBuf = fastItoa(itemBuf, int64(quantity))
-						itemBuf = append(itemBuf, ',')
+						itemBuf = append(itemBuf, itemLayout.prefix[4]...)
 						itemBuf = appendPrice(itemBuf, priceCents)
-						itemBuf = append(itemBuf, ',')
+						itemBuf = append(itemBuf, itemLayout.prefix[5]...)
 						itemBuf = appendDiscount(itemBuf, discountBP)
-						itemBuf = append(itemBuf, '\n')
+						itemBuf = append(itemBuf, itemLayout.end...)
 
-						itemWriter.Write(itemBuf)
+						if _, err = itemWriter.Write(itemBuf); err != nil {
+							return fmt.Errorf("failed to write row to %s: %w", itemFilename, err)
+						}
 					}
 				}
 
 				if headerBatchCount > 0 {
-					headerMutex.Lock()
-					sharedHeaderWriter.Write(headerBatch)
-					headerMutex.Unlock()
+					if _, err = headerWriter.Write(headerBatch); err != nil {
+						return fmt.Errorf("failed to write rows to %s: %w", headerFilename, err)
+					}
 				}
-			}(i, startOrderID, numToGen, startItemID, endItemID)
+				return nil
+			})
 
 			startItemID = endItemID
 		}
 	}
 
-	wg.Wait()
-	return nil
+	return g.Wait()
 }
 
-func generateECommerceModelDataParquet(numOrders int, customerIDs []int, customerAddressSlice [][]int, productDetails []ecommercemodels.ProductDetails, productIDsForSampling []int, outputDir string) error {
+func generateECommerceModelDataRecords(ctx context.Context, numOrders int, customerIDs []int, customerAddressSlice [][]int, productDetails []ecommercemodels.ProductDetails, productIDsForSampling []int, outputDir string, opts formats.Options, seed int64) error {
 	productSampler, err := NewAliasSampler(productIDsForSampling)
 	if err != nil {
 		return fmt.Errorf("failed to set up product sampler: %w", err)
@@ -383,7 +434,7 @@ func generateECommerceModelDataParquet(numOrders int, customerIDs []int, custome
 		return fmt.Errorf("failed to set up customer sampler: %w", err)
 	}
 
-	numWorkers := runtime.NumCPU()
+	numWorkers := common.ShardCount(numOrders)
 	ordersPerWorker := (numOrders + numWorkers - 1) / numWorkers
 
 	const avgItemsPerOrder = 11.0
@@ -391,9 +442,8 @@ func generateECommerceModelDataParquet(numOrders int, customerIDs []int, custome
 	itemsPerWorker := totalItems / int64(numWorkers)
 	extraItems := totalItems % int64(numWorkers)
 
-	var wg sync.WaitGroup
-	errChan := make(chan error, numWorkers*2)
-
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
 	startItemID := int64(1)
 
 	for i := 0; i < numWorkers; i++ {
@@ -412,17 +462,28 @@ func generateECommerceModelDataParquet(numOrders int, customerIDs []int, custome
 			endItemID++
 		}
 
-		wg.Add(1)
-		go func(workerID, startOrderID, numToGen int, startItemID, endItemID int64) {
-			defer wg.Done()
+		workerID, firstItemID := i, startItemID
+		g.Go(func() error {
+			ext := opts.FileExtension()
+			headerFilename := fmt.Sprintf("%s_%d%s", opts.TablePath(outputDir, "fact_orders_header"), workerID, ext)
+			itemFilename := fmt.Sprintf("%s_%d%s", opts.TablePath(outputDir, "fact_order_items"), workerID, ext)
+			if opts.Completed(headerFilename) && opts.Completed(itemFilename) {
+				return nil
+			}
 
-			rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), uint64(workerID)))
-			idGen := &idBlock{next: startItemID, end: endItemID}
+			rng := common.NewRandV2(seed, "fact_orders", workerID)
+			idGen := &idBlock{next: firstItemID, end: endItemID}
 
 			headers := make([]ecommercemodels.OrderHeader, 0, numToGen)
 			items := make([]ecommercemodels.OrderItem, 0, int(float64(numToGen)*avgItemsPerOrder))
 
 			for orderID := startOrderID; orderID < startOrderID+numToGen; orderID++ {
+				if (orderID-startOrderID)%ctxCheckInterval == 0 {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+				}
+
 				customerID := customerSampler.Sample(rng)
 				addresses := customerAddressSlice[customerID]
 				shippingAddressID := addresses[rng.IntN(len(addresses))]
@@ -455,7 +516,7 @@ func generateECommerceModelDataParquet(numOrders int, customerIDs []int, custome
 
 					itemID := idGen.nextID()
 					if itemID < 0 {
-						itemID = startItemID + int64(cap(items))
+						itemID = firstItemID + int64(cap(items))
 					}
 
 					items = append(items, ecommercemodels.OrderItem{
@@ -469,30 +530,18 @@ func generateECommerceModelDataParquet(numOrders int, customerIDs []int, custome
 				}
 			}
 
-			headerFilename := fmt.Sprintf("%s/fact_orders_header_%d.parquet", outputDir, workerID)
-			if err := formats.WriteOrderHeadersToParquetTyped(headers, headerFilename); err != nil {
-				errChan <- fmt.Errorf("failed to write order headers for worker %d: %w", workerID, err)
-				return
+			if err := formats.WriteOrderHeaderRecordsTyped(headers, headerFilename, opts); err != nil {
+				return fmt.Errorf("failed to write order headers for worker %d: %w", workerID, err)
 			}
 
-			itemFilename := fmt.Sprintf("%s/fact_order_items_%d.parquet", outputDir, workerID)
-			if err := formats.WriteOrderItemsToParquetTyped(items, itemFilename); err != nil {
-				errChan <- fmt.Errorf("failed to write order items for worker %d: %w", workerID, err)
-				return
+			if err := formats.WriteOrderItemRecordsTyped(items, itemFilename, opts); err != nil {
+				return fmt.Errorf("failed to write order items for worker %d: %w", workerID, err)
 			}
-		}(i, startOrderID, numToGen, startItemID, endItemID)
+			return nil
+		})
 
 		startItemID = endItemID
 	}
 
-	wg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return g.Wait()
 }
@@ -1,9 +1,7 @@
 package financial
 
 import (
-	"math/rand"
-
-	gf "github.com/brianvoe/gofakeit/v6"
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/models/financial"
 )
 
@@ -12,32 +10,34 @@ var (
 	exchangeNames = []string{"NASDAQ", "New York Stock Exchange", "London Stock Exchange", "Tokyo Stock Exchange", "Hong Kong Stock Exchange"}
 )
 
-func GenerateCompanies(count int) []financial.Company {
+func GenerateCompanies(count int, seed int64) []financial.Company {
 	if count <= 0 {
 		return []financial.Company{}
 	}
+	faker := common.NewFaker(seed, "dim_companies", 0)
 	companies := make([]financial.Company, count)
 	for i := 0; i < count; i++ {
 		companies[i] = financial.Company{
 			CompanyID:    i + 1,
-			CompanyName:  gf.Company(),
-			TickerSymbol: gf.LetterN(4),
-			Sector:       sectors[rand.Intn(len(sectors))],
+			CompanyName:  faker.Company(),
+			TickerSymbol: faker.LetterN(4),
+			Sector:       sectors[faker.Rand.Intn(len(sectors))],
 		}
 	}
 	return companies
 }
 
-func GenerateExchanges(count int) []financial.Exchange {
+func GenerateExchanges(count int, seed int64) []financial.Exchange {
 	if count <= 0 {
 		return []financial.Exchange{}
 	}
+	faker := common.NewFaker(seed, "dim_exchanges", 0)
 	exchanges := make([]financial.Exchange, count)
 	for i := 0; i < count; i++ {
 		exchanges[i] = financial.Exchange{
 			ExchangeID:   i + 1,
 			ExchangeName: exchangeNames[i%len(exchangeNames)],
-			Country:      gf.Country(),
+			Country:      faker.Country(),
 		}
 	}
 	return exchanges
@@ -1,44 +1,51 @@
 package financial
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
-	"runtime"
-	"sync"
 	"time"
 
 	gf "github.com/brianvoe/gofakeit/v6"
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/formats"
 	"github.com/peekknuf/Gengo/internal/models/financial"
+	"golang.org/x/sync/errgroup"
 )
 
 const NumYearsOfData = 5
 
+// priceHistoryEnd anchors the price history so that seeded runs do not depend on the clock.
+var priceHistoryEnd = time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
 // generateStockPricesForCompanies is a worker function that generates stock prices for a subset of companies.
-func generateStockPricesForCompanies(companies []financial.Company, exchanges []financial.Exchange, numPricesPerCompany int, priceIDStart int64) []financial.DailyStockPrice {
+// It stops between companies once ctx is cancelled.
+func generateStockPricesForCompanies(ctx context.Context, companies []financial.Company, exchanges []financial.Exchange, numPricesPerCompany int, priceIDStart int64, faker *gf.Faker) ([]financial.DailyStockPrice, error) {
 	prices := make([]financial.DailyStockPrice, 0, len(companies)*numPricesPerCompany)
 	priceIDCounter := priceIDStart
 
 	for _, company := range companies {
-		lastClose := gf.Float64Range(20, 500)
-		date := time.Now().AddDate(-NumYearsOfData, 0, 0)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lastClose := faker.Float64Range(20, 500)
+		date := priceHistoryEnd.AddDate(-NumYearsOfData, 0, 0)
 
 		for i := 0; i < numPricesPerCompany; i++ {
-			openPrice := lastClose * (1 + (rand.Float64()-0.5)*0.1)
-			highPrice := openPrice * (1 + rand.Float64()*0.05)
-			lowPrice := openPrice * (1 - rand.Float64()*0.05)
-			closePrice := (highPrice + lowPrice) / 2 * (1 + (rand.Float64()-0.5)*0.02)
+			openPrice := lastClose * (1 + (faker.Rand.Float64()-0.5)*0.1)
+			highPrice := openPrice * (1 + faker.Rand.Float64()*0.05)
+			lowPrice := openPrice * (1 - faker.Rand.Float64()*0.05)
+			closePrice := (highPrice + lowPrice) / 2 * (1 + (faker.Rand.Float64()-0.5)*0.02)
 
 			prices = append(prices, financial.DailyStockPrice{
 				PriceID:    priceIDCounter,
 				Date:       date,
 				CompanyID:  company.CompanyID,
-				ExchangeID: exchanges[rand.Intn(len(exchanges))].ExchangeID,
+				ExchangeID: exchanges[faker.Rand.Intn(len(exchanges))].ExchangeID,
 				OpenPrice:  openPrice,
 				HighPrice:  highPrice,
 				LowPrice:   lowPrice,
 				ClosePrice: closePrice,
-				Volume:     gf.Number(10000, 10000000),
+				Volume:     faker.Number(10000, 10000000),
 			})
 
 			lastClose = closePrice
@@ -46,15 +53,18 @@ func generateStockPricesForCompanies(companies []financial.Company, exchanges []
 			priceIDCounter++
 		}
 	}
-	return prices
+	return prices, nil
 }
 
-func generateAndWriteDailyStockPricesConcurrently(numPrices int, companies []financial.Company, exchanges []financial.Exchange, format string, outputDir string) error {
+func generateAndWriteDailyStockPricesConcurrently(ctx context.Context, numPrices int, companies []financial.Company, exchanges []financial.Exchange, opts formats.Options, outputDir string, seed int64) error {
 	if numPrices <= 0 || len(companies) == 0 || len(exchanges) == 0 {
 		return nil
 	}
 
-	numWorkers := runtime.NumCPU()
+	numWorkers := common.ShardCount(numPrices)
+	if numWorkers > len(companies) {
+		numWorkers = len(companies)
+	}
 	companyChunks := make([][]financial.Company, numWorkers)
 	chunkSize := (len(companies) + numWorkers - 1) / numWorkers
 
@@ -71,8 +81,9 @@ func generateAndWriteDailyStockPricesConcurrently(numPrices int, companies []fin
 		}
 	}
 
-	var wg sync.WaitGroup
-	resultsChan := make(chan []financial.DailyStockPrice, numWorkers)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
+	results := make([][]financial.DailyStockPrice, numWorkers)
 
 	avgPricesPerCompany := numPrices / len(companies)
 	priceIDOffset := int64(1)
@@ -80,26 +91,28 @@ func generateAndWriteDailyStockPricesConcurrently(numPrices int, companies []fin
 	fmt.Println("Starting concurrent generation of stock prices...")
 	for i := 0; i < numWorkers; i++ {
 		if len(companyChunks[i]) > 0 {
-			wg.Add(1)
-			go func(chunk []financial.Company, startID int64) {
-				defer wg.Done()
-				resultsChan <- generateStockPricesForCompanies(chunk, exchanges, avgPricesPerCompany, startID)
-			}(companyChunks[i], priceIDOffset)
+			shard, chunk, startID := i, companyChunks[i], priceIDOffset
+			g.Go(func() (err error) {
+				faker := common.NewFaker(seed, "fact_daily_stock_prices", shard)
+				results[shard], err = generateStockPricesForCompanies(gctx, chunk, exchanges, avgPricesPerCompany, startID, faker)
+				return err
+			})
 			priceIDOffset += int64(len(companyChunks[i]) * avgPricesPerCompany)
 		}
 	}
 
-	wg.Wait()
-	close(resultsChan)
+	if err := g.Wait(); err != nil {
+		return err
+	}
 
 	fmt.Println("Aggregating results...")
 	allPrices := make([]financial.DailyStockPrice, 0, numPrices)
-	for prices := range resultsChan {
+	for _, prices := range results {
 		allPrices = append(allPrices, prices...)
 	}
 
 	fmt.Println("Writing fact data to file...")
-	return formats.WriteSliceData(allPrices, "fact_daily_stock_prices", format, outputDir)
+	return formats.WriteSliceData(ctx, allPrices, "fact_daily_stock_prices", opts, outputDir)
 }
 
 type FinancialRowCounts struct {
@@ -108,9 +121,9 @@ type FinancialRowCounts struct {
 	DailyStockPrices int
 }
 
-func GenerateFinancialModelData(counts FinancialRowCounts, companies []financial.Company, exchanges []financial.Exchange, format string, outputDir string) error {
+func GenerateFinancialModelData(ctx context.Context, counts FinancialRowCounts, companies []financial.Company, exchanges []financial.Exchange, opts formats.Options, outputDir string, seed int64) error {
 	// Generate and write daily stock prices concurrently
-	if err := generateAndWriteDailyStockPricesConcurrently(counts.DailyStockPrices, companies, exchanges, format, outputDir); err != nil {
+	if err := generateAndWriteDailyStockPricesConcurrently(ctx, counts.DailyStockPrices, companies, exchanges, opts, outputDir, seed); err != nil {
 		return fmt.Errorf("error generating daily stock prices: %w", err)
 	}
 
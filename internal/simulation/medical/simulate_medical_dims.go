@@ -2,57 +2,58 @@ package medical
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
-	gf "github.com/brianvoe/gofakeit/v6"
-
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/models/medical"
 )
 
-func GeneratePatients(count int) []medical.Patient {
+func GeneratePatients(count int, seed int64) []medical.Patient {
 	if count <= 0 {
 		return []medical.Patient{}
 	}
+	faker := common.NewFaker(seed, "dim_patients", 0)
 	patients := make([]medical.Patient, count)
 	for i := 0; i < count; i++ {
-		dob := gf.DateRange(time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+		dob := faker.DateRange(time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
 		patients[i] = medical.Patient{
 			PatientID:   i + 1,
-			PatientName: gf.Name(),
+			PatientName: faker.Name(),
 			DateOfBirth: dob,
-			Gender:      gf.Gender(),
+			Gender:      faker.Gender(),
 		}
 	}
 	return patients
 }
 
-func GenerateDoctors(count int) []medical.Doctor {
+func GenerateDoctors(count int, seed int64) []medical.Doctor {
 	if count <= 0 {
 		return []medical.Doctor{}
 	}
+	faker := common.NewFaker(seed, "dim_doctors", 0)
 	doctors := make([]medical.Doctor, count)
 	specializations := []string{"Cardiology", "Neurology", "Pediatrics", "General Practice", "Oncology"}
 	for i := 0; i < count; i++ {
 		doctors[i] = medical.Doctor{
 			DoctorID:       i + 1,
-			DoctorName:     fmt.Sprintf("Dr. %s", gf.Name()),
-			Specialization: specializations[rand.Intn(len(specializations))],
+			DoctorName:     fmt.Sprintf("Dr. %s", faker.Name()),
+			Specialization: specializations[faker.Rand.Intn(len(specializations))],
 		}
 	}
 	return doctors
 }
 
-func GenerateClinics(count int) []medical.Clinic {
+func GenerateClinics(count int, seed int64) []medical.Clinic {
 	if count <= 0 {
 		return []medical.Clinic{}
 	}
+	faker := common.NewFaker(seed, "dim_clinics", 0)
 	clinics := make([]medical.Clinic, count)
 	for i := 0; i < count; i++ {
-		addr := gf.Address()
+		addr := faker.Address()
 		clinics[i] = medical.Clinic{
 			ClinicID:   i + 1,
-			ClinicName: fmt.Sprintf("%s Clinic", gf.LastName()),
+			ClinicName: fmt.Sprintf("%s Clinic", faker.LastName()),
 			Address:    addr.Address,
 		}
 	}
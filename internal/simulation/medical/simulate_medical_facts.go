@@ -1,30 +1,43 @@
 package medical
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
-	"runtime"
-	"sync"
 	"time"
 
 	gf "github.com/brianvoe/gofakeit/v6"
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/formats"
 	"github.com/peekknuf/Gengo/internal/models/medical"
+	"golang.org/x/sync/errgroup"
+)
+
+const ctxCheckInterval = 4096 // Rows between checks for a cancelled run
+
+// Appointments are spread between these dates; a fixed end keeps seeded runs independent of the clock.
+var (
+	appointmentsStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	appointmentsEnd   = time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
 )
 
 // generateAppointmentsChunk is a worker function that generates a chunk of appointments.
-func generateAppointmentsChunk(startID, count int, patients []medical.Patient, doctors []medical.Doctor, clinics []medical.Clinic) []medical.Appointment {
+func generateAppointmentsChunk(ctx context.Context, startID, count int, patients []medical.Patient, doctors []medical.Doctor, clinics []medical.Clinic, faker *gf.Faker) ([]medical.Appointment, error) {
 	if count <= 0 || len(patients) == 0 || len(doctors) == 0 || len(clinics) == 0 {
-		return []medical.Appointment{}
+		return []medical.Appointment{}, nil
 	}
 	appointments := make([]medical.Appointment, count)
 	diagnoses := []string{"Common Cold", "Hypertension", "Diabetes", "Routine Check-up", "Injury"}
 
 	for i := 0; i < count; i++ {
-		patient := patients[rand.Intn(len(patients))]
-		doctor := doctors[rand.Intn(len(doctors))]
-		clinic := clinics[rand.Intn(len(clinics))]
-		appDate := gf.DateRange(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Now())
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		patient := patients[faker.Rand.Intn(len(patients))]
+		doctor := doctors[faker.Rand.Intn(len(doctors))]
+		clinic := clinics[faker.Rand.Intn(len(clinics))]
+		appDate := faker.DateRange(appointmentsStart, appointmentsEnd)
 
 		appointments[i] = medical.Appointment{
 			AppointmentID:   int64(startID + i),
@@ -32,23 +45,24 @@ func generateAppointmentsChunk(startID, count int, patients []medical.Patient, d
 			DoctorID:        doctor.DoctorID,
 			ClinicID:        clinic.ClinicID,
 			AppointmentDate: appDate,
-			Diagnosis:       diagnoses[rand.Intn(len(diagnoses))],
+			Diagnosis:       diagnoses[faker.Rand.Intn(len(diagnoses))],
 		}
 	}
-	return appointments
+	return appointments, nil
 }
 
 // generateAppointmentsConcurrently generates the appointment fact data in parallel.
-func generateAppointmentsConcurrently(count int, patients []medical.Patient, doctors []medical.Doctor, clinics []medical.Clinic) []medical.Appointment {
+func generateAppointmentsConcurrently(ctx context.Context, count int, patients []medical.Patient, doctors []medical.Doctor, clinics []medical.Clinic, seed int64) ([]medical.Appointment, error) {
 	if count <= 0 {
-		return []medical.Appointment{}
+		return []medical.Appointment{}, nil
 	}
 
-	numWorkers := runtime.NumCPU()
+	numWorkers := common.ShardCount(count)
 	appointmentsPerWorker := (count + numWorkers - 1) / numWorkers
 
-	var wg sync.WaitGroup
-	resultsChan := make(chan []medical.Appointment, numWorkers)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(common.MaxParallelShards())
+	results := make([][]medical.Appointment, numWorkers)
 
 	for i := 0; i < numWorkers; i++ {
 		startID := (i * appointmentsPerWorker) + 1
@@ -59,23 +73,25 @@ func generateAppointmentsConcurrently(count int, patients []medical.Patient, doc
 		}
 
 		if numToGen > 0 {
-			wg.Add(1)
-			go func(sID, c int) {
-				defer wg.Done()
-				resultsChan <- generateAppointmentsChunk(sID, c, patients, doctors, clinics)
-			}(startID, numToGen)
+			shard, sID, c := i, startID, numToGen
+			g.Go(func() (err error) {
+				faker := common.NewFaker(seed, "fact_appointments", shard)
+				results[shard], err = generateAppointmentsChunk(ctx, sID, c, patients, doctors, clinics, faker)
+				return err
+			})
 		}
 	}
 
-	wg.Wait()
-	close(resultsChan)
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
 	finalAppointments := make([]medical.Appointment, 0, count)
-	for result := range resultsChan {
+	for _, result := range results {
 		finalAppointments = append(finalAppointments, result...)
 	}
 
-	return finalAppointments
+	return finalAppointments, nil
 }
 
 type MedicalRowCounts struct {
@@ -85,10 +101,13 @@ type MedicalRowCounts struct {
 	Appointments int
 }
 
-func GenerateMedicalModelData(counts MedicalRowCounts, patients []medical.Patient, doctors []medical.Doctor, clinics []medical.Clinic, format string, outputDir string) error {
+func GenerateMedicalModelData(ctx context.Context, counts MedicalRowCounts, patients []medical.Patient, doctors []medical.Doctor, clinics []medical.Clinic, opts formats.Options, outputDir string, seed int64) error {
 	// Generate and write appointments
-	appointments := generateAppointmentsConcurrently(counts.Appointments, patients, doctors, clinics)
-	if err := formats.WriteSliceData(appointments, "fact_appointments", format, outputDir); err != nil {
+	appointments, err := generateAppointmentsConcurrently(ctx, counts.Appointments, patients, doctors, clinics, seed)
+	if err != nil {
+		return fmt.Errorf("error generating appointments: %w", err)
+	}
+	if err := formats.WriteSliceData(ctx, appointments, "fact_appointments", opts, outputDir); err != nil {
 		return fmt.Errorf("error generating appointments: %w", err)
 	}
 
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"github.com/peekknuf/Gengo/internal/common"
 	"github.com/peekknuf/Gengo/internal/core"
+	"github.com/peekknuf/Gengo/internal/formats"
+	"github.com/peekknuf/Gengo/internal/schema"
 	"github.com/peekknuf/Gengo/internal/utils"
 	"github.com/spf13/cobra"
 )
@@ -12,13 +20,57 @@ import (
 var (
 	modelType string
 	targetGB  float64
+	scale     int
 	format    string
 	outputDir string
+	seed      int64
+	resumeDir string
+	ddl       string
+	modelFile string
+	rows      string
+	rowsFile  string
+
+	csvDelimiter string
+	csvQuoting   string
+	csvNull      string
+	csvHeader    bool
+	csvCRLF      bool
+
+	compression string
+	arrowIPC    string
+	avroCodec   string
+
+	parquetCodec         string
+	parquetLevel         int
+	parquetRowGroupBytes int64
+	parquetPageBytes     int64
+	parquetDictionary    bool
+	parquetStatistics    bool
+	parquetVersion       string
+
+	partitionBy       string
+	maxOpenPartitions int
 )
 
+// parquetFlags are the flags that set the writer properties of Parquet files.
+var parquetFlags = []string{"parquet-codec", "parquet-compression-level", "parquet-row-group-bytes", "parquet-page-bytes", "parquet-dictionary", "parquet-statistics", "parquet-version"}
+
+// arrowFlags are the flags that set how Arrow IPC files are written.
+var arrowFlags = []string{"arrow-ipc"}
+
+// avroFlags are the flags that set how Avro files are written.
+var avroFlags = []string{"avro-codec"}
+
+// csvFlags are the flags that set the CSV dialect.
+var csvFlags = []string{"csv-delimiter", "csv-quoting", "csv-null", "csv-header", "csv-crlf"}
+
 var RootCmd = &cobra.Command{
-	Use:   "gengo",
-	Short: "Large-scale synthetic relational data generator",
+	Use:     "gengo",
+	Short:   "Large-scale synthetic relational data generator",
+	Version: core.GengoVersion(),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		fmt.Println(utils.Logo())
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
@@ -30,46 +82,405 @@ var generateCmd = &cobra.Command{
 	Long: `Generates normalized (3NF) synthetic datasets across four domains:
   ecommerce, ecommerce-ds (TPC-DS), financial, and medical.
 
-Supports CSV and Apache Parquet output formats.
+Supports CSV, JSON Lines, Apache Parquet, Arrow IPC and Avro output formats.
+--format arrow writes Arrow IPC files (.arrow) for memory-mapping, or with
+--arrow-ipc stream the streaming format (.arrows). --format avro writes Avro
+object container files (.avro) with the schema of each table embedded and
+blocks compressed with --avro-codec deflate|snappy|null. ecommerce-ds can
+also be written like TPC-DS dsdgen (--format dsdgen): '|'-delimited .dat files
+named after the TPC-DS tables, e.g. store_sales.dat, or store_sales_1_8.dat for
+the first of eight files of a table written in parallel, as dsdgen -parallel names them.
+
+With --format iceberg every table is written as an Iceberg v2 table: Parquet
+data files under <table>/data and a single snapshot with column statistics
+under <table>/metadata, readable through a Hadoop catalog. --format delta
+writes Delta Lake tables instead: the same data files and a single commit
+with file statistics in <table>/_delta_log.
+
+The CSV dialect is set with --csv-delimiter (e.g. ';' or tab), --csv-quoting
+minimal|all|none, --csv-null (the token written for NULLs), --csv-header and
+--csv-crlf. The dialect is recorded in the run manifest.
+
+With --compression gzip|zstd|lz4 CSV and JSON Lines files are compressed as
+they are written, each shard by its own worker, and named e.g. .csv.gz or
+.jsonl.zst. With arrow output, zstd and lz4 compress the IPC buffers.
+
+The Parquet writer is set with --parquet-codec zstd|gzip|lz4|snappy|none,
+--parquet-compression-level, --parquet-row-group-bytes, --parquet-page-bytes,
+--parquet-dictionary, --parquet-statistics and --parquet-version 1.0|2.4|2.6,
+for dimension and fact tables alike.
+
+With --partition-by year,month (or day, or a column such as store_sk) the
+ecommerce-ds fact tables are written in Hive-style partitions, e.g.
+fact_store_sales/year=2001/month=04/part-00003.parquet. Each shard writes one
+file per partition, keeping at most --max-open-partitions of them open and
+spilling the rows of the others to disk until the shard is done.
+
+The same --seed, model, size and format always produce identical files.
+Iceberg metadata holds absolute paths, so it is identical when written to the
+same directory. Without --seed a random seed is picked and printed so the run can be repeated.
+
+With --ddl postgres|duckdb|spark|clickhouse the CREATE TABLE statements of the
+model, with column types, primary keys and foreign keys, are written to
+schema.sql in the output directory.
+
+With --scale-factor 1|10|100|1000|3000|10000 instead of --size, ecommerce-ds
+generates the row counts dsdgen generates at that TPC-DS scale factor, with its
+date_dim calendar, so results are comparable to published numbers.
+
+--rows table=N,... sets the row counts of individual tables, and --rows-file
+reads them from a YAML or JSON file mapping table names to row counts. The
+other tables keep the row counts derived from --size; --rows wins over the file.
+
+A custom model is declared in a YAML file passed with --model-file: its
+tables, columns and their generators, keys and size ratios.
+
+An interrupted run is continued with --resume <dir>: the configuration is read
+from the run manifest and only the missing files are generated.
 
 Example:
   gengo gen --model ecommerce-ds --size 10 --format parquet --output my-data`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Starting data model generation process...")
 
-		// --- Get User Input from flags or interactive prompts ---
-		model, counts, outputFormat, dir, err := core.GetUserInput(modelType, targetGB, format, outputDir)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "\nError getting user input: %v\n", err)
-			os.Exit(1)
-		}
+		var generate func(ctx context.Context) error
+		if resumeDir != "" {
+			configFlags := []string{"model", "size", "format", "output", "seed", "ddl", "model-file", "rows", "rows-file", "scale-factor", "compression", "partition-by", "max-open-partitions"}
+			configFlags = append(append(append(append(configFlags, csvFlags...), parquetFlags...), arrowFlags...), avroFlags...)
+			for _, name := range configFlags {
+				if cmd.Flags().Changed(name) {
+					fmt.Fprintf(os.Stderr, "\nError: --resume reads the configuration from the run manifest and cannot be combined with --%s\n", name)
+					os.Exit(1)
+				}
+			}
+			generate = func(ctx context.Context) error {
+				return core.ResumeModelData(ctx, resumeDir)
+			}
+		} else {
+			if ddl != "" {
+				if err := schema.CheckDialect(ddl); err != nil {
+					fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if modelFile != "" {
+				if cmd.Flags().Changed("model") {
+					fmt.Fprintln(os.Stderr, "\nError: --model-file declares the model and cannot be combined with --model")
+					os.Exit(1)
+				}
+				name, err := core.LoadModelFile(modelFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+					os.Exit(1)
+				}
+				modelType = name
+			}
+
+			if cmd.Flags().Changed("scale-factor") && scale <= 0 {
+				fmt.Fprintln(os.Stderr, "\nError: --scale-factor must be positive")
+				os.Exit(1)
+			}
+			if cmd.Flags().Changed("scale-factor") && cmd.Flags().Changed("size") {
+				fmt.Fprintln(os.Stderr, "\nError: --scale-factor sizes the tables and cannot be combined with --size")
+				os.Exit(1)
+			}
 
-		fmt.Printf("\nConfiguration:\n Target Format: %s\n Output Directory: %s\n", outputFormat, dir)
+			rowCounts := make(map[string]int)
+			if rowsFile != "" {
+				fileCounts, err := core.ReadRowCountsFile(rowsFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+					os.Exit(1)
+				}
+				for table, n := range fileCounts {
+					rowCounts[table] = n
+				}
+			}
+			if rows != "" {
+				flagCounts, err := core.ParseRowCounts(rows)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+					os.Exit(1)
+				}
+				for table, n := range flagCounts {
+					rowCounts[table] = n
+				}
+			}
+
+			dialect, err := csvDialect()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			parquetOptions := formats.ParquetOptions{
+				Codec:            parquetCodec,
+				CompressionLevel: parquetLevel,
+				RowGroupBytes:    parquetRowGroupBytes,
+				PageBytes:        parquetPageBytes,
+				Statistics:       parquetStatistics,
+				Version:          parquetVersion,
+			}
+			if cmd.Flags().Changed("parquet-dictionary") {
+				parquetOptions.Dictionary = &parquetDictionary
+			}
+			if err := parquetOptions.Check(); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+
+			// --- Get User Input from flags or interactive prompts ---
+			model, size, counts, outputFormat, dir, err := core.GetUserInput(modelType, targetGB, scale, format, outputDir, rowCounts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError getting user input: %v\n", err)
+				os.Exit(1)
+			}
+			for only, flags := range map[string][]string{"csv": csvFlags, "parquet": parquetFlags, "arrow": arrowFlags, "avro": avroFlags} {
+				if formats.DataFormat(outputFormat) == only {
+					continue
+				}
+				for _, name := range flags {
+					if cmd.Flags().Changed(name) {
+						fmt.Fprintf(os.Stderr, "\nError: --%s only applies to %s output, not %s\n", name, only, outputFormat)
+						os.Exit(1)
+					}
+				}
+			}
+			if err := formats.CheckCompression(compression, outputFormat); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			if err := formats.CheckArrowIPC(arrowIPC); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			if err := formats.CheckAvroCodec(avroCodec); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			if cmd.Flags().Changed("max-open-partitions") && partitionBy == "" {
+				fmt.Fprintln(os.Stderr, "\nError: --max-open-partitions only applies with --partition-by")
+				os.Exit(1)
+			}
+			partitioning := formats.Partitioning{Keys: formats.ParsePartitionKeys(partitionBy), MaxOpen: maxOpenPartitions}
+			if len(partitioning.Keys) > 0 && formats.DataFormat(outputFormat) != outputFormat {
+				fmt.Fprintf(os.Stderr, "\nError: --partition-by writes Hive-style partitions and does not apply to %s output\n", outputFormat)
+				os.Exit(1)
+			}
+			if err := core.CheckPartitioning(model, partitioning); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+
+			if !cmd.Flags().Changed("seed") {
+				seed = common.RandomSeed()
+			}
+
+			fmt.Printf("\nConfiguration:\n Target Format: %s\n Output Directory: %s\n Seed: %d\n", outputFormat, dir, seed)
+			generate = func(ctx context.Context) error {
+				return core.GenerateModelData(ctx, model, counts, outputFormat, dir, seed, size, ddl, core.OutputOptions{CSV: dialect, Compression: compression, ArrowIPC: arrowIPC, AvroCodec: avroCodec, Parquet: parquetOptions, Partitioning: partitioning})
+			}
+		}
 
 		fmt.Println("\nStarting generation (this might take a while)...")
 
+		// The first SIGINT/SIGTERM cancels the run so workers can stop and clean up;
+		// a second one kills the process straight away.
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
 		// --- Call the Main Generation Orchestrator ---
-		err = core.GenerateModelData(model, counts, outputFormat, dir)
+		err := generate(ctx)
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "\nGeneration interrupted.")
+			os.Exit(1)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "\nError during data generation: %v\n", err)
 			os.Exit(1)
 		}
 
-		// If no error returned from GenerateModelData
+		// If no error returned from the orchestrator
 		fmt.Println("\nProcess completed successfully.")
 	},
 }
 
+var validateCmd = &cobra.Command{
+	Use:   "validate <dir>",
+	Short: "Check the keys and row counts of a generated dataset",
+	Long: `Reads a generated dataset (CSV, JSON Lines or Parquet, including sharded fact
+files) and checks that primary keys are present and unique, that every foreign
+key refers to an existing row of its table, and that row counts match the run
+manifest and the planned row counts. Violations are reported per table and
+make the command exit with a non-zero status.
+
+The model and planned row counts are read from manifest.json. For a dataset
+without one, pass --model or --model-file, and --size to compare with the
+sizing estimates. CSV files are read in the dialect recorded in the manifest,
+or the one given with the --csv-* flags.
+
+Memory is bounded by the largest referenced table and the largest shard: the
+keys of tables other tables refer to (dimensions) are held in memory, about 40
+bytes per key. Fact tables are checked shard by shard; each shard's keys are
+written to a temporary file under $TMPDIR (8 bytes per key) and merged with
+the other shards' to find duplicates, and returns are checked against the keys
+of the sales shard they were written next to.
+
+Example:
+  gengo validate my-data`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if modelFile != "" {
+			name, err := core.LoadModelFile(modelFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			modelType = name
+		}
+
+		dialect, err := csvDialect()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(1)
+		}
+
+		report, err := core.ValidateDataset(ctx, args[0], modelType, targetGB, dialect)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError validating dataset: %v\n", err)
+			os.Exit(1)
+		}
+		report.Print()
+		if report.Violations() > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Write the SQL DDL of a data model",
+	Long: `Writes the CREATE TABLE statements of a data model, with column types,
+primary keys and foreign keys, in the given SQL dialect: postgres, duckdb,
+spark or clickhouse. Column types follow the output format, since CSV and
+JSON Lines lay out some columns differently from Parquet.
+
+The DDL is written to schema.sql in --output, or to standard output.
+
+Example:
+  gengo schema --model ecommerce-ds --format parquet --ddl duckdb`,
+	Args: cobra.NoArgs,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Keep standard output clean when the DDL is written there.
+		if outputDir != "" {
+			fmt.Println(utils.Logo())
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if modelFile != "" {
+			name, err := core.LoadModelFile(modelFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+			modelType = name
+		}
+		if modelType == "" {
+			fmt.Fprintln(os.Stderr, "\nError: --model or --model-file is required")
+			os.Exit(1)
+		}
+		sql, err := core.SchemaDDL(modelType, format, ddl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(1)
+		}
+		if outputDir == "" {
+			fmt.Print(sql)
+			return
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError creating output directory %s: %v\n", outputDir, err)
+			os.Exit(1)
+		}
+		if err := core.WriteSchemaFile(outputDir, sql); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s\n", filepath.Join(outputDir, core.SchemaFile))
+	},
+}
+
 func init() {
 	RootCmd.AddCommand(generateCmd)
+	RootCmd.AddCommand(validateCmd)
+	RootCmd.AddCommand(schemaCmd)
 	generateCmd.Flags().StringVarP(&modelType, "model", "m", "", "Data model to generate (ecommerce, ecommerce-ds, financial, medical)")
 	generateCmd.Flags().Float64VarP(&targetGB, "size", "s", 0, "Approximate target size in GB (e.g., 0.5, 10)")
-	generateCmd.Flags().StringVarP(&format, "format", "f", "", "Output format (csv, json, parquet)")
+	generateCmd.Flags().IntVar(&scale, "scale-factor", 0, "TPC-DS scale factor to size ecommerce-ds by instead of --size (1, 10, 100, 1000, 3000, 10000)")
+	generateCmd.Flags().StringVarP(&format, "format", "f", "", "Output format (csv, json, parquet, arrow, avro, iceberg, delta; dsdgen for ecommerce-ds)")
 	generateCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory name")
+	generateCmd.Flags().Int64Var(&seed, "seed", 0, "Random seed; the same seed reproduces the same dataset (default: random)")
+	generateCmd.Flags().StringVar(&resumeDir, "resume", "", "Continue the interrupted run in this output directory")
+	generateCmd.Flags().StringVar(&modelFile, "model-file", "", "Generate the custom model declared in this YAML file")
+	generateCmd.Flags().StringVar(&rows, "rows", "", "Row counts of individual tables, as table=N pairs separated by commas")
+	generateCmd.Flags().StringVar(&rowsFile, "rows-file", "", "YAML or JSON file mapping table names to row counts")
+	generateCmd.Flags().StringVar(&ddl, "ddl", "", "Also write schema.sql in this SQL dialect (postgres, duckdb, spark, clickhouse)")
+	addCSVFlags(generateCmd, "in CSV output")
+	generateCmd.Flags().StringVar(&compression, "compression", "", "Compress CSV and JSON Lines files (gzip, zstd, lz4) or Arrow IPC buffers (zstd, lz4)")
+	generateCmd.Flags().StringVar(&arrowIPC, "arrow-ipc", "file", "Arrow IPC variant of arrow output (file, stream)")
+	generateCmd.Flags().StringVar(&avroCodec, "avro-codec", "deflate", "Compression codec of the blocks of avro output (deflate, snappy, null)")
+	generateCmd.Flags().StringVar(&parquetCodec, "parquet-codec", formats.DefaultParquetOptions.Codec, "Compression codec of Parquet files (zstd, gzip, lz4, snappy, none)")
+	generateCmd.Flags().IntVar(&parquetLevel, "parquet-compression-level", 0, "Compression level of the gzip (1-9) or zstd (1-22) Parquet codec; 0 uses the codec default")
+	generateCmd.Flags().Int64Var(&parquetRowGroupBytes, "parquet-row-group-bytes", 0, "Target size of Parquet row groups in bytes; 0 writes a row group per 64K rows")
+	generateCmd.Flags().Int64Var(&parquetPageBytes, "parquet-page-bytes", formats.DefaultParquetOptions.PageBytes, "Target size of Parquet data pages in bytes")
+	generateCmd.Flags().BoolVar(&parquetDictionary, "parquet-dictionary", false, "Dictionary-encode all Parquet columns, or none with =false (default: dimension tables only)")
+	generateCmd.Flags().BoolVar(&parquetStatistics, "parquet-statistics", formats.DefaultParquetOptions.Statistics, "Write Parquet column statistics")
+	generateCmd.Flags().StringVar(&parquetVersion, "parquet-version", formats.DefaultParquetOptions.Version, "Parquet format version (1.0, 2.4, 2.6)")
+	generateCmd.Flags().StringVar(&partitionBy, "partition-by", "", "Write ecommerce-ds fact tables in Hive-style partitions by these keys (year, month, day or a column, e.g. store_sk)")
+	generateCmd.Flags().IntVar(&maxOpenPartitions, "max-open-partitions", formats.DefaultMaxOpenPartitions, "Partition files each fact shard keeps open with --partition-by")
+
+	validateCmd.Flags().StringVarP(&modelType, "model", "m", "", "Data model of a dataset without manifest.json")
+	validateCmd.Flags().StringVar(&modelFile, "model-file", "", "Custom model of a dataset without manifest.json")
+	validateCmd.Flags().Float64VarP(&targetGB, "size", "s", 0, "Target size in GB the dataset was generated for, to check row counts without manifest.json")
+	addCSVFlags(validateCmd, "in CSV files without manifest.json")
+
+	schemaCmd.Flags().StringVarP(&modelType, "model", "m", "", "Data model (ecommerce, ecommerce-ds, financial, medical)")
+	schemaCmd.Flags().StringVar(&modelFile, "model-file", "", "Custom model declared in a YAML file")
+	schemaCmd.Flags().StringVarP(&format, "format", "f", "parquet", "Output format the column types follow (csv, json, parquet, arrow, avro, iceberg, delta; dsdgen for ecommerce-ds)")
+	schemaCmd.Flags().StringVar(&ddl, "ddl", "", "SQL dialect (postgres, duckdb, spark, clickhouse)")
+	schemaCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Directory to write schema.sql to (default: standard output)")
+	schemaCmd.MarkFlagRequired("ddl")
+}
+
+// addCSVFlags adds the flags that set the CSV dialect to cmd.
+func addCSVFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVar(&csvDelimiter, "csv-delimiter", ",", "Field delimiter "+usage+" (a single character, or tab)")
+	cmd.Flags().StringVar(&csvQuoting, "csv-quoting", "minimal", "When fields are quoted "+usage+" (minimal, all, none)")
+	cmd.Flags().StringVar(&csvNull, "csv-null", "", "Token for NULL values "+usage)
+	cmd.Flags().BoolVar(&csvHeader, "csv-header", true, "Whether CSV files "+usage+" start with a header line")
+	cmd.Flags().BoolVar(&csvCRLF, "csv-crlf", false, "Whether lines "+usage+" end in CRLF instead of LF")
+}
+
+// csvDialect returns the CSV dialect set by the flags.
+func csvDialect() (formats.CSVDialect, error) {
+	d := formats.CSVDialect{
+		Delimiter: formats.ParseCSVDelimiter(csvDelimiter),
+		Quoting:   csvQuoting,
+		Null:      csvNull,
+		Header:    csvHeader,
+		CRLF:      csvCRLF,
+	}
+	return d, d.Check()
 }
 
 func main() {
-	fmt.Println(utils.Logo())
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -1,12 +1,20 @@
 package tests
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -16,7 +24,12 @@ import (
 	"context"
 
 	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/file"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 )
 
 const defaultTestSizeGB = 0.1
@@ -97,6 +110,10 @@ func TestE2E(t *testing.T) {
 		{"financial", "parquet"},
 		{"medical", "csv"},
 		{"medical", "parquet"},
+		{"ecommerce", "json"},
+		{"ecommerce-ds", "json"},
+		{"financial", "json"},
+		{"medical", "json"},
 	}
 
 	for _, tc := range cases {
@@ -107,6 +124,133 @@ func TestE2E(t *testing.T) {
 	}
 }
 
+// TestModelFile generates the example custom model in every format and checks
+// its keys with gengo validate.
+func TestModelFile(t *testing.T) {
+	root := moduleRoot(t)
+	bin := binaryPath(t)
+	for _, format := range []string{"csv", "json", "parquet"} {
+		t.Run(format, func(t *testing.T) {
+			outputDir := filepath.Join(root, "tests", "output_shop_"+format)
+			if !keepOutput() {
+				defer os.RemoveAll(outputDir)
+			}
+			cmd := exec.CommandContext(t.Context(), bin, "gen",
+				"--model-file", filepath.Join(root, "examples", "shop.yaml"),
+				"--size", fmt.Sprintf("%.2f", getTestSizeGB()),
+				"--format", format,
+				"--output", outputDir,
+			)
+			cmd.Dir = root
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("gengo gen --model-file failed: %v\n%s", err, out)
+			}
+			validateOutputDir(t, outputDir)
+
+			validate := exec.CommandContext(t.Context(), bin, "validate", outputDir)
+			validate.Dir = root
+			if out, err := validate.CombinedOutput(); err != nil {
+				t.Errorf("gengo validate failed: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+// TestResume interrupts a run after the fact by dropping two fact shards from
+// its output and manifest, then checks that --resume restores exactly the
+// files of the uninterrupted run, schema.sql included.
+func TestResume(t *testing.T) {
+	root := moduleRoot(t)
+	bin := binaryPath(t)
+	outputDir := filepath.Join(root, "tests", "output_resume")
+	if !keepOutput() {
+		defer os.RemoveAll(outputDir)
+	}
+
+	gen := func(args ...string) {
+		t.Helper()
+		cmd := exec.CommandContext(t.Context(), bin, append([]string{"gen"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("gengo %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	gen("--model", "ecommerce-ds", "--size", fmt.Sprintf("%.2f", getTestSizeGB()), "--format", "csv", "--output", outputDir, "--seed", "7", "--ddl", "duckdb")
+	want := readDirFiles(t, outputDir)
+	if !bytes.Contains(want["schema.sql"], []byte("CREATE TABLE fact_store_sales (")) {
+		t.Errorf("schema.sql does not create fact_store_sales:\n%s", want["schema.sql"])
+	}
+
+	manifestPath := filepath.Join(outputDir, "manifest.json")
+	var manifest map[string]interface{}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("cannot read manifest: %v", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("cannot parse manifest: %v", err)
+	}
+	dropped := map[string]bool{"fact_store_sales_0.csv": true, "fact_inventory_0.csv": true}
+	var files []interface{}
+	for _, f := range manifest["files"].([]interface{}) {
+		if !dropped[f.(map[string]interface{})["name"].(string)] {
+			files = append(files, f)
+		}
+	}
+	var tables []interface{}
+	for _, tbl := range manifest["tables"].([]interface{}) {
+		switch tbl.(map[string]interface{})["name"] {
+		case "fact_store_sales", "fact_store_returns", "fact_inventory":
+		default:
+			tables = append(tables, tbl)
+		}
+	}
+	manifest["files"], manifest["tables"], manifest["complete"] = files, tables, false
+	data, _ = json.MarshalIndent(manifest, "", "  ")
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		t.Fatalf("cannot write manifest: %v", err)
+	}
+	for name := range dropped {
+		os.Remove(filepath.Join(outputDir, name))
+	}
+	os.Remove(filepath.Join(outputDir, "_SUCCESS"))
+	os.Remove(filepath.Join(outputDir, "schema.sql"))
+
+	gen("--resume", outputDir)
+	got := readDirFiles(t, outputDir)
+	// The manifest was rewritten above, so only its completion is compared.
+	delete(want, "manifest.json")
+	if !bytes.Contains(got["manifest.json"], []byte(`"complete": true`)) {
+		t.Errorf("manifest not marked complete after resume")
+	}
+	delete(got, "manifest.json")
+	for name, content := range want {
+		if !bytes.Equal(got[name], content) {
+			t.Errorf("%s differs after resume", name)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("resumed run has %d files, uninterrupted run had %d", len(got), len(want))
+	}
+}
+
+func readDirFiles(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read output directory %s: %v", dir, err)
+	}
+	files := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatalf("cannot read %s: %v", e.Name(), err)
+		}
+		files[e.Name()] = data
+	}
+	return files
+}
+
 func runE2ETest(t *testing.T, tc testCase) {
 	t.Helper()
 	root := moduleRoot(t)
@@ -162,6 +306,12 @@ func runE2ETest(t *testing.T, tc testCase) {
 
 	validateOutputDir(t, outputDir)
 	validateSchemaFiles(t, outputDir, tc.model, tc.format)
+
+	validate := exec.CommandContext(ctx, bin, "validate", outputDir)
+	validate.Dir = root
+	if out, err := validate.CombinedOutput(); err != nil {
+		t.Errorf("gengo validate failed: %v\n%s", err, out)
+	}
 }
 
 func validateOutputDir(t *testing.T, dir string) {
@@ -174,8 +324,17 @@ func validateOutputDir(t *testing.T, dir string) {
 		t.Fatalf("expected at least 2 files in output dir, got %d", len(entries))
 	}
 
+	if _, err := os.Stat(filepath.Join(dir, "_SUCCESS")); err != nil {
+		t.Fatalf("expected _SUCCESS marker in %s: %v", dir, err)
+	}
+
+	validateManifest(t, dir)
+
 	totalSize := int64(0)
 	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Fatalf("temporary file %s left in output dir", e.Name())
+		}
 		if !e.IsDir() {
 			info, err := e.Info()
 			if err == nil {
@@ -189,6 +348,71 @@ func validateOutputDir(t *testing.T, dir string) {
 	t.Logf("output dir: %d files, %d bytes total", len(entries), totalSize)
 }
 
+// validateManifest checks that manifest.json lists every data file in dir with
+// its size and checksum, and that the table row counts add up.
+func validateManifest(t *testing.T, dir string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("cannot read manifest: %v", err)
+	}
+	var manifest struct {
+		Complete bool `json:"complete"`
+		Tables   []struct {
+			Name    string            `json:"name"`
+			Rows    int64             `json:"rows"`
+			Columns []json.RawMessage `json:"columns"`
+		} `json:"tables"`
+		Files []struct {
+			Name   string `json:"name"`
+			Table  string `json:"table"`
+			Rows   int64  `json:"rows"`
+			Bytes  int64  `json:"bytes"`
+			SHA256 string `json:"sha256"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("cannot parse manifest: %v", err)
+	}
+	if !manifest.Complete {
+		t.Errorf("manifest not marked complete")
+	}
+
+	listed := make(map[string]bool)
+	tableRows := make(map[string]int64)
+	for _, f := range manifest.Files {
+		listed[f.Name] = true
+		tableRows[f.Table] += f.Rows
+		content, err := os.ReadFile(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Errorf("manifest lists %s: %v", f.Name, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if int64(len(content)) != f.Bytes || hex.EncodeToString(sum[:]) != f.SHA256 {
+			t.Errorf("%s does not match its size or checksum in the manifest", f.Name)
+		}
+	}
+	for _, tbl := range manifest.Tables {
+		if tbl.Rows != tableRows[tbl.Name] {
+			t.Errorf("table %s has %d rows in the manifest, its files have %d", tbl.Name, tbl.Rows, tableRows[tbl.Name])
+		}
+		if len(tbl.Columns) == 0 {
+			t.Errorf("table %s has no columns in the manifest", tbl.Name)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read output directory %s: %v", dir, err)
+	}
+	for _, e := range entries {
+		if name := e.Name(); name != "manifest.json" && name != "_SUCCESS" && !listed[name] {
+			t.Errorf("%s is missing from the manifest", name)
+		}
+	}
+}
+
 type fileSpec struct {
 	baseName string
 	sharded  bool
@@ -232,6 +456,10 @@ func getSchemaSpecs(model string) (dims []string, facts []fileSpec) {
 			{baseName: "fact_store_sales", sharded: true},
 			{baseName: "fact_catalog_sales", sharded: true},
 			{baseName: "fact_web_sales", sharded: true},
+			{baseName: "fact_store_returns", sharded: true},
+			{baseName: "fact_catalog_returns", sharded: true},
+			{baseName: "fact_web_returns", sharded: true},
+			{baseName: "fact_inventory", sharded: true},
 		}
 	case "financial":
 		dims = []string{
@@ -260,6 +488,8 @@ func getFileExt(format string) string {
 		return ".csv"
 	case "parquet":
 		return ".parquet"
+	case "json":
+		return ".jsonl"
 	default:
 		return "." + format
 	}
@@ -316,7 +546,75 @@ func validateFileContent(t *testing.T, path, format, model, tableName string) {
 		validateCSVContent(t, path, model, tableName)
 	case "parquet":
 		validateParquetContent(t, path, model, tableName)
+	case "json":
+		validateJSONLContent(t, path, model, tableName)
+	}
+}
+
+func validateJSONLContent(t *testing.T, path, model, tableName string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Errorf("cannot open JSON Lines file %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	expectedKeys := getExpectedCSVHeaders(model, tableName)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	rows := 0
+	for rows < 3 && scanner.Scan() {
+		rows++
+		keys, err := jsonObjectKeys(scanner.Bytes())
+		if err != nil {
+			t.Errorf("table %s row %d: invalid JSON object: %v", tableName, rows, err)
+			return
+		}
+		if expectedKeys == nil {
+			continue
+		}
+		if len(keys) != len(expectedKeys) {
+			t.Errorf("table %s row %d: expected %d keys, got %d (keys: %v)", tableName, rows, len(expectedKeys), len(keys), keys)
+			continue
+		}
+		for i, k := range keys {
+			if k != expectedKeys[i] {
+				t.Errorf("table %s row %d: key %d expected %q, got %q", tableName, rows, i, expectedKeys[i], k)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Errorf("error reading %s: %v", path, err)
+		return
+	}
+	if rows == 0 {
+		t.Errorf("JSON Lines file %s has zero rows", path)
+	}
+}
+
+// jsonObjectKeys returns the keys of a flat JSON object in document order.
+func jsonObjectKeys(line []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected an object, got %v (%v)", tok, err)
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
 	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return keys, nil
 }
 
 func validateCSVContent(t *testing.T, path, model, tableName string) {
@@ -546,6 +844,14 @@ func getECommerceDSCSVHeaders(tableName string) []string {
 		return []string{"cs_sold_date_sk", "cs_sold_time_sk", "cs_ship_date_sk", "cs_bill_customer_sk", "cs_bill_cdemo_sk", "cs_bill_hdemo_sk", "cs_bill_addr_sk", "cs_ship_customer_sk", "cs_ship_cdemo_sk", "cs_ship_hdemo_sk", "cs_ship_addr_sk", "cs_call_center_sk", "cs_catalog_page_sk", "cs_ship_mode_sk", "cs_warehouse_sk", "cs_item_sk", "cs_promo_sk", "cs_order_number", "cs_quantity", "cs_wholesale_cost", "cs_list_price", "cs_sales_price", "cs_ext_discount_amt", "cs_ext_sales_price", "cs_ext_wholesale_cost", "cs_ext_list_price", "cs_ext_tax", "cs_coupon_amt", "cs_ext_ship_cost", "cs_net_paid", "cs_net_paid_inc_tax", "cs_net_paid_inc_ship", "cs_net_paid_inc_ship_tax", "cs_net_profit"}
 	case "fact_web_sales":
 		return []string{"ws_sold_date_sk", "ws_sold_time_sk", "ws_ship_date_sk", "ws_item_sk", "ws_bill_customer_sk", "ws_bill_cdemo_sk", "ws_bill_hdemo_sk", "ws_bill_addr_sk", "ws_ship_customer_sk", "ws_ship_cdemo_sk", "ws_ship_hdemo_sk", "ws_ship_addr_sk", "ws_web_page_sk", "ws_web_site_sk", "ws_ship_mode_sk", "ws_warehouse_sk", "ws_promo_sk", "ws_order_number", "ws_quantity", "ws_wholesale_cost", "ws_list_price", "ws_sales_price", "ws_ext_discount_amt", "ws_ext_sales_price", "ws_ext_wholesale_cost", "ws_ext_list_price", "ws_ext_tax", "ws_coupon_amt", "ws_ext_ship_cost", "ws_net_paid", "ws_net_paid_inc_tax", "ws_net_paid_inc_ship", "ws_net_paid_inc_ship_tax", "ws_net_profit"}
+	case "fact_store_returns":
+		return []string{"sr_returned_date_sk", "sr_return_time_sk", "sr_item_sk", "sr_customer_sk", "sr_cdemo_sk", "sr_hdemo_sk", "sr_addr_sk", "sr_store_sk", "sr_reason_sk", "sr_ticket_number", "sr_return_quantity", "sr_return_amt", "sr_return_tax", "sr_return_amt_inc_tax", "sr_fee", "sr_return_ship_cost", "sr_refunded_cash", "sr_reversed_charge", "sr_store_credit", "sr_net_loss"}
+	case "fact_catalog_returns":
+		return []string{"cr_returned_date_sk", "cr_returned_time_sk", "cr_item_sk", "cr_refund_customer_sk", "cr_refund_cdemo_sk", "cr_refund_hdemo_sk", "cr_refund_addr_sk", "cr_returning_customer_sk", "cr_returning_cdemo_sk", "cr_returning_hdemo_sk", "cr_returning_addr_sk", "cr_call_center_sk", "cr_catalog_page_sk", "cr_ship_mode_sk", "cr_warehouse_sk", "cr_reason_sk", "cr_order_number", "cr_return_quantity", "cr_return_amount", "cr_return_tax", "cr_return_amt_inc_tax", "cr_fee", "cr_return_ship_cost", "cr_refunded_cash", "cr_reversed_charge", "cr_store_credit", "cr_net_loss"}
+	case "fact_inventory":
+		return []string{"inv_date_sk", "inv_item_sk", "inv_warehouse_sk", "inv_quantity_on_hand"}
+	case "fact_web_returns":
+		return []string{"wr_returned_date_sk", "wr_returned_time_sk", "wr_item_sk", "wr_refund_customer_sk", "wr_refund_cdemo_sk", "wr_refund_hdemo_sk", "wr_refund_addr_sk", "wr_returning_customer_sk", "wr_returning_cdemo_sk", "wr_returning_hdemo_sk", "wr_returning_addr_sk", "wr_web_page_sk", "wr_reason_sk", "wr_order_number", "wr_return_quantity", "wr_return_amt", "wr_return_tax", "wr_return_amt_inc_tax", "wr_fee", "wr_return_ship_cost", "wr_refunded_cash", "wr_reversed_charge", "wr_account_credit", "wr_net_loss"}
 	default:
 		return nil
 	}
@@ -600,3 +906,1006 @@ func getTotalDirSize(dir string) int64 {
 	})
 	return total
 }
+
+// gengo runs the gengo binary with args from the module root and returns its
+// combined output.
+func gengo(t *testing.T, args ...string) ([]byte, error) {
+	t.Helper()
+	cmd := exec.CommandContext(t.Context(), binaryPath(t), args...)
+	cmd.Dir = moduleRoot(t)
+	return cmd.CombinedOutput()
+}
+
+// mustGengo runs gengo with args and fails the test if it fails.
+func mustGengo(t *testing.T, args ...string) []byte {
+	t.Helper()
+	out, err := gengo(t, args...)
+	if err != nil {
+		t.Fatalf("gengo %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// testOutputDir returns an empty output directory under tests/, removed when
+// the test ends unless GENGO_KEEP_OUTPUT=1.
+func testOutputDir(t *testing.T, name string) string {
+	t.Helper()
+	dir := filepath.Join(moduleRoot(t), "tests", "output_"+name)
+	os.RemoveAll(dir)
+	if !keepOutput() {
+		t.Cleanup(func() { os.RemoveAll(dir) })
+	}
+	return dir
+}
+
+// TestCSVQuoting generates the financial model, whose company names hold
+// commas, in every quoting mode and reads it back with gengo validate. Without
+// quoting, a comma-delimited run must fail instead of writing fields it cannot
+// read back.
+func TestCSVQuoting(t *testing.T) {
+	for _, tc := range []struct{ quoting, delimiter string }{
+		{"minimal", ","},
+		{"all", ","},
+		{"none", "|"},
+		{"none", "tab"},
+	} {
+		t.Run(tc.quoting+"_"+tc.delimiter, func(t *testing.T) {
+			dir := testOutputDir(t, "quoting_"+tc.quoting)
+			mustGengo(t, "gen", "--model", "financial", "--size", "0.01", "--format", "csv", "--seed", "1",
+				"--csv-quoting", tc.quoting, "--csv-delimiter", tc.delimiter, "--output", dir)
+			validateOutputDir(t, dir)
+			mustGengo(t, "validate", dir)
+		})
+	}
+
+	t.Run("none_comma", func(t *testing.T) {
+		dir := testOutputDir(t, "quoting_none_comma")
+		out, err := gengo(t, "gen", "--model", "financial", "--size", "0.01", "--format", "csv", "--seed", "1",
+			"--csv-quoting", "none", "--output", dir)
+		if err == nil {
+			t.Fatalf("gen with --csv-quoting none succeeded on fields holding the delimiter:\n%s", out)
+		}
+		if !bytes.Contains(out, []byte("cannot be written with --csv-quoting none")) {
+			t.Errorf("unexpected error output:\n%s", out)
+		}
+		for _, name := range []string{"_SUCCESS", "dim_companies.csv", "dim_companies.csv.tmp"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				t.Errorf("%s left in the output of the failed run", name)
+			}
+		}
+	})
+}
+
+// manifestRows returns the row count of every table recorded in the manifest
+// of dir.
+func manifestRows(t *testing.T, dir string) map[string]int64 {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("cannot read manifest: %v", err)
+	}
+	var manifest struct {
+		Tables []struct {
+			Name string `json:"name"`
+			Rows int64  `json:"rows"`
+		} `json:"tables"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("cannot parse manifest: %v", err)
+	}
+	rows := make(map[string]int64, len(manifest.Tables))
+	for _, tbl := range manifest.Tables {
+		rows[tbl.Name] = tbl.Rows
+	}
+	return rows
+}
+
+// TestRowCounts sets the row counts of ecommerce-ds tables with --rows and
+// --rows-file. Store returns follow an overridden store sales table, so they
+// keep their ratio to it instead of outnumbering it.
+func TestRowCounts(t *testing.T) {
+	t.Run("rows", func(t *testing.T) {
+		dir := testOutputDir(t, "rows")
+		mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.02", "--format", "csv", "--seed", "1",
+			"--rows", "fact_store_sales=1000,dim_stores=20", "--output", dir)
+		mustGengo(t, "validate", dir)
+		rows := manifestRows(t, dir)
+		if rows["fact_store_sales"] != 1000 || rows["dim_stores"] != 20 {
+			t.Errorf("fact_store_sales has %d rows and dim_stores %d, want 1000 and 20", rows["fact_store_sales"], rows["dim_stores"])
+		}
+		if r := rows["fact_store_returns"]; r == 0 || r > 200 {
+			t.Errorf("fact_store_returns has %d rows, want about 8%% of 1000 store sales", r)
+		}
+	})
+
+	t.Run("unknown_table", func(t *testing.T) {
+		dir := testOutputDir(t, "rows_unknown")
+		out, err := gengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.02", "--format", "csv",
+			"--rows", "fact_nope=10", "--output", dir)
+		if err == nil {
+			t.Fatalf("gen with an unknown table in --rows succeeded:\n%s", out)
+		}
+		if !bytes.Contains(out, []byte("has no table fact_nope")) {
+			t.Errorf("unexpected error output:\n%s", out)
+		}
+	})
+
+	t.Run("inventory_limit", func(t *testing.T) {
+		dir := testOutputDir(t, "rows_inventory")
+		out, err := gengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.02", "--format", "csv",
+			"--rows", "dim_items=10,dim_warehouses=2,fact_inventory=100000", "--output", dir)
+		if err == nil {
+			t.Fatalf("gen with more inventory rows than weekly snapshots succeeded:\n%s", out)
+		}
+		if !bytes.Contains(out, []byte("a weekly snapshot of 10 items in 2 warehouses")) {
+			t.Errorf("unexpected error output:\n%s", out)
+		}
+	})
+
+	t.Run("rows_file", func(t *testing.T) {
+		dir := testOutputDir(t, "rows_file")
+		rowsFile := filepath.Join(t.TempDir(), "rows.yaml")
+		if err := os.WriteFile(rowsFile, []byte("# clinics and their visits\ndim_clinics: 30\nfact_appointments: 5000\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mustGengo(t, "gen", "--model", "medical", "--size", "0.01", "--format", "parquet", "--seed", "1",
+			"--rows-file", rowsFile, "--output", dir)
+		mustGengo(t, "validate", dir)
+		rows := manifestRows(t, dir)
+		if rows["dim_clinics"] != 30 || rows["fact_appointments"] != 5000 {
+			t.Errorf("dim_clinics has %d rows and fact_appointments %d, want 30 and 5000", rows["dim_clinics"], rows["fact_appointments"])
+		}
+	})
+}
+
+// TestReturnDates returns every sales line and checks that each return falls
+// after its sale, including the sales of the last day of the date dimension.
+func TestReturnDates(t *testing.T) {
+	dir := testOutputDir(t, "return_dates")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.02", "--format", "csv", "--seed", "1",
+		"--rows", "fact_store_sales=20000,fact_store_returns=20000,fact_catalog_sales=20000,fact_catalog_returns=20000,"+
+			"fact_web_sales=20000,fact_web_returns=20000", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	for _, c := range []struct {
+		sales, salesDate, salesNumber       string
+		returns, returnsDate, returnsNumber string
+	}{
+		{"fact_store_sales", "ss_sold_date_sk", "ss_ticket_number", "fact_store_returns", "sr_returned_date_sk", "sr_ticket_number"},
+		{"fact_catalog_sales", "cs_sold_date_sk", "cs_order_number", "fact_catalog_returns", "cr_returned_date_sk", "cr_order_number"},
+		{"fact_web_sales", "ws_sold_date_sk", "ws_order_number", "fact_web_returns", "wr_returned_date_sk", "wr_order_number"},
+	} {
+		sold := csvColumnPairs(t, dir, c.sales, c.salesNumber, c.salesDate)
+		returned := csvColumnPairs(t, dir, c.returns, c.returnsNumber, c.returnsDate)
+		if len(returned) != len(sold) {
+			t.Errorf("%s has %d lines, want one per line of %s (%d)", c.returns, len(returned), c.sales, len(sold))
+		}
+		for number, returnedDate := range returned {
+			if soldDate, ok := sold[number]; !ok || returnedDate <= soldDate {
+				t.Errorf("%s %s: returned on %d, sold on %d", c.returns, number, returnedDate, soldDate)
+			}
+		}
+	}
+}
+
+// csvColumnPairs reads the CSV shards of table under dir and maps the values of
+// its key column to those of its date key column.
+func csvColumnPairs(t *testing.T, dir, table, key, value string) map[string]int64 {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, table+"_*.csv"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no CSV shards of %s in %s", table, dir)
+	}
+	pairs := map[string]int64{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil || len(records) == 0 {
+			t.Fatalf("cannot read %s: %v", path, err)
+		}
+		keyIdx, valueIdx := -1, -1
+		for i, name := range records[0] {
+			switch name {
+			case key:
+				keyIdx = i
+			case value:
+				valueIdx = i
+			}
+		}
+		if keyIdx < 0 || valueIdx < 0 {
+			t.Fatalf("%s has no %s or %s column", path, key, value)
+		}
+		for _, record := range records[1:] {
+			v, err := strconv.ParseInt(record[valueIdx], 10, 64)
+			if err != nil {
+				t.Fatalf("%s: %s %q is not a date key", path, value, record[valueIdx])
+			}
+			pairs[record[keyIdx]] = v
+		}
+	}
+	return pairs
+}
+
+// fileSums returns the sha256 of every file under dir but the manifest, which
+// records the times of the run, by path relative to dir.
+func fileSums(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	sums := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == "manifest.json" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		sum := sha256.Sum256(data)
+		sums[rel] = hex.EncodeToString(sum[:])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot read %s: %v", dir, err)
+	}
+	return sums
+}
+
+// TestParquetReproducible generates the ecommerce model twice with the same
+// seed and compares the files. The addresses of dim_customer_addresses outgrow
+// their dictionary, so their column chunk has pages of two encodings, whose
+// stats the Parquet writer lists in map order; gengo sorts them by page type
+// and encoding.
+func TestParquetReproducible(t *testing.T) {
+	var runs [2]map[string]string
+	for i := range runs {
+		dir := testOutputDir(t, fmt.Sprintf("parquet_seed_%d", i))
+		mustGengo(t, "gen", "--model", "ecommerce", "--size", "0.05", "--format", "parquet", "--seed", "7", "--output", dir)
+		runs[i] = fileSums(t, dir)
+		if i == 0 {
+			checkEncodingStats(t, filepath.Join(dir, "dim_customer_addresses.parquet"))
+		}
+	}
+	for name, sum := range runs[0] {
+		if runs[1][name] != sum {
+			t.Errorf("%s differs between runs with the same seed", name)
+		}
+	}
+	if len(runs[0]) != len(runs[1]) {
+		t.Errorf("runs with the same seed wrote %d and %d files", len(runs[0]), len(runs[1]))
+	}
+}
+
+// checkEncodingStats checks that some column chunk of the Parquet file at path
+// has data pages of more than one encoding, and that the encoding stats of
+// every column chunk are sorted.
+func checkEncodingStats(t *testing.T, path string) {
+	t.Helper()
+	rdr, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		t.Fatalf("cannot open %s: %v", path, err)
+	}
+	defer rdr.Close()
+	mixed := false
+	for g := 0; g < rdr.NumRowGroups(); g++ {
+		for c := 0; c < rdr.MetaData().Schema.NumColumns(); c++ {
+			chunk, err := rdr.MetaData().RowGroup(g).ColumnChunk(c)
+			if err != nil {
+				t.Fatalf("cannot read column chunk %d of %s: %v", c, path, err)
+			}
+			stats, dataEncodings := chunk.EncodingStats(), 0
+			for i, s := range stats {
+				if strings.HasPrefix(s.PageType.String(), "DATA_PAGE") {
+					dataEncodings++
+				}
+				if i > 0 && (s.PageType < stats[i-1].PageType || s.PageType == stats[i-1].PageType && s.Encoding < stats[i-1].Encoding) {
+					t.Errorf("encoding stats of %s in row group %d are not sorted: %v", chunk.PathInSchema(), g, stats)
+				}
+			}
+			mixed = mixed || dataEncodings > 1
+		}
+	}
+	if !mixed {
+		t.Errorf("no column chunk of %s has data pages of more than one encoding", path)
+	}
+}
+
+// TestCompression generates the ecommerce-ds model uncompressed and with each
+// codec, and checks that every compressed file decompresses to the file of the
+// uncompressed run.
+func TestCompression(t *testing.T) {
+	plain := testOutputDir(t, "compression_none")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "csv", "--seed", "1", "--output", plain)
+	want := fileSums(t, plain)
+
+	for _, tc := range []struct {
+		codec, ext string
+		open       func(io.Reader) (io.Reader, error)
+	}{
+		{"gzip", ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"zstd", ".zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r, zstd.WithDecoderConcurrency(1)) }},
+		{"lz4", ".lz4", func(r io.Reader) (io.Reader, error) { return lz4.NewReader(r), nil }},
+	} {
+		t.Run(tc.codec, func(t *testing.T) {
+			dir := testOutputDir(t, "compression_"+tc.codec)
+			mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "csv", "--seed", "1",
+				"--compression", tc.codec, "--output", dir)
+			mustGengo(t, "validate", dir)
+			for name, sum := range want {
+				if !strings.HasSuffix(name, ".csv") {
+					continue
+				}
+				f, err := os.Open(filepath.Join(dir, name+tc.ext))
+				if err != nil {
+					t.Errorf("%s: %v", tc.codec, err)
+					continue
+				}
+				r, err := tc.open(f)
+				if err != nil {
+					f.Close()
+					t.Fatalf("cannot decompress %s%s: %v", name, tc.ext, err)
+				}
+				h := sha256.New()
+				_, err = io.Copy(h, r)
+				f.Close()
+				if err != nil {
+					t.Fatalf("cannot decompress %s%s: %v", name, tc.ext, err)
+				}
+				if hex.EncodeToString(h.Sum(nil)) != sum {
+					t.Errorf("%s%s does not decompress to %s", name, tc.ext, name)
+				}
+			}
+		})
+	}
+}
+
+// TestParquetOptions writes Parquet with a codec, format version and row group
+// size other than the defaults. The store sales shards hold more rows than a
+// record batch, which is a row group of its own by default; with a large target
+// size the batches share one row group.
+func TestParquetOptions(t *testing.T) {
+	dir := testOutputDir(t, "parquet_options")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "parquet", "--seed", "1",
+		"--rows", "fact_store_sales=200000", "--parquet-codec", "zstd", "--parquet-version", "1.0",
+		"--parquet-row-group-bytes", "1073741824", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "fact_store_sales*.parquet"))
+	if len(paths) == 0 {
+		t.Fatalf("no fact_store_sales files in %s", dir)
+	}
+	for _, path := range paths {
+		rdr, err := file.OpenParquetFile(path, false)
+		if err != nil {
+			t.Fatalf("cannot open %s: %v", path, err)
+		}
+		defer rdr.Close()
+		if v := rdr.MetaData().Version(); v != parquet.V1_0 {
+			t.Errorf("%s has format version %s, want 1.0", path, v)
+		}
+		if rdr.NumRowGroups() != 1 || rdr.NumRows() <= 65536 {
+			t.Errorf("%s has %d rows in %d row groups, want more than 65536 in one", path, rdr.NumRows(), rdr.NumRowGroups())
+		}
+		for c := 0; c < rdr.MetaData().Schema.NumColumns(); c++ {
+			chunk, err := rdr.MetaData().RowGroup(0).ColumnChunk(c)
+			if err != nil {
+				t.Fatalf("cannot read column chunk %d of %s: %v", c, path, err)
+			}
+			if chunk.Compression() != compress.Codecs.Zstd {
+				t.Errorf("%s of %s is compressed with %s, want zstd", chunk.PathInSchema(), path, chunk.Compression())
+			}
+		}
+	}
+}
+
+// TestPartitionBy writes the ecommerce-ds fact tables in Hive-style partitions
+// by year and month and checks that every store sales row lies in the partition
+// of its sale date.
+func TestPartitionBy(t *testing.T) {
+	dir := testOutputDir(t, "partition_by")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "csv", "--seed", "1",
+		"--partition-by", "year,month", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	if paths, _ := filepath.Glob(filepath.Join(dir, "fact_store_sales*.csv")); len(paths) > 0 {
+		t.Errorf("unpartitioned store sales files written: %v", paths)
+	}
+	part := regexp.MustCompile(`^year=(\d{4})/month=(\d{2})/part-\d{5}\.csv$`)
+	table := filepath.Join(dir, "fact_store_sales")
+	files := 0
+	err := filepath.WalkDir(table, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(table, path)
+		m := part.FindStringSubmatch(filepath.ToSlash(rel))
+		if m == nil {
+			t.Errorf("%s is not a partition file", rel)
+			return nil
+		}
+		files++
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", rel, err)
+		}
+		if len(records) < 2 || records[0][0] != "ss_sold_date_sk" {
+			t.Errorf("%s has no rows under a header starting with ss_sold_date_sk", rel)
+			return nil
+		}
+		for _, row := range records[1:] {
+			julian, err := strconv.ParseInt(row[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: bad ss_sold_date_sk %q", rel, row[0])
+			}
+			// Date keys are Julian day numbers; 2440588 is 1970-01-01.
+			date := time.Unix((julian-2440588)*86400, 0).UTC().Format("2006/01")
+			if date != m[1]+"/"+m[2] {
+				t.Errorf("%s holds a sale of %s", rel, date)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files == 0 {
+		t.Errorf("no partition files under %s", table)
+	}
+}
+
+// TestPartitionFiles partitions store sales by day into far more partitions
+// than a shard keeps files open for, and checks that each shard still writes
+// a single file per partition, holding every row.
+func TestPartitionFiles(t *testing.T) {
+	dir := testOutputDir(t, "partition_files")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "csv", "--seed", "1",
+		"--rows", "fact_store_sales=400000", "--partition-by", "year,month,day", "--max-open-partitions", "4",
+		"--output", dir)
+	mustGengo(t, "validate", dir)
+
+	part := regexp.MustCompile(`^(year=\d{4}/month=\d{2}/day=\d{2})/(part-\d{5})(-\d+)?\.csv$`)
+	table := filepath.Join(dir, "fact_store_sales")
+	partitions, shards := map[string]bool{}, map[string]bool{}
+	files, rows := 0, int64(0)
+	err := filepath.WalkDir(table, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(table, path)
+		m := part.FindStringSubmatch(filepath.ToSlash(rel))
+		if m == nil {
+			t.Errorf("%s is not a partition file", rel)
+			return nil
+		}
+		partitions[m[1]], shards[m[2]] = true, true
+		files++
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rows += int64(strings.Count(string(data), "\n")) - 1
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) <= 4 {
+		t.Fatalf("store sales fall in %d partitions, want more than the 4 open files", len(partitions))
+	}
+	if files > len(partitions)*len(shards) {
+		t.Errorf("%d files for %d partitions of %d shards, want at most one per shard and partition", files, len(partitions), len(shards))
+	}
+	if want := manifestRows(t, dir)["fact_store_sales"]; rows != want {
+		t.Errorf("partition files hold %d rows, want %d", rows, want)
+	}
+}
+
+// TestIceberg writes the ecommerce-ds model as Iceberg tables and checks the
+// metadata of each: version-hint.text names v1.metadata.json, whose current
+// snapshot holds the table's data files and rows.
+func TestIceberg(t *testing.T) {
+	dir := testOutputDir(t, "iceberg")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "iceberg", "--seed", "1", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	rows := manifestRows(t, dir)
+	if len(rows) == 0 {
+		t.Fatal("the manifest records no tables")
+	}
+	for table, n := range rows {
+		tableDir := filepath.Join(dir, table)
+		hint, err := os.ReadFile(filepath.Join(tableDir, "metadata", "version-hint.text"))
+		if err != nil || strings.TrimSpace(string(hint)) != "1" {
+			t.Errorf("%s: version-hint.text is %q (%v), want 1", table, hint, err)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(tableDir, "metadata", "v1.metadata.json"))
+		if err != nil {
+			t.Errorf("%s: %v", table, err)
+			continue
+		}
+		var metadata struct {
+			FormatVersion     int    `json:"format-version"`
+			Location          string `json:"location"`
+			CurrentSnapshotID int64  `json:"current-snapshot-id"`
+			Schemas           []struct {
+				Fields []json.RawMessage `json:"fields"`
+			} `json:"schemas"`
+			Snapshots []struct {
+				SnapshotID   int64             `json:"snapshot-id"`
+				ManifestList string            `json:"manifest-list"`
+				Summary      map[string]string `json:"summary"`
+			} `json:"snapshots"`
+		}
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			t.Errorf("%s: cannot parse v1.metadata.json: %v", table, err)
+			continue
+		}
+		if metadata.FormatVersion != 2 || !strings.HasSuffix(metadata.Location, "/"+table) {
+			t.Errorf("%s: format version %d at %s", table, metadata.FormatVersion, metadata.Location)
+		}
+		if len(metadata.Schemas) != 1 || len(metadata.Schemas[0].Fields) == 0 {
+			t.Errorf("%s: no schema with fields", table)
+		}
+		if len(metadata.Snapshots) != 1 || metadata.Snapshots[0].SnapshotID != metadata.CurrentSnapshotID {
+			t.Errorf("%s: current snapshot %d is not the only snapshot", table, metadata.CurrentSnapshotID)
+			continue
+		}
+		snapshot := metadata.Snapshots[0]
+		if _, err := os.Stat(strings.TrimPrefix(snapshot.ManifestList, "file://")); err != nil {
+			t.Errorf("%s: manifest list: %v", table, err)
+		}
+		files, _ := filepath.Glob(filepath.Join(tableDir, "data", "*.parquet"))
+		if snapshot.Summary["total-data-files"] != strconv.Itoa(len(files)) || snapshot.Summary["total-records"] != strconv.FormatInt(n, 10) {
+			t.Errorf("%s: snapshot holds %s files and %s rows, want %d and %d", table,
+				snapshot.Summary["total-data-files"], snapshot.Summary["total-records"], len(files), n)
+		}
+	}
+}
+
+// TestDelta writes the ecommerce-ds model as Delta tables and checks the first
+// commit of each: it holds the protocol, the table's schema and an add action
+// for each data file, and the rows of those files.
+func TestDelta(t *testing.T) {
+	dir := testOutputDir(t, "delta")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "delta", "--seed", "1", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	rows := manifestRows(t, dir)
+	if len(rows) == 0 {
+		t.Fatal("the manifest records no tables")
+	}
+	for table, n := range rows {
+		tableDir := filepath.Join(dir, table)
+		logs, _ := filepath.Glob(filepath.Join(tableDir, "_delta_log", "*.json"))
+		if len(logs) != 1 || filepath.Base(logs[0]) != "00000000000000000000.json" {
+			t.Errorf("%s: delta log holds %v, want only 00000000000000000000.json", table, logs)
+			continue
+		}
+		data, err := os.ReadFile(logs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions := map[string]int{}
+		var records int64
+		for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+			var action struct {
+				CommitInfo json.RawMessage `json:"commitInfo"`
+				Protocol   *struct {
+					MinReaderVersion int `json:"minReaderVersion"`
+				} `json:"protocol"`
+				MetaData *struct {
+					SchemaString string `json:"schemaString"`
+				} `json:"metaData"`
+				Add *struct {
+					Path  string `json:"path"`
+					Size  int64  `json:"size"`
+					Stats string `json:"stats"`
+				} `json:"add"`
+			}
+			if err := json.Unmarshal(line, &action); err != nil {
+				t.Fatalf("%s: cannot parse delta log line %s: %v", table, line, err)
+			}
+			switch {
+			case action.CommitInfo != nil:
+				actions["commitInfo"]++
+			case action.Protocol != nil:
+				actions["protocol"]++
+			case action.MetaData != nil:
+				actions["metaData"]++
+				var schema struct {
+					Type   string            `json:"type"`
+					Fields []json.RawMessage `json:"fields"`
+				}
+				if err := json.Unmarshal([]byte(action.MetaData.SchemaString), &schema); err != nil || schema.Type != "struct" || len(schema.Fields) == 0 {
+					t.Errorf("%s: bad schemaString %q", table, action.MetaData.SchemaString)
+				}
+			case action.Add != nil:
+				actions["add"]++
+				info, err := os.Stat(filepath.Join(tableDir, filepath.FromSlash(action.Add.Path)))
+				if err != nil || info.Size() != action.Add.Size {
+					t.Errorf("%s: added file %s of %d bytes: %v", table, action.Add.Path, action.Add.Size, err)
+				}
+				var stats struct {
+					NumRecords int64 `json:"numRecords"`
+				}
+				if err := json.Unmarshal([]byte(action.Add.Stats), &stats); err != nil {
+					t.Errorf("%s: bad stats of %s: %v", table, action.Add.Path, err)
+				}
+				records += stats.NumRecords
+			}
+		}
+		files, _ := filepath.Glob(filepath.Join(tableDir, "data", "*.parquet"))
+		if actions["commitInfo"] != 1 || actions["protocol"] != 1 || actions["metaData"] != 1 || actions["add"] != len(files) {
+			t.Errorf("%s: delta log holds actions %v for %d data files", table, actions, len(files))
+		}
+		if records != n {
+			t.Errorf("%s: added files hold %d rows, want %d", table, records, n)
+		}
+	}
+}
+
+// TestArrow writes the ecommerce model in both Arrow IPC variants and checks the
+// framing of every file: the file format starts and ends with the ARROW1 magic,
+// the stream format starts with a continuation marker and ends with the
+// end-of-stream marker.
+func TestArrow(t *testing.T) {
+	for _, tc := range []struct {
+		variant, ext string
+		head, tail   []byte
+	}{
+		{"file", ".arrow", []byte("ARROW1\x00\x00"), []byte("ARROW1")},
+		{"stream", ".arrows", []byte{0xff, 0xff, 0xff, 0xff}, []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}},
+	} {
+		t.Run(tc.variant, func(t *testing.T) {
+			dir := testOutputDir(t, "arrow_"+tc.variant)
+			mustGengo(t, "gen", "--model", "ecommerce", "--size", "0.01", "--format", "arrow", "--seed", "1",
+				"--arrow-ipc", tc.variant, "--output", dir)
+			mustGengo(t, "validate", dir)
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*"+tc.ext))
+			if len(files) == 0 {
+				t.Fatalf("no %s files in %s", tc.ext, dir)
+			}
+			for _, path := range files {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.HasPrefix(data, tc.head) || !bytes.HasSuffix(data, tc.tail) {
+					t.Errorf("%s is not framed as an Arrow IPC %s", filepath.Base(path), tc.variant)
+				}
+			}
+		})
+	}
+}
+
+// TestAvro writes the ecommerce-ds model as Avro with each block codec and
+// checks the object container header of every file: its codec, a record schema
+// named after the table and a sync marker that also ends the file.
+func TestAvro(t *testing.T) {
+	for _, codec := range []string{"deflate", "snappy", "null"} {
+		t.Run(codec, func(t *testing.T) {
+			dir := testOutputDir(t, "avro_"+codec)
+			mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "avro", "--seed", "1",
+				"--avro-codec", codec, "--output", dir)
+			mustGengo(t, "validate", dir)
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*.avro"))
+			if len(files) == 0 {
+				t.Fatalf("no .avro files in %s", dir)
+			}
+			shard := regexp.MustCompile(`_\d+$`)
+			for _, path := range files {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				meta, sync, err := avroHeader(data)
+				if err != nil {
+					t.Errorf("%s: %v", filepath.Base(path), err)
+					continue
+				}
+				if string(meta["avro.codec"]) != codec {
+					t.Errorf("%s has codec %q, want %s", filepath.Base(path), meta["avro.codec"], codec)
+				}
+				var schema struct {
+					Type   string            `json:"type"`
+					Name   string            `json:"name"`
+					Fields []json.RawMessage `json:"fields"`
+				}
+				table := shard.ReplaceAllString(strings.TrimSuffix(filepath.Base(path), ".avro"), "")
+				if err := json.Unmarshal(meta["avro.schema"], &schema); err != nil || schema.Type != "record" || schema.Name != table || len(schema.Fields) == 0 {
+					t.Errorf("%s has schema %s, want a record named %s", filepath.Base(path), meta["avro.schema"], table)
+				}
+				if !bytes.HasSuffix(data, sync) {
+					t.Errorf("%s does not end with its sync marker", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
+
+// avroHeader parses the header of an Avro object container file: the magic,
+// the metadata map and the sync marker.
+func avroHeader(data []byte) (map[string][]byte, []byte, error) {
+	if !bytes.HasPrefix(data, []byte("Obj\x01")) {
+		return nil, nil, fmt.Errorf("no Avro magic")
+	}
+	r := bytes.NewReader(data[4:])
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadVarint(r)
+		if err != nil || n < 0 || n > int64(r.Len()) {
+			return nil, fmt.Errorf("bad length in header")
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+	meta := map[string][]byte{}
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(r); err != nil { // size of the block in bytes
+				return nil, nil, err
+			}
+		}
+		for ; count > 0; count-- {
+			key, err := readBytes()
+			if err != nil {
+				return nil, nil, err
+			}
+			value, err := readBytes()
+			if err != nil {
+				return nil, nil, err
+			}
+			meta[string(key)] = value
+		}
+	}
+	sync := make([]byte, 16)
+	if _, err := io.ReadFull(r, sync); err != nil {
+		return nil, nil, fmt.Errorf("no sync marker: %w", err)
+	}
+	return meta, sync, nil
+}
+
+// TestWriteError makes publishing a dimension file and a fact shard fail by
+// putting a directory in their place. gen must exit non-zero without leaving
+// temporary files or a _SUCCESS marker behind.
+func TestWriteError(t *testing.T) {
+	for _, blocked := range []string{"dim_stores.csv", "fact_store_sales_0.csv"} {
+		t.Run(blocked, func(t *testing.T) {
+			dir := testOutputDir(t, "write_error_"+strings.TrimSuffix(blocked, ".csv"))
+			if err := os.MkdirAll(filepath.Join(dir, blocked), 0755); err != nil {
+				t.Fatal(err)
+			}
+			out, err := gengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.02", "--format", "csv", "--seed", "1", "--output", dir)
+			if err == nil {
+				t.Fatalf("gen succeeded although %s could not be written:\n%s", blocked, out)
+			}
+			if !bytes.Contains(out, []byte("failed to publish")) {
+				t.Errorf("unexpected error output:\n%s", out)
+			}
+			err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if strings.HasSuffix(d.Name(), ".tmp") || d.Name() == "_SUCCESS" {
+					t.Errorf("%s left in the output of the failed run", path)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestInterrupt sends gen an interrupt once it has published its first fact
+// shard. The run must stop with the files it published kept and its incomplete
+// tables reported, and --resume must complete it into the dataset of an
+// uninterrupted run.
+func TestInterrupt(t *testing.T) {
+	args := []string{"gen", "--model", "ecommerce-ds", "--size", "0.1", "--format", "csv", "--seed", "3", "--output"}
+	full := testOutputDir(t, "interrupt_full")
+	mustGengo(t, append(args, full)...)
+	want := fileSums(t, full)
+
+	dir := testOutputDir(t, "interrupt")
+	cmd := exec.CommandContext(t.Context(), binaryPath(t), append(args, dir)...)
+	cmd.Dir = moduleRoot(t)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var published []string
+	for len(published) == 0 {
+		select {
+		case err := <-done:
+			t.Fatalf("gen exited before publishing a fact shard: %v\n%s", err, out.Bytes())
+		case <-time.After(5 * time.Millisecond):
+		}
+		published, _ = filepath.Glob(filepath.Join(dir, "fact_*.csv"))
+	}
+	published, _ = filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err == nil {
+		t.Fatalf("interrupted gen exited successfully:\n%s", out.Bytes())
+	}
+	if !bytes.Contains(out.Bytes(), []byte("Incomplete tables: ")) || !bytes.Contains(out.Bytes(), []byte("gengo gen --resume")) {
+		t.Errorf("interrupted gen did not report its incomplete tables:\n%s", out.Bytes())
+	}
+	for _, path := range published {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s published before the interrupt is gone: %v", filepath.Base(path), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "_SUCCESS")); err == nil {
+		t.Error("_SUCCESS written by the interrupted run")
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(tmp) > 0 {
+		t.Errorf("temporary files left by the interrupted run: %v", tmp)
+	}
+
+	mustGengo(t, "gen", "--resume", dir)
+	mustGengo(t, "validate", dir)
+	got := fileSums(t, dir)
+	for name, sum := range want {
+		if got[name] != sum {
+			t.Errorf("%s differs from the uninterrupted run after resume", name)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("resumed run has %d files, uninterrupted run had %d", len(got), len(want))
+	}
+}
+
+// TestScaleFactor sizes ecommerce-ds by TPC-DS scale factor 1. The sales and
+// inventory tables are set small with --rows; every dimension must get the row
+// count dsdgen generates at SF1.
+func TestScaleFactor(t *testing.T) {
+	dir := testOutputDir(t, "scale_factor")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--scale-factor", "1", "--format", "csv", "--seed", "1",
+		"--rows", "fact_store_sales=20000,fact_catalog_sales=10000,fact_web_sales=5000,fact_inventory=10000", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	rows := manifestRows(t, dir)
+	for table, want := range map[string]int64{
+		"dim_customers": 100000, "dim_customer_addresses": 50000, "dim_customer_demographics": 1920800,
+		"dim_household_demographics": 7200, "dim_items": 18000, "dim_promotions": 300, "dim_stores": 12,
+		"dim_call_centers": 6, "dim_catalog_pages": 11718, "dim_web_sites": 30, "dim_web_pages": 60,
+		"dim_warehouses": 5, "dim_reasons": 35, "dim_income_bands": 20, "dim_ship_modes": 20,
+		"dim_date": 73049, "dim_time": 86400,
+		"fact_store_sales": 20000, "fact_catalog_sales": 10000, "fact_web_sales": 5000, "fact_inventory": 10000,
+	} {
+		if rows[table] != want {
+			t.Errorf("%s has %d rows, want %d", table, rows[table], want)
+		}
+	}
+	// The returns follow their sales in the ratio of the dsdgen SF1 tables.
+	for _, c := range []struct {
+		returns, sales       string
+		sf1Returns, sf1Sales float64
+	}{
+		{"fact_store_returns", "fact_store_sales", 287514, 2880404},
+		{"fact_catalog_returns", "fact_catalog_sales", 144067, 1441548},
+		{"fact_web_returns", "fact_web_sales", 71763, 719384},
+	} {
+		want := float64(rows[c.sales]) * c.sf1Returns / c.sf1Sales
+		if got := float64(rows[c.returns]); got < want-1 || got > want+1 {
+			t.Errorf("%s has %.0f rows, want about %.0f", c.returns, got, want)
+		}
+	}
+}
+
+// tpcdsColumns is the number of columns of each TPC-DS table.
+var tpcdsColumns = map[string]int{
+	"date_dim": 28, "time_dim": 10, "income_band": 3, "customer_demographics": 9,
+	"household_demographics": 5, "customer_address": 13, "customer": 18, "item": 22,
+	"promotion": 19, "store": 29, "call_center": 31, "catalog_page": 9, "web_site": 26,
+	"web_page": 14, "warehouse": 14, "reason": 3, "ship_mode": 6,
+	"store_sales": 23, "store_returns": 20, "catalog_sales": 34, "catalog_returns": 27,
+	"web_sales": 34, "web_returns": 24, "inventory": 4,
+}
+
+// TestDsdgen writes ecommerce-ds in the dsdgen layout: .dat files named after
+// the TPC-DS tables the way dsdgen names them, without a header, with a '|'
+// after each of the columns of the TPC-DS table and decimals of scale 2.
+func TestDsdgen(t *testing.T) {
+	dir := testOutputDir(t, "dsdgen")
+	mustGengo(t, "gen", "--model", "ecommerce-ds", "--size", "0.01", "--format", "dsdgen", "--seed", "1", "--output", dir)
+	mustGengo(t, "validate", dir)
+
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("cannot read manifest: %v", err)
+	}
+	var manifest struct {
+		Files []struct {
+			Name  string `json:"name"`
+			Table string `json:"table"`
+			Rows  int64  `json:"rows"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("cannot parse manifest: %v", err)
+	}
+	// A table is written as <table>.dat, or in parallel as
+	// <table>_<child>_<parallel>.dat like dsdgen -parallel -child.
+	name := regexp.MustCompile(`^([a-z_]+?)(?:_(\d+)_(\d+))?\.dat$`)
+	if len(manifest.Files) == 0 {
+		t.Fatal("the manifest records no files")
+	}
+	tables := map[string]bool{}
+	for _, f := range manifest.Files {
+		m := name.FindStringSubmatch(f.Name)
+		if m == nil {
+			t.Errorf("%s of %s is not named like a dsdgen file", f.Name, f.Table)
+			continue
+		}
+		columns, ok := tpcdsColumns[m[1]]
+		if !ok {
+			t.Errorf("%s of %s is not named after a TPC-DS table", f.Name, f.Table)
+			continue
+		}
+		tables[m[1]] = true
+		if m[2] != "" {
+			child, _ := strconv.Atoi(m[2])
+			parallel, _ := strconv.Atoi(m[3])
+			if child < 1 || child > parallel || parallel < 2 {
+				t.Errorf("%s is not child 1 to %d of a parallel run", f.Name, parallel)
+			}
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if int64(len(lines)) != f.Rows {
+			t.Errorf("%s has %d lines, want %d rows", f.Name, len(lines), f.Rows)
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, "|") || strings.Count(line, "|") != columns {
+				t.Errorf("line %d of %s does not hold the %d columns of %s each ending in '|': %s", i+1, f.Name, columns, m[1], line)
+				break
+			}
+		}
+		if m[1] == "item" {
+			// i_current_price and i_wholesale_cost are decimal(7,2).
+			price := regexp.MustCompile(`^\d+\.\d\d$`)
+			for i, line := range lines {
+				fields := strings.Split(line, "|")
+				if !price.MatchString(fields[5]) || !price.MatchString(fields[6]) {
+					t.Errorf("line %d of %s holds prices %s and %s, want 2 decimals", i+1, f.Name, fields[5], fields[6])
+					break
+				}
+			}
+		}
+	}
+	if len(tables) != len(tpcdsColumns) {
+		t.Errorf("wrote %d of the %d TPC-DS tables", len(tables), len(tpcdsColumns))
+	}
+}